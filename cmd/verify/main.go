@@ -0,0 +1,72 @@
+// verify checks the append-only files of the node without starting it
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/f1monkey/search/internal/storage"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s <file>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ok := true
+	for _, path := range flag.Args() {
+		if !verify(path) {
+			ok = false
+		}
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func verify(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("%s: %v\n", path, err)
+		return false
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		fmt.Printf("%s: %v\n", path, err)
+		return false
+	}
+
+	result, err := storage.Verify(f, stat.Size())
+	if err != nil {
+		fmt.Printf("%s: %v\n", path, err)
+		return false
+	}
+
+	format := "framed"
+	if result.Legacy {
+		format = "legacy"
+	}
+	fmt.Printf("%s: format %s, %d valid records, %d valid bytes\n", path, format, result.Records, result.Size)
+
+	if c := result.Corruption; c != nil {
+		if c.Tail {
+			fmt.Printf("%s: torn write at offset %d: %s, will be truncated on load\n", path, c.Offset, c.Reason)
+		} else {
+			fmt.Printf("%s: %v\n", path, c)
+		}
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,95 @@
+package aggregation
+
+import (
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/f1monkey/search/internal/index/date"
+	"github.com/f1monkey/search/internal/index/ip"
+	"github.com/f1monkey/search/internal/index/schema"
+)
+
+const (
+	DefaultTermsSize = 10
+
+	// MaxBuckets limits the number of buckets a single aggregation can produce
+	MaxBuckets = 10000
+)
+
+// Values gives access to the doc values of the matched documents
+type Values interface {
+	Numeric(field string, ord uint32) (uint64, bool)
+	Keyword(field string, ord uint32) (string, bool)
+	IP(field string, ord uint32) (ip.Addr, bool)
+	Range(field string, min uint64, max uint64) *roaring.Bitmap
+}
+
+// Aggregation node of the parsed aggregations tree
+type Aggregation interface {
+	aggregation()
+}
+
+// Terms groups documents by field value and returns the most frequent values
+type Terms struct {
+	Field string
+	Type  schema.Type
+	Size  int
+	Aggs  map[string]Aggregation
+}
+
+// Range groups documents into the buckets by numeric field value, from is inclusive and to is exclusive
+type Range struct {
+	Field  string
+	Type   schema.Type
+	Ranges []RangeBucket
+	Aggs   map[string]Aggregation
+}
+
+type RangeBucket struct {
+	Key  string
+	From interface{}
+	To   interface{}
+}
+
+// Histogram groups documents into the buckets of fixed interval by numeric field value
+type Histogram struct {
+	Field       string
+	Type        schema.Type
+	Interval    float64
+	MinDocCount uint64
+	Aggs        map[string]Aggregation
+}
+
+// DateHistogram groups documents into the buckets of calendar or fixed interval by date field value.
+// Buckets start in the time zone of the location and their keys are milliseconds since the epoch
+type DateHistogram struct {
+	Field       string
+	Interval    date.Interval
+	Location    *time.Location
+	Formats     date.Formats
+	MinDocCount uint64
+	Aggs        map[string]Aggregation
+}
+
+type MetricType string
+
+const (
+	MetricMin   MetricType = "min"
+	MetricMax   MetricType = "max"
+	MetricAvg   MetricType = "avg"
+	MetricSum   MetricType = "sum"
+	MetricStats MetricType = "stats"
+)
+
+// Metric calculates a value over numeric field values
+type Metric struct {
+	Metric MetricType
+	Field  string
+	Type   schema.Type
+}
+
+func (Terms) aggregation()         {}
+func (Range) aggregation()         {}
+func (Histogram) aggregation()     {}
+func (DateHistogram) aggregation() {}
+func (Metric) aggregation()        {}
@@ -0,0 +1,366 @@
+package aggregation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/f1monkey/search/internal/index/date"
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+)
+
+// MaxDepth of the aggregations tree: top-level aggregations and their sub-aggregations
+const MaxDepth = 2
+
+// Parse builds aggregations from their JSON representation.
+// Field names and types are checked against the schema, errors are keyed by the path of the invalid aggregation
+func Parse(s schema.Schema, path string, data json.RawMessage) (map[string]Aggregation, error) {
+	p := &parser{
+		schema: s,
+		errors: validation.Errors{},
+	}
+
+	result := p.parseAggs(path, data, 1)
+	if len(p.errors) != 0 {
+		return nil, p.errors
+	}
+
+	return result, nil
+}
+
+type parser struct {
+	schema schema.Schema
+	errors validation.Errors
+}
+
+func (p *parser) addErr(path string, format string, args ...interface{}) {
+	p.errors[path] = validation.NewError("", fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseAggs(path string, data json.RawMessage, depth int) map[string]Aggregation {
+	if depth > MaxDepth {
+		p.addErr(path, "only %d levels of aggregations are allowed", MaxDepth)
+		return nil
+	}
+
+	items := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		p.addErr(path, "must be an object")
+		return nil
+	}
+
+	result := make(map[string]Aggregation, len(items))
+	for name, item := range items {
+		if name == "" {
+			p.addErr(path, "empty names not allowed")
+			continue
+		}
+		if a := p.parseAgg(join(path, name), item, depth); a != nil {
+			result[name] = a
+		}
+	}
+
+	return result
+}
+
+func (p *parser) parseAgg(path string, data json.RawMessage, depth int) Aggregation {
+	obj := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		p.addErr(path, "must be an object")
+		return nil
+	}
+
+	var subAggs map[string]Aggregation
+	for _, key := range []string{"aggs", "aggregations"} {
+		if raw, ok := obj[key]; ok {
+			subAggs = p.parseAggs(join(path, key), raw, depth+1)
+			delete(obj, key)
+		}
+	}
+
+	if len(obj) != 1 {
+		p.addErr(path, "must contain exactly one aggregation type")
+		return nil
+	}
+
+	for name, body := range obj {
+		aggPath := join(path, name)
+		switch name {
+		case "terms":
+			return p.parseTerms(aggPath, body, subAggs)
+		case "range":
+			return p.parseRange(aggPath, body, subAggs)
+		case "histogram":
+			return p.parseHistogram(aggPath, body, subAggs)
+		case "date_histogram":
+			return p.parseDateHistogram(aggPath, body, subAggs)
+		case string(MetricMin), string(MetricMax), string(MetricAvg), string(MetricSum), string(MetricStats):
+			if subAggs != nil {
+				p.addErr(path, "metric aggregations cannot have sub-aggregations")
+				return nil
+			}
+			return p.parseMetric(aggPath, MetricType(name), body)
+		default:
+			p.addErr(aggPath, "unknown aggregation type %q", name)
+		}
+	}
+
+	return nil
+}
+
+type termsBody struct {
+	Field string `json:"field"`
+	Size  *int   `json:"size"`
+}
+
+func (p *parser) parseTerms(path string, data json.RawMessage, subAggs map[string]Aggregation) Aggregation {
+	body := termsBody{}
+	if !p.decode(path, data, &body) {
+		return nil
+	}
+
+	f, ok := p.field(join(path, "field"), body.Field, termsTypes)
+	if !ok {
+		return nil
+	}
+
+	result := Terms{Field: body.Field, Type: f.Type, Size: DefaultTermsSize, Aggs: subAggs}
+	if body.Size != nil {
+		if *body.Size < 1 || *body.Size > MaxBuckets {
+			p.addErr(join(path, "size"), "must be between 1 and %d", MaxBuckets)
+			return nil
+		}
+		result.Size = *body.Size
+	}
+
+	return result
+}
+
+type rangeBody struct {
+	Field  string `json:"field"`
+	Ranges []struct {
+		Key  string      `json:"key"`
+		From interface{} `json:"from"`
+		To   interface{} `json:"to"`
+	} `json:"ranges"`
+}
+
+func (p *parser) parseRange(path string, data json.RawMessage, subAggs map[string]Aggregation) Aggregation {
+	body := rangeBody{}
+	if !p.decode(path, data, &body) {
+		return nil
+	}
+
+	f, ok := p.field(join(path, "field"), body.Field, numericTypes)
+	if !ok {
+		return nil
+	}
+
+	if len(body.Ranges) == 0 {
+		p.addErr(join(path, "ranges"), "cannot be empty")
+		return nil
+	}
+
+	result := Range{Field: body.Field, Type: f.Type, Aggs: subAggs}
+	valid := true
+	for i, r := range body.Ranges {
+		rangePath := join(path, "ranges", fmt.Sprint(i))
+		if _, _, _, err := docvalues.Bounds(f.Type, nil, r.From, r.To, nil); err != nil {
+			p.addErr(rangePath, "invalid bounds: %s", err)
+			valid = false
+			continue
+		}
+
+		key := r.Key
+		if key == "" {
+			key = rangeKey(r.From, r.To)
+		}
+		result.Ranges = append(result.Ranges, RangeBucket{Key: key, From: r.From, To: r.To})
+	}
+	if !valid {
+		return nil
+	}
+
+	return result
+}
+
+func rangeKey(from interface{}, to interface{}) string {
+	bound := func(v interface{}) string {
+		if v == nil {
+			return "*"
+		}
+		return fmt.Sprint(v)
+	}
+
+	return bound(from) + "-" + bound(to)
+}
+
+type histogramBody struct {
+	Field       string  `json:"field"`
+	Interval    float64 `json:"interval"`
+	MinDocCount uint64  `json:"min_doc_count"`
+}
+
+func (p *parser) parseHistogram(path string, data json.RawMessage, subAggs map[string]Aggregation) Aggregation {
+	body := histogramBody{}
+	if !p.decode(path, data, &body) {
+		return nil
+	}
+
+	f, ok := p.field(join(path, "field"), body.Field, numericTypes)
+	if !ok {
+		return nil
+	}
+
+	if body.Interval <= 0 {
+		p.addErr(join(path, "interval"), "must be > 0")
+		return nil
+	}
+
+	return Histogram{
+		Field:       body.Field,
+		Type:        f.Type,
+		Interval:    body.Interval,
+		MinDocCount: body.MinDocCount,
+		Aggs:        subAggs,
+	}
+}
+
+type dateHistogramBody struct {
+	Field            string `json:"field"`
+	CalendarInterval string `json:"calendar_interval"`
+	FixedInterval    string `json:"fixed_interval"`
+	TimeZone         string `json:"time_zone"`
+	Format           string `json:"format"`
+	MinDocCount      uint64 `json:"min_doc_count"`
+}
+
+func (p *parser) parseDateHistogram(path string, data json.RawMessage, subAggs map[string]Aggregation) Aggregation {
+	body := dateHistogramBody{}
+	if !p.decode(path, data, &body) {
+		return nil
+	}
+
+	f, ok := p.field(join(path, "field"), body.Field, dateTypes)
+	if !ok {
+		return nil
+	}
+
+	result := DateHistogram{Field: body.Field, Formats: f.DateFormats(), MinDocCount: body.MinDocCount, Aggs: subAggs}
+
+	var err error
+	switch {
+	case body.CalendarInterval != "" && body.FixedInterval != "":
+		p.addErr(path, "only one of calendar_interval and fixed_interval is allowed")
+		return nil
+	case body.CalendarInterval != "":
+		if result.Interval, err = date.ParseCalendarInterval(body.CalendarInterval); err != nil {
+			p.addErr(join(path, "calendar_interval"), "%s", err)
+			return nil
+		}
+	case body.FixedInterval != "":
+		if result.Interval, err = date.ParseFixedInterval(body.FixedInterval); err != nil {
+			p.addErr(join(path, "fixed_interval"), "%s", err)
+			return nil
+		}
+	default:
+		p.addErr(path, "calendar_interval or fixed_interval is required")
+		return nil
+	}
+
+	if result.Location, err = date.ParseLocation(body.TimeZone); err != nil {
+		p.addErr(join(path, "time_zone"), "%s", err)
+		return nil
+	}
+
+	if body.Format != "" {
+		result.Formats = date.ParseFormats(body.Format)
+		if err := result.Formats.Validate(); err != nil {
+			p.addErr(join(path, "format"), "%s", err)
+			return nil
+		}
+	}
+
+	return result
+}
+
+type metricBody struct {
+	Field string `json:"field"`
+}
+
+func (p *parser) parseMetric(path string, metric MetricType, data json.RawMessage) Aggregation {
+	body := metricBody{}
+	if !p.decode(path, data, &body) {
+		return nil
+	}
+
+	f, ok := p.field(join(path, "field"), body.Field, numericTypes)
+	if !ok {
+		return nil
+	}
+
+	return Metric{Metric: metric, Field: body.Field, Type: f.Type}
+}
+
+var numericTypes = []schema.Type{
+	schema.TypeByte,
+	schema.TypeShort,
+	schema.TypeInteger,
+	schema.TypeLong,
+	schema.TypeUnsignedLong,
+	schema.TypeFloat,
+	schema.TypeDouble,
+}
+
+var dateTypes = []schema.Type{
+	schema.TypeDate,
+}
+
+var termsTypes = []schema.Type{
+	schema.TypeKeyword,
+	schema.TypeBool,
+	schema.TypeIP,
+}
+
+func (p *parser) field(path string, name string, allowed []schema.Type) (schema.Field, bool) {
+	if name == "" {
+		p.addErr(path, "cannot be blank")
+		return schema.Field{}, false
+	}
+
+	f, ok := p.schema.Field(name)
+	if !ok {
+		p.addErr(path, "unknown field %q", name)
+		return f, false
+	}
+
+	for _, t := range allowed {
+		if f.Type == t {
+			return f, true
+		}
+	}
+
+	p.addErr(path, "field %q of type %q is not supported by this aggregation", name, f.Type)
+
+	return f, false
+}
+
+func (p *parser) decode(path string, data json.RawMessage, v interface{}) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		p.addErr(path, "invalid body: %s", err)
+		return false
+	}
+
+	return true
+}
+
+func join(parts ...string) string {
+	return strings.Join(parts, ".")
+}
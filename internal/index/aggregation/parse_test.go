@@ -0,0 +1,200 @@
+package aggregation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/f1monkey/search/internal/index/date"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchema() schema.Schema {
+	return schema.NewSchema(
+		map[string]schema.Field{
+			"tag":   schema.NewField(schema.TypeKeyword, false, ""),
+			"bool":  schema.NewField(schema.TypeBool, false, ""),
+			"price": schema.NewField(schema.TypeDouble, false, ""),
+			"count": schema.NewField(schema.TypeLong, false, ""),
+			"text":  schema.NewField(schema.TypeText, false, "analyzer"),
+			"date":  schema.NewField(schema.TypeDate, false, ""),
+			"ip":    schema.NewField(schema.TypeIP, false, ""),
+		},
+		nil,
+	)
+}
+
+func requireErrorKeys(t *testing.T, err error, keys ...string) {
+	t.Helper()
+
+	var ve validation.Errors
+	require.ErrorAs(t, err, &ve)
+	for _, k := range keys {
+		require.Contains(t, ve, k)
+	}
+	require.Len(t, ve, len(keys))
+}
+
+func Test_Parse(t *testing.T) {
+	t.Run("must fail if aggregation type is missing or unknown", func(t *testing.T) {
+		_, err := Parse(testSchema(), "aggs", json.RawMessage(`{
+			"a": {},
+			"b": {"unknown": {}},
+			"c": {"terms": {"field": "tag"}, "avg": {"field": "price"}}
+		}`))
+		requireErrorKeys(t, err, "aggs.a", "aggs.b.unknown", "aggs.c")
+	})
+
+	t.Run("terms", func(t *testing.T) {
+		t.Run("must fail for unsupported field types", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"terms": {"field": "text"}}, "b": {"terms": {"field": "unknown"}}}`))
+			requireErrorKeys(t, err, "aggs.a.terms.field", "aggs.b.terms.field")
+		})
+		t.Run("must fail for invalid size", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"terms": {"field": "tag", "size": 0}}}`))
+			requireErrorKeys(t, err, "aggs.a.terms.size")
+		})
+		t.Run("must fail for unknown keys", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"terms": {"field": "tag", "extra": 1}}}`))
+			requireErrorKeys(t, err, "aggs.a.terms")
+		})
+		t.Run("must parse with default size", func(t *testing.T) {
+			aggs, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"terms": {"field": "bool"}}}`))
+			require.NoError(t, err)
+			require.Equal(t, map[string]Aggregation{
+				"a": Terms{Field: "bool", Type: schema.TypeBool, Size: DefaultTermsSize},
+			}, aggs)
+		})
+	})
+
+	t.Run("range", func(t *testing.T) {
+		t.Run("must fail if ranges are empty or invalid", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{
+				"a": {"range": {"field": "price", "ranges": []}},
+				"b": {"range": {"field": "count", "ranges": [{"to": 1}, {"from": 1.5}]}}
+			}`))
+			requireErrorKeys(t, err, "aggs.a.range.ranges", "aggs.b.range.ranges.1")
+		})
+		t.Run("must parse ranges with generated keys", func(t *testing.T) {
+			aggs, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"range": {"field": "price", "ranges": [
+				{"to": 10}, {"key": "mid", "from": 10, "to": 20}, {"from": 20}
+			]}}}`))
+			require.NoError(t, err)
+			require.Equal(t, map[string]Aggregation{
+				"a": Range{Field: "price", Type: schema.TypeDouble, Ranges: []RangeBucket{
+					{Key: "*-10", To: json.Number("10")},
+					{Key: "mid", From: json.Number("10"), To: json.Number("20")},
+					{Key: "20-*", From: json.Number("20")},
+				}},
+			}, aggs)
+		})
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		t.Run("must fail if interval is not positive", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"histogram": {"field": "price", "interval": 0}}}`))
+			requireErrorKeys(t, err, "aggs.a.histogram.interval")
+		})
+		t.Run("must parse histogram", func(t *testing.T) {
+			aggs, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"histogram": {"field": "price", "interval": 5, "min_doc_count": 1}}}`))
+			require.NoError(t, err)
+			require.Equal(t, map[string]Aggregation{
+				"a": Histogram{Field: "price", Type: schema.TypeDouble, Interval: 5, MinDocCount: 1},
+			}, aggs)
+		})
+	})
+
+	t.Run("date_histogram", func(t *testing.T) {
+		t.Run("must fail for invalid settings", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{
+				"a": {"date_histogram": {"field": "price", "calendar_interval": "day"}},
+				"b": {"date_histogram": {"field": "date"}},
+				"c": {"date_histogram": {"field": "date", "calendar_interval": "day", "fixed_interval": "1d"}},
+				"d": {"date_histogram": {"field": "date", "calendar_interval": "2d"}},
+				"e": {"date_histogram": {"field": "date", "fixed_interval": "1M"}},
+				"f": {"date_histogram": {"field": "date", "calendar_interval": "day", "time_zone": "Mars/Olympus"}},
+				"g": {"date_histogram": {"field": "date", "calendar_interval": "day", "format": "date"}}
+			}`))
+			requireErrorKeys(t, err,
+				"aggs.a.date_histogram.field",
+				"aggs.b.date_histogram",
+				"aggs.c.date_histogram",
+				"aggs.d.date_histogram.calendar_interval",
+				"aggs.e.date_histogram.fixed_interval",
+				"aggs.f.date_histogram.time_zone",
+				"aggs.g.date_histogram.format",
+			)
+		})
+		t.Run("must parse date histogram", func(t *testing.T) {
+			aggs, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"date_histogram": {
+				"field": "date",
+				"calendar_interval": "month",
+				"time_zone": "+01:00",
+				"format": "2006-01",
+				"min_doc_count": 1
+			}}}`))
+			require.NoError(t, err)
+
+			a, ok := aggs["a"].(DateHistogram)
+			require.True(t, ok)
+			require.Equal(t, "date", a.Field)
+			require.Equal(t, date.Formats{"2006-01"}, a.Formats)
+			require.Equal(t, uint64(1), a.MinDocCount)
+			_, offset := time.Date(2023, 1, 1, 0, 0, 0, 0, a.Location).Zone()
+			require.Equal(t, 3600, offset)
+			require.NotNil(t, a.Interval)
+		})
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		t.Run("must fail for non-numeric fields", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"avg": {"field": "tag"}}, "b": {"stats": {}}}`))
+			requireErrorKeys(t, err, "aggs.a.avg.field", "aggs.b.stats.field")
+		})
+		t.Run("must fail if sub-aggregations are provided", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"avg": {"field": "price"}, "aggs": {"b": {"max": {"field": "price"}}}}}`))
+			requireErrorKeys(t, err, "aggs.a")
+		})
+		t.Run("must parse all metrics", func(t *testing.T) {
+			aggs, err := Parse(testSchema(), "aggs", json.RawMessage(`{
+				"min": {"min": {"field": "price"}},
+				"max": {"max": {"field": "price"}},
+				"avg": {"avg": {"field": "price"}},
+				"sum": {"sum": {"field": "price"}},
+				"stats": {"stats": {"field": "count"}}
+			}`))
+			require.NoError(t, err)
+			require.Equal(t, map[string]Aggregation{
+				"min":   Metric{Metric: MetricMin, Field: "price", Type: schema.TypeDouble},
+				"max":   Metric{Metric: MetricMax, Field: "price", Type: schema.TypeDouble},
+				"avg":   Metric{Metric: MetricAvg, Field: "price", Type: schema.TypeDouble},
+				"sum":   Metric{Metric: MetricSum, Field: "price", Type: schema.TypeDouble},
+				"stats": Metric{Metric: MetricStats, Field: "count", Type: schema.TypeLong},
+			}, aggs)
+		})
+	})
+
+	t.Run("sub-aggregations", func(t *testing.T) {
+		t.Run("must parse one level of sub-aggregations", func(t *testing.T) {
+			aggs, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {
+				"terms": {"field": "tag"},
+				"aggs": {"b": {"avg": {"field": "price"}}}
+			}}`))
+			require.NoError(t, err)
+			require.Equal(t, map[string]Aggregation{
+				"a": Terms{Field: "tag", Type: schema.TypeKeyword, Size: DefaultTermsSize, Aggs: map[string]Aggregation{
+					"b": Metric{Metric: MetricAvg, Field: "price", Type: schema.TypeDouble},
+				}},
+			}, aggs)
+		})
+		t.Run("must fail if aggregations are nested too deep", func(t *testing.T) {
+			_, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {
+				"terms": {"field": "tag"},
+				"aggs": {"b": {"terms": {"field": "bool"}, "aggs": {"c": {"avg": {"field": "price"}}}}}
+			}}`))
+			requireErrorKeys(t, err, "aggs.a.aggs.b.aggs")
+		})
+	})
+}
@@ -0,0 +1,366 @@
+package aggregation
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+)
+
+type BucketsResult struct {
+	Buckets []Bucket `json:"buckets"`
+}
+
+type Bucket struct {
+	Key          interface{}
+	KeyAsString  string
+	From         interface{}
+	To           interface{}
+	DocCount     uint64
+	Aggregations map[string]interface{}
+}
+
+// MarshalJSON puts sub-aggregation results next to the bucket fields
+func (b Bucket) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(b.Aggregations)+4)
+	for k, v := range b.Aggregations {
+		m[k] = v
+	}
+	m["key"] = b.Key
+	if b.KeyAsString != "" {
+		m["key_as_string"] = b.KeyAsString
+	}
+	m["doc_count"] = b.DocCount
+	if b.From != nil {
+		m["from"] = b.From
+	}
+	if b.To != nil {
+		m["to"] = b.To
+	}
+
+	return json.Marshal(m)
+}
+
+type MetricResult struct {
+	Value *float64 `json:"value"`
+}
+
+type StatsResult struct {
+	Count uint64   `json:"count"`
+	Min   *float64 `json:"min"`
+	Max   *float64 `json:"max"`
+	Avg   *float64 `json:"avg"`
+	Sum   float64  `json:"sum"`
+}
+
+// Run calculate aggregations over the matched documents
+func Run(aggs map[string]Aggregation, docs *roaring.Bitmap, values Values) (map[string]interface{}, error) {
+	errors := validation.Errors{}
+	result := run("aggs", aggs, docs, values, errors)
+	if len(errors) != 0 {
+		return nil, errors
+	}
+
+	return result, nil
+}
+
+func run(path string, aggs map[string]Aggregation, docs *roaring.Bitmap, values Values, errors validation.Errors) map[string]interface{} {
+	if len(aggs) == 0 {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(aggs))
+	for name, a := range aggs {
+		aggPath := path + "." + name
+		switch a := a.(type) {
+		case Terms:
+			result[name] = runTerms(aggPath, a, docs, values, errors)
+		case Range:
+			result[name] = runRange(aggPath, a, docs, values, errors)
+		case Histogram:
+			result[name] = runHistogram(aggPath, a, docs, values, errors)
+		case DateHistogram:
+			result[name] = runDateHistogram(aggPath, a, docs, values, errors)
+		case Metric:
+			result[name] = runMetric(a, docs, values)
+		}
+	}
+
+	return result
+}
+
+func runTerms(path string, a Terms, docs *roaring.Bitmap, values Values, errors validation.Errors) BucketsResult {
+	groups := make(map[interface{}]*roaring.Bitmap)
+	it := docs.Iterator()
+	for it.HasNext() {
+		ord := it.Next()
+
+		var key interface{}
+		switch a.Type {
+		case schema.TypeKeyword:
+			v, ok := values.Keyword(a.Field, ord)
+			if !ok {
+				continue
+			}
+			key = v
+		case schema.TypeIP:
+			v, ok := values.IP(a.Field, ord)
+			if !ok {
+				continue
+			}
+			key = v.String()
+		default:
+			v, ok := values.Numeric(a.Field, ord)
+			if !ok {
+				continue
+			}
+			key = bucketKey(a.Type, v)
+		}
+
+		bm, ok := groups[key]
+		if !ok {
+			bm = roaring.New()
+			groups[key] = bm
+		}
+		bm.Add(ord)
+	}
+
+	buckets := make([]Bucket, 0, len(groups))
+	for key, bm := range groups {
+		buckets = append(buckets, Bucket{Key: key, DocCount: bm.GetCardinality()})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].DocCount != buckets[j].DocCount {
+			return buckets[i].DocCount > buckets[j].DocCount
+		}
+		return keyLess(buckets[i].Key, buckets[j].Key)
+	})
+	if len(buckets) > a.Size {
+		buckets = buckets[:a.Size]
+	}
+
+	for i := range buckets {
+		buckets[i].Aggregations = run(path, a.Aggs, groups[buckets[i].Key], values, errors)
+	}
+
+	return BucketsResult{Buckets: buckets}
+}
+
+func bucketKey(t schema.Type, key uint64) interface{} {
+	if t == schema.TypeBool {
+		return key == 1
+	}
+
+	return docvalues.Decode(t, key)
+}
+
+func keyLess(a interface{}, b interface{}) bool {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string)) < 0
+	case bool:
+		return !a && b.(bool)
+	}
+
+	return false
+}
+
+func runRange(path string, a Range, docs *roaring.Bitmap, values Values, errors validation.Errors) BucketsResult {
+	buckets := make([]Bucket, 0, len(a.Ranges))
+	for _, r := range a.Ranges {
+		bm := roaring.New()
+		if min, max, ok, err := docvalues.Bounds(a.Type, nil, r.From, r.To, nil); err == nil && ok {
+			bm = roaring.And(docs, values.Range(a.Field, min, max))
+		}
+
+		buckets = append(buckets, Bucket{
+			Key:          r.Key,
+			From:         r.From,
+			To:           r.To,
+			DocCount:     bm.GetCardinality(),
+			Aggregations: run(path, a.Aggs, bm, values, errors),
+		})
+	}
+
+	return BucketsResult{Buckets: buckets}
+}
+
+func runHistogram(path string, a Histogram, docs *roaring.Bitmap, values Values, errors validation.Errors) BucketsResult {
+	groups := make(map[int64]*roaring.Bitmap)
+	it := docs.Iterator()
+	for it.HasNext() {
+		ord := it.Next()
+		v, ok := values.Numeric(a.Field, ord)
+		if !ok {
+			continue
+		}
+
+		n := int64(math.Floor(docvalues.Float(a.Type, v) / a.Interval))
+		bm, ok := groups[n]
+		if !ok {
+			bm = roaring.New()
+			groups[n] = bm
+		}
+		bm.Add(ord)
+	}
+
+	if len(groups) == 0 {
+		return BucketsResult{Buckets: []Bucket{}}
+	}
+
+	keys := make([]int64, 0, len(groups))
+	for n := range groups {
+		keys = append(keys, n)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	if a.MinDocCount == 0 {
+		// fill the gaps between the first and the last buckets with empty ones
+		first, last := keys[0], keys[len(keys)-1]
+		if last-first >= MaxBuckets {
+			errors[path] = validation.NewError("", "too many buckets, increase the interval")
+			return BucketsResult{}
+		}
+		keys = keys[:0]
+		for n := first; n <= last; n++ {
+			keys = append(keys, n)
+		}
+	} else if len(keys) > MaxBuckets {
+		errors[path] = validation.NewError("", "too many buckets, increase the interval")
+		return BucketsResult{}
+	}
+
+	buckets := make([]Bucket, 0, len(keys))
+	for _, n := range keys {
+		bm, ok := groups[n]
+		if !ok {
+			bm = roaring.New()
+		}
+		if bm.GetCardinality() < a.MinDocCount {
+			continue
+		}
+
+		buckets = append(buckets, Bucket{
+			Key:          float64(n) * a.Interval,
+			DocCount:     bm.GetCardinality(),
+			Aggregations: run(path, a.Aggs, bm, values, errors),
+		})
+	}
+
+	return BucketsResult{Buckets: buckets}
+}
+
+func runDateHistogram(path string, a DateHistogram, docs *roaring.Bitmap, values Values, errors validation.Errors) BucketsResult {
+	groups := make(map[int64]*roaring.Bitmap)
+	it := docs.Iterator()
+	for it.HasNext() {
+		ord := it.Next()
+		v, ok := values.Numeric(a.Field, ord)
+		if !ok {
+			continue
+		}
+
+		start := a.Interval.Floor(time.UnixMilli(docvalues.DecodeInt(v)).In(a.Location)).UnixMilli()
+		bm, ok := groups[start]
+		if !ok {
+			bm = roaring.New()
+			groups[start] = bm
+		}
+		bm.Add(ord)
+	}
+
+	if len(groups) == 0 {
+		return BucketsResult{Buckets: []Bucket{}}
+	}
+
+	keys := make([]int64, 0, len(groups))
+	for start := range groups {
+		keys = append(keys, start)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	if a.MinDocCount == 0 {
+		// calendar buckets vary in length, so the gaps are filled by walking from the first bucket to the last one
+		last := keys[len(keys)-1]
+		t := time.UnixMilli(keys[0]).In(a.Location)
+		keys = keys[:0]
+		for t.UnixMilli() <= last {
+			if len(keys) == MaxBuckets {
+				errors[path] = validation.NewError("", "too many buckets, increase the interval")
+				return BucketsResult{}
+			}
+			keys = append(keys, t.UnixMilli())
+			t = a.Interval.Next(t)
+		}
+	} else if len(keys) > MaxBuckets {
+		errors[path] = validation.NewError("", "too many buckets, increase the interval")
+		return BucketsResult{}
+	}
+
+	buckets := make([]Bucket, 0, len(keys))
+	for _, start := range keys {
+		bm, ok := groups[start]
+		if !ok {
+			bm = roaring.New()
+		}
+		if bm.GetCardinality() < a.MinDocCount {
+			continue
+		}
+
+		buckets = append(buckets, Bucket{
+			Key:          start,
+			KeyAsString:  a.Formats.Format(start, a.Location),
+			DocCount:     bm.GetCardinality(),
+			Aggregations: run(path, a.Aggs, bm, values, errors),
+		})
+	}
+
+	return BucketsResult{Buckets: buckets}
+}
+
+func runMetric(a Metric, docs *roaring.Bitmap, values Values) interface{} {
+	stats := StatsResult{}
+	var min, max float64
+
+	it := docs.Iterator()
+	for it.HasNext() {
+		v, ok := values.Numeric(a.Field, it.Next())
+		if !ok {
+			continue
+		}
+
+		f := docvalues.Float(a.Type, v)
+		if stats.Count == 0 || f < min {
+			min = f
+		}
+		if stats.Count == 0 || f > max {
+			max = f
+		}
+		stats.Sum += f
+		stats.Count++
+	}
+
+	if stats.Count != 0 {
+		avg := stats.Sum / float64(stats.Count)
+		stats.Min, stats.Max, stats.Avg = &min, &max, &avg
+	}
+
+	switch a.Metric {
+	case MetricMin:
+		return MetricResult{Value: stats.Min}
+	case MetricMax:
+		return MetricResult{Value: stats.Max}
+	case MetricAvg:
+		return MetricResult{Value: stats.Avg}
+	case MetricSum:
+		return MetricResult{Value: &stats.Sum}
+	}
+
+	return stats
+}
@@ -0,0 +1,173 @@
+package aggregation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func testValues() (*docvalues.Index, *roaring.Bitmap) {
+	values := docvalues.New(testSchema())
+	values.Add(1, schema.Source{"tag": "a", "bool": true, "price": json.Number("5"), "count": json.Number("1"), "date": "2023-01-15T10:00:00Z", "ip": "10.0.0.1"})
+	values.Add(2, schema.Source{"tag": "b", "bool": false, "price": json.Number("15"), "count": json.Number("2"), "date": "2023-03-01T00:30:00Z", "ip": "::ffff:10.0.0.1"})
+	values.Add(3, schema.Source{"tag": "a", "bool": true, "price": json.Number("25"), "count": json.Number("3"), "date": "2023-03-31T23:30:00Z", "ip": "2001:db8::1"})
+	values.Add(4, schema.Source{"tag": "c"})
+
+	return values, roaring.BitmapOf(1, 2, 3, 4)
+}
+
+func runAggs(t *testing.T, request string, docs *roaring.Bitmap, values Values) map[string]interface{} {
+	t.Helper()
+
+	aggs, err := Parse(testSchema(), "aggs", json.RawMessage(request))
+	require.NoError(t, err)
+	result, err := Run(aggs, docs, values)
+	require.NoError(t, err)
+
+	return result
+}
+
+func Test_Run(t *testing.T) {
+	values, docs := testValues()
+
+	t.Run("must return nil if there are no aggregations", func(t *testing.T) {
+		result, err := Run(nil, docs, values)
+		require.NoError(t, err)
+		require.Nil(t, result)
+	})
+
+	t.Run("terms", func(t *testing.T) {
+		result := runAggs(t, `{"tags": {"terms": {"field": "tag", "size": 2}}, "bools": {"terms": {"field": "bool"}}}`, docs, values)
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: "a", DocCount: 2},
+			{Key: "b", DocCount: 1},
+		}}, result["tags"])
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: true, DocCount: 2},
+			{Key: false, DocCount: 1},
+		}}, result["bools"])
+	})
+
+	t.Run("terms by ip", func(t *testing.T) {
+		result := runAggs(t, `{"ips": {"terms": {"field": "ip"}}}`, docs, values)
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: "10.0.0.1", DocCount: 2},
+			{Key: "2001:db8::1", DocCount: 1},
+		}}, result["ips"])
+	})
+
+	t.Run("terms must count only matched documents", func(t *testing.T) {
+		result := runAggs(t, `{"tags": {"terms": {"field": "tag"}}}`, roaring.BitmapOf(2, 4), values)
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: "b", DocCount: 1},
+			{Key: "c", DocCount: 1},
+		}}, result["tags"])
+	})
+
+	t.Run("range", func(t *testing.T) {
+		result := runAggs(t, `{"prices": {"range": {"field": "price", "ranges": [{"to": 15}, {"from": 15, "to": 25}, {"from": 25}]}}}`, docs, values)
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: "*-15", To: json.Number("15"), DocCount: 1},
+			{Key: "15-25", From: json.Number("15"), To: json.Number("25"), DocCount: 1},
+			{Key: "25-*", From: json.Number("25"), DocCount: 1},
+		}}, result["prices"])
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		result := runAggs(t, `{"a": {"histogram": {"field": "price", "interval": 10}}, "b": {"histogram": {"field": "price", "interval": 4, "min_doc_count": 1}}}`, docs, values)
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: 0.0, DocCount: 1},
+			{Key: 10.0, DocCount: 1},
+			{Key: 20.0, DocCount: 1},
+		}}, result["a"])
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: 4.0, DocCount: 1},
+			{Key: 12.0, DocCount: 1},
+			{Key: 24.0, DocCount: 1},
+		}}, result["b"])
+	})
+
+	t.Run("histogram must fill gaps with empty buckets", func(t *testing.T) {
+		result := runAggs(t, `{"a": {"histogram": {"field": "price", "interval": 5}}}`, roaring.BitmapOf(1, 2), values)
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: 5.0, DocCount: 1},
+			{Key: 10.0, DocCount: 0},
+			{Key: 15.0, DocCount: 1},
+		}}, result["a"])
+	})
+
+	t.Run("histogram must fail if there are too many buckets", func(t *testing.T) {
+		aggs, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"histogram": {"field": "price", "interval": 0.0001}}}`))
+		require.NoError(t, err)
+		_, err = Run(aggs, docs, values)
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "aggs.a")
+	})
+
+	t.Run("date histogram", func(t *testing.T) {
+		result := runAggs(t, `{
+			"a": {"date_histogram": {"field": "date", "calendar_interval": "month"}},
+			"b": {"date_histogram": {"field": "date", "calendar_interval": "1M", "time_zone": "+01:00", "format": "2006-01", "min_doc_count": 1}}
+		}`, docs, values)
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: int64(1672531200000), KeyAsString: "2023-01-01T00:00:00Z", DocCount: 1},
+			{Key: int64(1675209600000), KeyAsString: "2023-02-01T00:00:00Z", DocCount: 0},
+			{Key: int64(1677628800000), KeyAsString: "2023-03-01T00:00:00Z", DocCount: 2},
+		}}, result["a"])
+		require.Equal(t, BucketsResult{Buckets: []Bucket{
+			{Key: int64(1672527600000), KeyAsString: "2023-01", DocCount: 1},
+			{Key: int64(1677625200000), KeyAsString: "2023-03", DocCount: 1},
+			{Key: int64(1680303600000), KeyAsString: "2023-04", DocCount: 1},
+		}}, result["b"])
+	})
+
+	t.Run("date histogram must fail if there are too many buckets", func(t *testing.T) {
+		aggs, err := Parse(testSchema(), "aggs", json.RawMessage(`{"a": {"date_histogram": {"field": "date", "fixed_interval": "1s"}}}`))
+		require.NoError(t, err)
+		_, err = Run(aggs, docs, values)
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "aggs.a")
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		result := runAggs(t, `{
+			"min": {"min": {"field": "price"}},
+			"max": {"max": {"field": "price"}},
+			"avg": {"avg": {"field": "price"}},
+			"sum": {"sum": {"field": "price"}},
+			"stats": {"stats": {"field": "count"}}
+		}`, docs, values)
+
+		value := func(f float64) *float64 { return &f }
+		require.Equal(t, MetricResult{Value: value(5)}, result["min"])
+		require.Equal(t, MetricResult{Value: value(25)}, result["max"])
+		require.Equal(t, MetricResult{Value: value(15)}, result["avg"])
+		require.Equal(t, MetricResult{Value: value(45)}, result["sum"])
+		require.Equal(t, StatsResult{Count: 3, Min: value(1), Max: value(3), Avg: value(2), Sum: 6}, result["stats"])
+	})
+
+	t.Run("metrics must return null values if there are no documents", func(t *testing.T) {
+		result := runAggs(t, `{"avg": {"avg": {"field": "price"}}, "stats": {"stats": {"field": "price"}}}`, roaring.BitmapOf(4), values)
+		require.Equal(t, MetricResult{}, result["avg"])
+		require.Equal(t, StatsResult{}, result["stats"])
+	})
+
+	t.Run("sub-aggregations", func(t *testing.T) {
+		result := runAggs(t, `{"tags": {"terms": {"field": "tag"}, "aggs": {"price": {"sum": {"field": "price"}}}}}`, docs, values)
+
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"tags": {"buckets": [
+			{"key": "a", "doc_count": 2, "price": {"value": 30}},
+			{"key": "b", "doc_count": 1, "price": {"value": 15}},
+			{"key": "c", "doc_count": 1, "price": {"value": 0}}
+		]}}`, string(data))
+	})
+}
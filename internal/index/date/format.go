@@ -0,0 +1,168 @@
+package date
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f1monkey/search/pkg/errs"
+
+	// time zones are resolved without the system database
+	_ "time/tzdata"
+)
+
+const (
+	// FormatRFC3339 date and time with the time zone offset like 2006-01-02T15:04:05Z07:00, fractional seconds are optional
+	FormatRFC3339 = "rfc3339"
+	// FormatEpochMillis number of milliseconds since the epoch, as a number or a string
+	FormatEpochMillis = "epoch_millis"
+
+	// DefaultFormat used for the date fields without the format
+	DefaultFormat = FormatRFC3339 + formatSeparator + FormatEpochMillis
+
+	formatSeparator = "||"
+)
+
+// Formats the value can be parsed with, tried in order. Any format except the predefined ones is a Go time layout
+type Formats []string
+
+// ParseFormats split the formats separated by "||". Empty string gives the default formats
+func ParseFormats(format string) Formats {
+	if format == "" {
+		format = DefaultFormat
+	}
+
+	parts := strings.Split(format, formatSeparator)
+	result := make(Formats, 0, len(parts))
+	for _, p := range parts {
+		result = append(result, strings.TrimSpace(p))
+	}
+
+	return result
+}
+
+func (f Formats) Validate() error {
+	for _, format := range f {
+		switch format {
+		case FormatRFC3339, FormatEpochMillis:
+			continue
+		case "":
+			return errs.Errorf("empty format")
+		}
+
+		// a layout without any elements would match only itself
+		example := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(format)
+		if example == format {
+			return errs.Errorf("layout %q has no date or time elements", format)
+		}
+		if _, err := time.Parse(format, example); err != nil {
+			return errs.Errorf("invalid layout %q: %w", format, err)
+		}
+	}
+
+	return nil
+}
+
+// Parse convert the value to milliseconds since the epoch.
+// Layouts without the time zone are parsed in the location
+func (f Formats) Parse(v interface{}, loc *time.Location) (int64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if f.has(FormatEpochMillis) {
+			return parseMillis(v.String())
+		}
+		return 0, errs.Errorf("numbers are not allowed by the date format %q", f.String())
+	case string:
+		for _, format := range f {
+			var (
+				t   time.Time
+				err error
+			)
+			switch format {
+			case FormatEpochMillis:
+				ms, err := parseMillis(v)
+				if err == nil {
+					return ms, nil
+				}
+				continue
+			case FormatRFC3339:
+				t, err = time.Parse(time.RFC3339Nano, v)
+			default:
+				t, err = time.ParseInLocation(format, v, loc)
+			}
+			if err == nil {
+				return t.UnixMilli(), nil
+			}
+		}
+		return 0, errs.Errorf("cannot parse %q with the date format %q", v, f.String())
+	}
+
+	return 0, errs.Errorf("required date string or number, got %#v", v)
+}
+
+// Format convert milliseconds since the epoch to the string of the first format
+func (f Formats) Format(ms int64, loc *time.Location) string {
+	t := time.UnixMilli(ms).In(loc)
+
+	switch f[0] {
+	case FormatEpochMillis:
+		return strconv.FormatInt(ms, 10)
+	case FormatRFC3339:
+		return t.Format(time.RFC3339Nano)
+	}
+
+	return t.Format(f[0])
+}
+
+func (f Formats) String() string {
+	return strings.Join(f, formatSeparator)
+}
+
+func (f Formats) has(format string) bool {
+	for _, item := range f {
+		if item == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Millis convert the epoch milliseconds to the number the numeric doc values are encoded from
+func Millis(ms int64) json.Number {
+	return json.Number(strconv.FormatInt(ms, 10))
+}
+
+func parseMillis(s string) (int64, error) {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errs.Errorf("cannot parse %q as epoch millis", s)
+	}
+
+	return ms, nil
+}
+
+// ParseLocation get the time zone by its name like "Europe/Berlin" or the offset like "+01:00".
+// Empty string gives UTC
+func ParseLocation(s string) (*time.Location, error) {
+	if s == "" {
+		return time.UTC, nil
+	}
+
+	if s[0] == '+' || s[0] == '-' {
+		t, err := time.Parse("-07:00", s)
+		if err != nil {
+			return nil, errs.Errorf("invalid time zone offset %q", s)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(s, offset), nil
+	}
+
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return nil, errs.Errorf("unknown time zone %q", s)
+	}
+
+	return loc, nil
+}
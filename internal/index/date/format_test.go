@@ -0,0 +1,106 @@
+package date
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseFormats(t *testing.T) {
+	t.Run("must return default formats for empty string", func(t *testing.T) {
+		require.Equal(t, Formats{FormatRFC3339, FormatEpochMillis}, ParseFormats(""))
+	})
+
+	t.Run("must split formats", func(t *testing.T) {
+		require.Equal(t, Formats{"2006-01-02", FormatEpochMillis}, ParseFormats("2006-01-02 || epoch_millis"))
+	})
+}
+
+func Test_Formats_Validate(t *testing.T) {
+	t.Run("must accept predefined formats and layouts", func(t *testing.T) {
+		require.NoError(t, ParseFormats("rfc3339||epoch_millis||2006-01-02||02.01.2006 15:04").Validate())
+	})
+
+	t.Run("must fail for layouts without elements", func(t *testing.T) {
+		require.Error(t, ParseFormats("date").Validate())
+		require.Error(t, ParseFormats("rfc3339||").Validate())
+	})
+}
+
+func Test_Formats_Parse(t *testing.T) {
+	expected := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC).UnixMilli()
+
+	t.Run("must parse rfc3339", func(t *testing.T) {
+		ms, err := ParseFormats("").Parse("2023-04-05T08:07:08+02:00", time.UTC)
+		require.NoError(t, err)
+		require.Equal(t, expected, ms)
+
+		ms, err = ParseFormats("").Parse("2023-04-05T06:07:08.123Z", time.UTC)
+		require.NoError(t, err)
+		require.Equal(t, expected+123, ms)
+	})
+
+	t.Run("must parse epoch millis", func(t *testing.T) {
+		ms, err := ParseFormats("").Parse(json.Number("1680674828000"), time.UTC)
+		require.NoError(t, err)
+		require.Equal(t, expected, ms)
+
+		ms, err = ParseFormats("").Parse("1680674828000", time.UTC)
+		require.NoError(t, err)
+		require.Equal(t, expected, ms)
+	})
+
+	t.Run("must parse layouts in the location", func(t *testing.T) {
+		ms, err := ParseFormats("2006-01-02 15:04:05").Parse("2023-04-05 06:07:08", time.UTC)
+		require.NoError(t, err)
+		require.Equal(t, expected, ms)
+
+		loc := time.FixedZone("", 3600)
+		ms, err = ParseFormats("2006-01-02 15:04:05").Parse("2023-04-05 07:07:08", loc)
+		require.NoError(t, err)
+		require.Equal(t, expected, ms)
+	})
+
+	t.Run("must fail for values not matching the formats", func(t *testing.T) {
+		_, err := ParseFormats("rfc3339").Parse(json.Number("1"), time.UTC)
+		require.Error(t, err)
+		_, err = ParseFormats("").Parse("2023-04-05", time.UTC)
+		require.Error(t, err)
+		_, err = ParseFormats("").Parse(true, time.UTC)
+		require.Error(t, err)
+	})
+}
+
+func Test_Formats_Format(t *testing.T) {
+	ms := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC).UnixMilli()
+
+	require.Equal(t, "2023-04-05T08:07:08+02:00", ParseFormats("").Format(ms, time.FixedZone("", 7200)))
+	require.Equal(t, "1680674828000", ParseFormats("epoch_millis").Format(ms, time.UTC))
+	require.Equal(t, "2023-04-05", ParseFormats("2006-01-02||rfc3339").Format(ms, time.UTC))
+}
+
+func Test_ParseLocation(t *testing.T) {
+	t.Run("must parse names and offsets", func(t *testing.T) {
+		loc, err := ParseLocation("")
+		require.NoError(t, err)
+		require.Equal(t, time.UTC, loc)
+
+		loc, err = ParseLocation("Europe/Berlin")
+		require.NoError(t, err)
+		require.Equal(t, "Europe/Berlin", loc.String())
+
+		loc, err = ParseLocation("-05:30")
+		require.NoError(t, err)
+		_, offset := time.Date(2023, 1, 1, 0, 0, 0, 0, loc).Zone()
+		require.Equal(t, -(5*3600 + 30*60), offset)
+	})
+
+	t.Run("must fail for unknown time zones", func(t *testing.T) {
+		_, err := ParseLocation("Mars/Olympus")
+		require.Error(t, err)
+		_, err = ParseLocation("+25")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,174 @@
+package date
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// Interval splits the time into the buckets
+type Interval interface {
+	// Floor get the start of the bucket the time belongs to
+	Floor(t time.Time) time.Time
+	// Next get the start of the next bucket
+	Next(t time.Time) time.Time
+}
+
+// calendarUnit interval of the varying length aware of the time zone transitions and the calendar
+type calendarUnit byte
+
+const (
+	unitYear    calendarUnit = 'y'
+	unitQuarter calendarUnit = 'q'
+	unitMonth   calendarUnit = 'M'
+	unitWeek    calendarUnit = 'w'
+	unitDay     calendarUnit = 'd'
+	unitHour    calendarUnit = 'h'
+	unitMinute  calendarUnit = 'm'
+	unitSecond  calendarUnit = 's'
+)
+
+func parseUnit(c byte) (calendarUnit, error) {
+	switch c {
+	case 'H':
+		return unitHour, nil
+	case byte(unitYear), byte(unitMonth), byte(unitWeek), byte(unitDay), byte(unitHour), byte(unitMinute), byte(unitSecond):
+		return calendarUnit(c), nil
+	}
+
+	return 0, errs.Errorf("unknown unit %q", c)
+}
+
+func (u calendarUnit) Floor(t time.Time) time.Time {
+	return u.floor(t)
+}
+
+func (u calendarUnit) Next(t time.Time) time.Time {
+	return u.next(t)
+}
+
+func (u calendarUnit) floor(t time.Time) time.Time {
+	y, m, d := t.Date()
+	loc := t.Location()
+
+	switch u {
+	case unitYear:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+	case unitQuarter:
+		return time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, loc)
+	case unitMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	case unitWeek:
+		// weeks start on Monday
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+	case unitDay:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	case unitHour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, loc)
+	case unitMinute:
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, loc)
+	}
+
+	return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
+
+func (u calendarUnit) next(t time.Time) time.Time {
+	return u.add(t, 1)
+}
+
+func (u calendarUnit) add(t time.Time, n int) time.Time {
+	switch u {
+	case unitYear:
+		return t.AddDate(n, 0, 0)
+	case unitQuarter:
+		return t.AddDate(0, 3*n, 0)
+	case unitMonth:
+		return t.AddDate(0, n, 0)
+	case unitWeek:
+		return t.AddDate(0, 0, 7*n)
+	case unitDay:
+		return t.AddDate(0, 0, n)
+	case unitHour:
+		return t.Add(time.Duration(n) * time.Hour)
+	case unitMinute:
+		return t.Add(time.Duration(n) * time.Minute)
+	}
+
+	return t.Add(time.Duration(n) * time.Second)
+}
+
+var calendarIntervals = map[string]calendarUnit{
+	"year":    unitYear,
+	"1y":      unitYear,
+	"quarter": unitQuarter,
+	"1q":      unitQuarter,
+	"month":   unitMonth,
+	"1M":      unitMonth,
+	"week":    unitWeek,
+	"1w":      unitWeek,
+	"day":     unitDay,
+	"1d":      unitDay,
+	"hour":    unitHour,
+	"1h":      unitHour,
+	"minute":  unitMinute,
+	"1m":      unitMinute,
+	"second":  unitSecond,
+	"1s":      unitSecond,
+}
+
+// ParseCalendarInterval get the calendar interval by its name like "month" or a single unit like "1M"
+func ParseCalendarInterval(s string) (Interval, error) {
+	u, ok := calendarIntervals[s]
+	if !ok {
+		return nil, errs.Errorf("unknown calendar interval %q", s)
+	}
+
+	return u, nil
+}
+
+// fixedInterval interval of the fixed length. Buckets are aligned to the midnight of the time zone
+type fixedInterval time.Duration
+
+var fixedUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+}
+
+// ParseFixedInterval get the fixed interval like "90m" or "12h". Supported units are ms, s, m, h and d
+func ParseFixedInterval(s string) (Interval, error) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	unit, ok := fixedUnits[s[i:]]
+	if !ok || i == 0 {
+		return nil, errs.Errorf("invalid fixed interval %q", s)
+	}
+	n, err := strconv.ParseInt(s[:i], 10, 64)
+	if err != nil || n <= 0 {
+		return nil, errs.Errorf("invalid fixed interval %q", s)
+	}
+
+	return fixedInterval(time.Duration(n) * unit), nil
+}
+
+func (i fixedInterval) Floor(t time.Time) time.Time {
+	_, offset := t.Zone()
+	shift := int64(offset) * 1000
+	size := time.Duration(i).Milliseconds()
+
+	ms := t.UnixMilli() + shift
+	ms -= ((ms % size) + size) % size
+
+	return time.UnixMilli(ms - shift).In(t.Location())
+}
+
+func (i fixedInterval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
@@ -0,0 +1,74 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseCalendarInterval(t *testing.T) {
+	t.Run("must fail for unknown intervals", func(t *testing.T) {
+		_, err := ParseCalendarInterval("2d")
+		require.Error(t, err)
+	})
+
+	t.Run("must split time by calendar units", func(t *testing.T) {
+		tm := time.Date(2023, 8, 9, 10, 11, 12, 0, time.UTC)
+		cases := []struct {
+			interval string
+			floor    time.Time
+			next     time.Time
+		}{
+			{"year", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{"quarter", time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)},
+			{"1M", time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)},
+			{"week", time.Date(2023, 8, 7, 0, 0, 0, 0, time.UTC), time.Date(2023, 8, 14, 0, 0, 0, 0, time.UTC)},
+			{"day", time.Date(2023, 8, 9, 0, 0, 0, 0, time.UTC), time.Date(2023, 8, 10, 0, 0, 0, 0, time.UTC)},
+			{"1h", time.Date(2023, 8, 9, 10, 0, 0, 0, time.UTC), time.Date(2023, 8, 9, 11, 0, 0, 0, time.UTC)},
+			{"minute", time.Date(2023, 8, 9, 10, 11, 0, 0, time.UTC), time.Date(2023, 8, 9, 10, 12, 0, 0, time.UTC)},
+		}
+
+		for _, c := range cases {
+			i, err := ParseCalendarInterval(c.interval)
+			require.NoError(t, err)
+			floor := i.Floor(tm)
+			require.Equal(t, c.floor, floor, c.interval)
+			require.Equal(t, c.next, i.Next(floor), c.interval)
+		}
+	})
+
+	t.Run("must respect time zone transitions", func(t *testing.T) {
+		loc, err := ParseLocation("Europe/Berlin")
+		require.NoError(t, err)
+
+		i, err := ParseCalendarInterval("day")
+		require.NoError(t, err)
+
+		// the day of the switch to summer time is 23 hours long
+		floor := i.Floor(time.Date(2023, 3, 26, 12, 0, 0, 0, loc))
+		require.Equal(t, 23*time.Hour, i.Next(floor).Sub(floor))
+	})
+}
+
+func Test_ParseFixedInterval(t *testing.T) {
+	t.Run("must fail for invalid intervals", func(t *testing.T) {
+		for _, s := range []string{"", "d", "0d", "1M", "1.5h"} {
+			_, err := ParseFixedInterval(s)
+			require.Error(t, err, s)
+		}
+	})
+
+	t.Run("must align buckets to the midnight of the time zone", func(t *testing.T) {
+		i, err := ParseFixedInterval("12h")
+		require.NoError(t, err)
+
+		loc := time.FixedZone("", 3600)
+		floor := i.Floor(time.Date(2023, 8, 9, 10, 11, 12, 0, loc))
+		require.True(t, time.Date(2023, 8, 9, 0, 0, 0, 0, loc).Equal(floor))
+		require.True(t, time.Date(2023, 8, 9, 12, 0, 0, 0, loc).Equal(i.Next(floor)))
+
+		floor = i.Floor(time.Date(1969, 12, 31, 13, 0, 0, 0, time.UTC))
+		require.True(t, time.Date(1969, 12, 31, 12, 0, 0, 0, time.UTC).Equal(floor), "must floor times before the epoch")
+	})
+}
@@ -0,0 +1,112 @@
+package date
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+const (
+	mathNow       = "now"
+	mathSeparator = "||"
+)
+
+// ParseMath convert the date or the date math expression to milliseconds since the epoch.
+// The expression starts with "now" or with a date followed by "||" and contains the operations applied in order:
+// "+1d" and "-1d" add and subtract the number of units, "/d" rounds down to the unit.
+// Units are y (year), M (month), w (week), d (day), h or H (hour), m (minute) and s (second).
+// If roundUp is true, rounding gives the last millisecond of the unit instead of the first one,
+// so "lte": "now/d" includes the whole current day
+func ParseMath(v interface{}, formats Formats, now time.Time, loc *time.Location, roundUp bool) (int64, error) {
+	s, ok := v.(string)
+	if !ok {
+		return formats.Parse(v, loc)
+	}
+
+	var (
+		anchor time.Time
+		ops    string
+	)
+	switch {
+	case strings.HasPrefix(s, mathNow):
+		anchor, ops = now.In(loc), s[len(mathNow):]
+	case strings.Contains(s, mathSeparator):
+		i := strings.Index(s, mathSeparator)
+		ms, err := formats.Parse(s[:i], loc)
+		if err != nil {
+			return 0, err
+		}
+		anchor, ops = time.UnixMilli(ms).In(loc), s[i+len(mathSeparator):]
+	default:
+		return formats.Parse(s, loc)
+	}
+
+	t, err := applyMath(anchor, ops, roundUp)
+	if err != nil {
+		return 0, errs.Errorf("invalid date math %q: %w", s, err)
+	}
+
+	return t.UnixMilli(), nil
+}
+
+func applyMath(t time.Time, ops string, roundUp bool) (time.Time, error) {
+	for len(ops) > 0 {
+		op := ops[0]
+		ops = ops[1:]
+
+		switch op {
+		case '+', '-':
+			i := 0
+			for i < len(ops) && ops[i] >= '0' && ops[i] <= '9' {
+				i++
+			}
+			n := 1
+			if i > 0 {
+				var err error
+				if n, err = strconv.Atoi(ops[:i]); err != nil {
+					return t, err
+				}
+			}
+			if i >= len(ops) {
+				return t, errs.Errorf("unit is missing")
+			}
+			if op == '-' {
+				n = -n
+			}
+
+			var err error
+			if t, err = add(t, ops[i], n); err != nil {
+				return t, err
+			}
+			ops = ops[i+1:]
+		case '/':
+			if len(ops) == 0 {
+				return t, errs.Errorf("unit is missing")
+			}
+			u, err := parseUnit(ops[0])
+			if err != nil {
+				return t, err
+			}
+			t = u.floor(t)
+			if roundUp {
+				t = u.next(t).Add(-time.Millisecond)
+			}
+			ops = ops[1:]
+		default:
+			return t, errs.Errorf("unexpected %q", op)
+		}
+	}
+
+	return t, nil
+}
+
+func add(t time.Time, unit byte, n int) (time.Time, error) {
+	u, err := parseUnit(unit)
+	if err != nil {
+		return t, err
+	}
+
+	return u.add(t, n), nil
+}
@@ -0,0 +1,45 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseMath(t *testing.T) {
+	now := time.Date(2023, 4, 5, 6, 7, 8, 9000000, time.UTC)
+	formats := ParseFormats("")
+
+	cases := []struct {
+		expr     string
+		roundUp  bool
+		loc      *time.Location
+		expected time.Time
+	}{
+		{"now", false, time.UTC, now},
+		{"now-1d", false, time.UTC, now.AddDate(0, 0, -1)},
+		{"now+2h-30m", false, time.UTC, now.Add(90 * time.Minute)},
+		{"now-1d/d", false, time.UTC, time.Date(2023, 4, 4, 0, 0, 0, 0, time.UTC)},
+		{"now-1d/d", true, time.UTC, time.Date(2023, 4, 4, 23, 59, 59, 999000000, time.UTC)},
+		{"now/M", false, time.UTC, time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"now/y", false, time.UTC, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"now/w", false, time.UTC, time.Date(2023, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{"now/d", false, time.FixedZone("", -7*3600), time.Date(2023, 4, 4, 7, 0, 0, 0, time.UTC)},
+		{"2023-01-31T00:00:00Z||+1M", false, time.UTC, time.Date(2023, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"2023-01-31T10:00:00Z", false, time.UTC, time.Date(2023, 1, 31, 10, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		ms, err := ParseMath(c.expr, formats, now, c.loc, c.roundUp)
+		require.NoError(t, err, c.expr)
+		require.Equal(t, c.expected.UnixMilli(), ms, c.expr)
+	}
+
+	t.Run("must fail for invalid expressions", func(t *testing.T) {
+		for _, expr := range []string{"now-1", "now-1x", "now/", "now*1d", "invalid||+1d"} {
+			_, err := ParseMath(expr, formats, now, time.UTC, false)
+			require.Error(t, err, expr)
+		}
+	})
+}
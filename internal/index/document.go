@@ -0,0 +1,24 @@
+package index
+
+import (
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+)
+
+type Document struct {
+	ID     string        `json:"id"`
+	Source schema.Source `json:"source"`
+}
+
+func NewDocument(id string, source schema.Source) Document {
+	return Document{
+		ID:     id,
+		Source: source,
+	}
+}
+
+func (d Document) Validate() error {
+	return validation.ValidateStruct(&d,
+		validation.Field(&d.ID, validation.Required),
+	)
+}
@@ -0,0 +1,22 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Document_Validate(t *testing.T) {
+	t.Run("must fail if document id is empty", func(t *testing.T) {
+		d := NewDocument("", nil)
+		err := validation.Validate(d)
+		require.Error(t, err)
+	})
+
+	t.Run("must not fail if document is valid", func(t *testing.T) {
+		d := NewDocument("id", map[string]interface{}{"field": true})
+		err := validation.Validate(d)
+		require.NoError(t, err)
+	})
+}
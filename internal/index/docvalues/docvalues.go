@@ -0,0 +1,206 @@
+package docvalues
+
+import (
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/f1monkey/search/internal/index/date"
+	"github.com/f1monkey/search/internal/index/geo"
+	"github.com/f1monkey/search/internal/index/ip"
+	"github.com/f1monkey/search/internal/index/schema"
+)
+
+// Index keeps per-field column-oriented values of the documents
+type Index struct {
+	types   map[string]schema.Type
+	formats map[string]date.Formats
+	numeric map[string]*numeric
+	keyword map[string]map[uint32]string
+	ip      map[string]*addrs
+}
+
+// New create doc values for all numeric, bool, geo point, keyword and ip fields and sub-fields of the schema.
+// Bool values are kept in numeric columns as 0 and 1, date values as milliseconds since the epoch,
+// geo points as the keys interleaving their coordinates
+func New(s schema.Schema) *Index {
+	i := &Index{
+		types:   make(map[string]schema.Type),
+		formats: make(map[string]date.Formats),
+		numeric: make(map[string]*numeric),
+		keyword: make(map[string]map[uint32]string),
+		ip:      make(map[string]*addrs),
+	}
+	i.Extend(s)
+
+	return i
+}
+
+// Extend add empty columns for the fields of the schema without doc values yet.
+// Fields with doc values are kept as is
+func (i *Index) Extend(s schema.Schema) {
+	for name, f := range s.IndexedFields() {
+		if _, ok := i.types[name]; ok {
+			continue
+		}
+		switch {
+		case IsNumeric(f.Type), f.Type == schema.TypeBool, f.Type == schema.TypeGeoPoint:
+			i.types[name] = f.Type
+			i.numeric[name] = newNumeric()
+			if f.Type == schema.TypeDate {
+				i.formats[name] = f.DateFormats()
+			}
+		case f.Type == schema.TypeKeyword:
+			i.types[name] = f.Type
+			i.keyword[name] = make(map[uint32]string)
+		case f.Type == schema.TypeIP:
+			i.types[name] = f.Type
+			i.ip[name] = newAddrs()
+		}
+	}
+}
+
+// Add store field values of the document source under the provided ordinal.
+// Values of the sub-fields are taken by their dotted names, see schema.IndexedSource
+func (i *Index) Add(ord uint32, source schema.Source) {
+	for name, c := range i.numeric {
+		v, ok := source[name]
+		if !ok || v == nil {
+			continue
+		}
+		key, err := i.encode(name, v)
+		if err != nil {
+			// documents are validated before indexing
+			continue
+		}
+		c.add(ord, key)
+	}
+
+	for name, c := range i.keyword {
+		if v, ok := source[name].(string); ok {
+			c[ord] = v
+		}
+	}
+
+	for name, c := range i.ip {
+		if a, err := ip.ParseValue(source[name]); err == nil {
+			c.add(ord, a)
+		}
+	}
+}
+
+// Remove all field values of the document
+func (i *Index) Remove(ord uint32) {
+	for _, c := range i.numeric {
+		c.remove(ord)
+	}
+
+	for _, c := range i.keyword {
+		delete(c, ord)
+	}
+
+	for _, c := range i.ip {
+		c.remove(ord)
+	}
+}
+
+// Numeric get encoded value of the numeric, bool or geo point field
+func (i *Index) Numeric(field string, ord uint32) (uint64, bool) {
+	c, ok := i.numeric[field]
+	if !ok {
+		return 0, false
+	}
+
+	return c.value(ord)
+}
+
+// Keyword get value of the keyword field
+func (i *Index) Keyword(field string, ord uint32) (string, bool) {
+	c, ok := i.keyword[field]
+	if !ok {
+		return "", false
+	}
+
+	v, ok := c[ord]
+
+	return v, ok
+}
+
+// IP get value of the ip field
+func (i *Index) IP(field string, ord uint32) (ip.Addr, bool) {
+	c, ok := i.ip[field]
+	if !ok {
+		return ip.Addr{}, false
+	}
+
+	return c.value(ord)
+}
+
+// IPRange get ordinals of the documents with ip field value between min and max inclusive
+func (i *Index) IPRange(field string, min ip.Addr, max ip.Addr) *roaring.Bitmap {
+	c, ok := i.ip[field]
+	if !ok {
+		return roaring.New()
+	}
+
+	return c.rangeOf(min, max)
+}
+
+// Value get decoded field value of the document
+func (i *Index) Value(field string, ord uint32) (interface{}, bool) {
+	t := i.types[field]
+	switch t {
+	case schema.TypeKeyword:
+		return i.Keyword(field, ord)
+	case schema.TypeIP:
+		a, ok := i.IP(field, ord)
+		if !ok {
+			return nil, false
+		}
+		return a.String(), true
+	}
+
+	key, ok := i.Numeric(field, ord)
+	if !ok {
+		return nil, false
+	}
+	switch t {
+	case schema.TypeBool:
+		return key == 1, true
+	case schema.TypeGeoPoint:
+		return geo.Decode(key), true
+	}
+
+	return Decode(t, key), true
+}
+
+// Range get ordinals of the documents with encoded field value between min and max inclusive
+func (i *Index) Range(field string, min uint64, max uint64) *roaring.Bitmap {
+	c, ok := i.numeric[field]
+	if !ok {
+		return roaring.New()
+	}
+
+	return c.rangeOf(min, max)
+}
+
+func (i *Index) encode(field string, v interface{}) (uint64, error) {
+	t := i.types[field]
+	switch t {
+	case schema.TypeBool:
+		return EncodeBool(v)
+	case schema.TypeDate:
+		ms, err := i.formats[field].Parse(v, time.UTC)
+		if err != nil {
+			return 0, err
+		}
+		return EncodeInt(ms), nil
+	case schema.TypeGeoPoint:
+		p, err := geo.Parse(v)
+		if err != nil {
+			return 0, err
+		}
+		return geo.Encode(p), nil
+	}
+
+	return Encode(t, v)
+}
@@ -0,0 +1,115 @@
+package docvalues
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/geo"
+	"github.com/f1monkey/search/internal/index/ip"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchema() schema.Schema {
+	return schema.NewSchema(
+		map[string]schema.Field{
+			"long":    schema.NewField(schema.TypeLong, false, ""),
+			"double":  schema.NewField(schema.TypeDouble, false, ""),
+			"keyword": schema.NewField(schema.TypeKeyword, false, ""),
+			"bool":    schema.NewField(schema.TypeBool, false, ""),
+			"text":    schema.NewField(schema.TypeText, false, "analyzer"),
+			"date":    {Type: schema.TypeDate, Format: "2006-01-02||epoch_millis"},
+			"geo":     schema.NewField(schema.TypeGeoPoint, false, ""),
+			"ip":      schema.NewField(schema.TypeIP, false, ""),
+		},
+		nil,
+	)
+}
+
+func Test_Index(t *testing.T) {
+	i := New(testSchema())
+	require.Len(t, i.numeric, 5)
+	require.Len(t, i.keyword, 1)
+	require.Len(t, i.ip, 1)
+
+	i.Add(1, schema.Source{"long": json.Number("-5"), "double": json.Number("1.5"), "keyword": "a", "bool": true, "text": "a", "date": "2023-01-02", "geo": "52.52,13.405", "ip": "10.0.0.1"})
+	i.Add(2, schema.Source{"long": json.Number("10"), "double": nil, "bool": false, "date": json.Number("1000"), "ip": "2001:db8::1"})
+	i.Add(3, schema.Source{})
+
+	t.Run("must return field values", func(t *testing.T) {
+		v, ok := i.Numeric("long", 1)
+		require.True(t, ok)
+		require.Equal(t, EncodeInt(-5), v)
+
+		_, ok = i.Numeric("double", 2)
+		require.False(t, ok)
+		_, ok = i.Numeric("keyword", 1)
+		require.False(t, ok)
+
+		k, ok := i.Keyword("keyword", 1)
+		require.True(t, ok)
+		require.Equal(t, "a", k)
+		_, ok = i.Keyword("keyword", 2)
+		require.False(t, ok)
+		_, ok = i.Keyword("text", 1)
+		require.False(t, ok)
+	})
+
+	t.Run("must return decoded values", func(t *testing.T) {
+		cases := []struct {
+			field    string
+			ord      uint32
+			expected interface{}
+		}{
+			{"long", 1, json.Number("-5")},
+			{"double", 1, json.Number("1.5")},
+			{"keyword", 1, "a"},
+			{"bool", 1, true},
+			{"bool", 2, false},
+			{"date", 1, json.Number("1672617600000")},
+			{"date", 2, json.Number("1000")},
+			{"ip", 1, "10.0.0.1"},
+			{"ip", 2, "2001:db8::1"},
+		}
+		for _, c := range cases {
+			v, ok := i.Value(c.field, c.ord)
+			require.True(t, ok, c.field)
+			require.Equal(t, c.expected, v, c.field)
+		}
+
+		v, ok := i.Value("geo", 1)
+		require.True(t, ok)
+		require.InDelta(t, 52.52, v.(geo.Point).Lat, 1e-7)
+		require.InDelta(t, 13.405, v.(geo.Point).Lon, 1e-7)
+
+		_, ok = i.Value("double", 2)
+		require.False(t, ok)
+	})
+
+	t.Run("must find documents by range", func(t *testing.T) {
+		require.Equal(t, []uint32{1, 2}, i.Range("long", EncodeInt(-10), EncodeInt(10)).ToArray())
+		require.Equal(t, []uint32{1}, i.Range("double", EncodeFloat(1), EncodeFloat(2)).ToArray())
+		require.Equal(t, []uint32{2}, i.Range("date", EncodeInt(0), EncodeInt(1672617599999)).ToArray())
+		require.True(t, i.Range("unknown", 0, 100).IsEmpty())
+
+		addr := func(s string) ip.Addr {
+			a, err := ip.Parse(s)
+			require.NoError(t, err)
+			return a
+		}
+		require.Equal(t, []uint32{1}, i.IPRange("ip", addr("10.0.0.0"), addr("10.0.0.1")).ToArray())
+		require.Empty(t, i.IPRange("ip", addr("10.0.0.2"), addr("10.255.255.255")).ToArray())
+		require.Equal(t, []uint32{1, 2}, i.IPRange("ip", ip.Min, ip.Max).ToArray())
+		require.Equal(t, []uint32{2}, i.IPRange("ip", addr("2001:db8::1"), addr("2001:db8::1")).ToArray())
+	})
+
+	t.Run("must remove document values", func(t *testing.T) {
+		i.Remove(1)
+		require.Equal(t, []uint32{2}, i.Range("long", EncodeInt(-10), EncodeInt(10)).ToArray())
+		_, ok := i.Keyword("keyword", 1)
+		require.False(t, ok)
+		_, ok = i.IP("ip", 1)
+		require.False(t, ok)
+		require.Equal(t, []uint32{2}, i.IPRange("ip", ip.Min, ip.Max).ToArray())
+	})
+}
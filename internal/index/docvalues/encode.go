@@ -0,0 +1,163 @@
+package docvalues
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// IsNumeric check if values of the type are stored in the numeric column.
+// Dates are stored as milliseconds since the epoch
+func IsNumeric(t schema.Type) bool {
+	switch t {
+	case schema.TypeByte, schema.TypeShort, schema.TypeInteger, schema.TypeLong,
+		schema.TypeUnsignedLong, schema.TypeFloat, schema.TypeDouble, schema.TypeDate:
+		return true
+	}
+
+	return false
+}
+
+// Encode convert numeric value to the key preserving the order of values:
+// a < b if and only if Encode(a) < Encode(b)
+func Encode(t schema.Type, v interface{}) (uint64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, errs.Errorf("required number, got %#v", v)
+	}
+
+	switch t {
+	case schema.TypeByte, schema.TypeShort, schema.TypeInteger, schema.TypeLong, schema.TypeDate:
+		i, err := strconv.ParseInt(n.String(), 10, 64)
+		if err != nil {
+			return 0, errs.Errorf("cannot parse %q as int", n.String())
+		}
+		return EncodeInt(i), nil
+	case schema.TypeUnsignedLong:
+		u, err := strconv.ParseUint(n.String(), 10, 64)
+		if err != nil {
+			return 0, errs.Errorf("cannot parse %q as uint", n.String())
+		}
+		return u, nil
+	case schema.TypeFloat, schema.TypeDouble:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		if err != nil {
+			return 0, errs.Errorf("cannot parse %q as float", n.String())
+		}
+		return EncodeFloat(f), nil
+	}
+
+	return 0, errs.Errorf("type %q is not numeric", t)
+}
+
+// Decode convert the key back to the number
+func Decode(t schema.Type, key uint64) json.Number {
+	switch t {
+	case schema.TypeUnsignedLong:
+		return json.Number(strconv.FormatUint(key, 10))
+	case schema.TypeFloat, schema.TypeDouble:
+		return json.Number(strconv.FormatFloat(DecodeFloat(key), 'g', -1, 64))
+	}
+
+	return json.Number(strconv.FormatInt(DecodeInt(key), 10))
+}
+
+// Float convert the key to float64 value (with possible precision loss for large integers)
+func Float(t schema.Type, key uint64) float64 {
+	switch t {
+	case schema.TypeUnsignedLong:
+		return float64(key)
+	case schema.TypeFloat, schema.TypeDouble:
+		return DecodeFloat(key)
+	}
+
+	return float64(DecodeInt(key))
+}
+
+// EncodeBool convert bool value to the key: false is 0, true is 1
+func EncodeBool(v interface{}) (uint64, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return 0, errs.Errorf("required bool, got %#v", v)
+	}
+	if b {
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+func EncodeInt(v int64) uint64 {
+	return uint64(v) ^ (1 << 63)
+}
+
+func DecodeInt(key uint64) int64 {
+	return int64(key ^ (1 << 63))
+}
+
+func EncodeFloat(v float64) uint64 {
+	bits := math.Float64bits(v)
+	if bits>>63 == 1 {
+		return ^bits
+	}
+
+	return bits | (1 << 63)
+}
+
+func DecodeFloat(key uint64) float64 {
+	if key>>63 == 1 {
+		return math.Float64frombits(key &^ (1 << 63))
+	}
+
+	return math.Float64frombits(^key)
+}
+
+// Bounds convert range bounds to the inclusive range of keys.
+// The last result is false if no key can match the bounds
+func Bounds(t schema.Type, gt, gte, lt, lte interface{}) (uint64, uint64, bool, error) {
+	var min, max uint64 = 0, math.MaxUint64
+
+	if gte != nil {
+		k, err := Encode(t, gte)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		min = k
+	}
+	if gt != nil {
+		k, err := Encode(t, gt)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		if k == math.MaxUint64 {
+			return 0, 0, false, nil
+		}
+		if k+1 > min {
+			min = k + 1
+		}
+	}
+	if lte != nil {
+		k, err := Encode(t, lte)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		max = k
+	}
+	if lt != nil {
+		k, err := Encode(t, lt)
+		if err != nil {
+			return 0, 0, false, err
+		}
+		if k == 0 {
+			return 0, 0, false, nil
+		}
+		if k-1 < max {
+			max = k - 1
+		}
+	}
+
+	return min, max, min <= max, nil
+}
@@ -0,0 +1,109 @@
+package docvalues
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Encode(t *testing.T) {
+	t.Run("must fail for non-numeric values", func(t *testing.T) {
+		_, err := Encode(schema.TypeLong, "1")
+		require.Error(t, err)
+	})
+
+	t.Run("must fail if value does not match the type", func(t *testing.T) {
+		_, err := Encode(schema.TypeLong, json.Number("1.5"))
+		require.Error(t, err)
+		_, err = Encode(schema.TypeUnsignedLong, json.Number("-1"))
+		require.Error(t, err)
+		_, err = Encode(schema.TypeKeyword, json.Number("1"))
+		require.Error(t, err)
+	})
+
+	t.Run("must preserve order of values", func(t *testing.T) {
+		cases := []struct {
+			t      schema.Type
+			values []json.Number
+		}{
+			{schema.TypeLong, []json.Number{"-9223372036854775808", "-10", "-1", "0", "1", "10", "9223372036854775807"}},
+			{schema.TypeUnsignedLong, []json.Number{"0", "1", "10", "18446744073709551615"}},
+			{schema.TypeDouble, []json.Number{"-1e+300", "-1.5", "-0.1", "0", "0.1", "1", "1.5", "1e+300"}},
+		}
+
+		for _, c := range cases {
+			keys := make([]uint64, 0, len(c.values))
+			for _, v := range c.values {
+				k, err := Encode(c.t, v)
+				require.NoError(t, err)
+				keys = append(keys, k)
+				require.Equal(t, v, Decode(c.t, k))
+			}
+			require.True(t, sort.SliceIsSorted(keys, func(i, j int) bool { return keys[i] < keys[j] }), c.t)
+		}
+	})
+}
+
+func Test_EncodeBool(t *testing.T) {
+	k, err := EncodeBool(false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), k)
+
+	k, err = EncodeBool(true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), k)
+
+	_, err = EncodeBool("true")
+	require.Error(t, err)
+}
+
+func Test_Float(t *testing.T) {
+	require.Equal(t, -10.0, Float(schema.TypeLong, EncodeInt(-10)))
+	require.Equal(t, 10.0, Float(schema.TypeUnsignedLong, 10))
+	require.Equal(t, -1.5, Float(schema.TypeDouble, EncodeFloat(-1.5)))
+}
+
+func Test_Bounds(t *testing.T) {
+	t.Run("must return full range if no bounds provided", func(t *testing.T) {
+		min, max, ok, err := Bounds(schema.TypeLong, nil, nil, nil, nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(0), min)
+		require.Equal(t, uint64(math.MaxUint64), max)
+	})
+
+	t.Run("must convert exclusive bounds to inclusive ones", func(t *testing.T) {
+		min, max, ok, err := Bounds(schema.TypeLong, json.Number("1"), nil, json.Number("5"), nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, EncodeInt(2), min)
+		require.Equal(t, EncodeInt(4), max)
+	})
+
+	t.Run("must use the most restrictive bounds", func(t *testing.T) {
+		min, max, ok, err := Bounds(schema.TypeLong, json.Number("1"), json.Number("3"), json.Number("5"), json.Number("10"))
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, EncodeInt(3), min)
+		require.Equal(t, EncodeInt(4), max)
+	})
+
+	t.Run("must report empty range", func(t *testing.T) {
+		_, _, ok, err := Bounds(schema.TypeLong, json.Number("5"), nil, json.Number("5"), nil)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		_, _, ok, err = Bounds(schema.TypeUnsignedLong, nil, nil, json.Number("0"), nil)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("must fail for invalid bounds", func(t *testing.T) {
+		_, _, _, err := Bounds(schema.TypeLong, "a", nil, nil, nil)
+		require.Error(t, err)
+	})
+}
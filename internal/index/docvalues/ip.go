@@ -0,0 +1,58 @@
+package docvalues
+
+import (
+	"github.com/RoaringBitmap/roaring"
+	"github.com/f1monkey/search/internal/index/ip"
+)
+
+// addrs is a column of ip addresses. The high halves of the addresses are kept in the sorted numeric column
+// to find the candidates of the range, the low halves are checked against the exact bounds
+type addrs struct {
+	hi *numeric
+	lo map[uint32]uint64
+}
+
+func newAddrs() *addrs {
+	return &addrs{
+		hi: newNumeric(),
+		lo: make(map[uint32]uint64),
+	}
+}
+
+func (c *addrs) add(ord uint32, a ip.Addr) {
+	c.hi.add(ord, a.Hi)
+	c.lo[ord] = a.Lo
+}
+
+func (c *addrs) remove(ord uint32) {
+	c.hi.remove(ord)
+	delete(c.lo, ord)
+}
+
+func (c *addrs) value(ord uint32) (ip.Addr, bool) {
+	hi, ok := c.hi.value(ord)
+	if !ok {
+		return ip.Addr{}, false
+	}
+
+	return ip.Addr{Hi: hi, Lo: c.lo[ord]}, true
+}
+
+// rangeOf get ordinals of the documents with min <= address <= max
+func (c *addrs) rangeOf(min ip.Addr, max ip.Addr) *roaring.Bitmap {
+	candidates := c.hi.rangeOf(min.Hi, max.Hi)
+	if min.Lo == 0 && max.Lo == ^uint64(0) {
+		return candidates
+	}
+
+	result := roaring.New()
+	it := candidates.Iterator()
+	for it.HasNext() {
+		ord := it.Next()
+		if a, ok := c.value(ord); ok && a.Compare(min) >= 0 && a.Compare(max) <= 0 {
+			result.Add(ord)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,127 @@
+package docvalues
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring"
+)
+
+type entry struct {
+	key uint64
+	ord uint32
+}
+
+// numeric is a column of encoded numeric values.
+// Entries are kept sorted by key; new entries are buffered and merged on the next read,
+// removed entries are skipped on reads and compacted once they make up half of the column
+type numeric struct {
+	mtx     sync.Mutex
+	values  map[uint32]uint64
+	sorted  []entry
+	pending []entry
+	stale   int
+}
+
+func newNumeric() *numeric {
+	return &numeric{
+		values: make(map[uint32]uint64),
+	}
+}
+
+func (c *numeric) add(ord uint32, key uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.values[ord] = key
+	c.pending = append(c.pending, entry{key: key, ord: ord})
+}
+
+func (c *numeric) remove(ord uint32) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if _, ok := c.values[ord]; !ok {
+		return
+	}
+	delete(c.values, ord)
+	c.stale++
+}
+
+func (c *numeric) value(ord uint32) (uint64, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	v, ok := c.values[ord]
+
+	return v, ok
+}
+
+// rangeOf get ordinals of the documents with min <= value <= max
+func (c *numeric) rangeOf(min uint64, max uint64) *roaring.Bitmap {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.merge()
+
+	result := roaring.New()
+	start := sort.Search(len(c.sorted), func(i int) bool { return c.sorted[i].key >= min })
+	for i := start; i < len(c.sorted) && c.sorted[i].key <= max; i++ {
+		e := c.sorted[i]
+		if v, ok := c.values[e.ord]; ok && v == e.key {
+			result.Add(e.ord)
+		}
+	}
+
+	return result
+}
+
+func (c *numeric) merge() {
+	if c.stale > 0 && c.stale*2 >= len(c.sorted)+len(c.pending) {
+		c.compact()
+		return
+	}
+
+	if len(c.pending) == 0 {
+		return
+	}
+
+	sort.Slice(c.pending, func(i, j int) bool { return less(c.pending[i], c.pending[j]) })
+
+	merged := make([]entry, 0, len(c.sorted)+len(c.pending))
+	i, j := 0, 0
+	for i < len(c.sorted) && j < len(c.pending) {
+		if less(c.pending[j], c.sorted[i]) {
+			merged = append(merged, c.pending[j])
+			j++
+		} else {
+			merged = append(merged, c.sorted[i])
+			i++
+		}
+	}
+	merged = append(merged, c.sorted[i:]...)
+	merged = append(merged, c.pending[j:]...)
+
+	c.sorted = merged
+	c.pending = nil
+}
+
+func (c *numeric) compact() {
+	sorted := make([]entry, 0, len(c.values))
+	for ord, key := range c.values {
+		sorted = append(sorted, entry{key: key, ord: ord})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+
+	c.sorted = sorted
+	c.pending = nil
+	c.stale = 0
+}
+
+func less(a entry, b entry) bool {
+	if a.key != b.key {
+		return a.key < b.key
+	}
+
+	return a.ord < b.ord
+}
@@ -0,0 +1,62 @@
+package docvalues
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_numeric(t *testing.T) {
+	t.Run("must find values within range", func(t *testing.T) {
+		c := newNumeric()
+		c.add(1, 10)
+		c.add(2, 5)
+		c.add(3, 20)
+		c.add(4, 10)
+
+		require.Equal(t, []uint32{1, 2, 4}, c.rangeOf(5, 10).ToArray())
+		require.Equal(t, []uint32{3}, c.rangeOf(11, 100).ToArray())
+		require.True(t, c.rangeOf(21, 100).IsEmpty())
+	})
+
+	t.Run("must merge values added after read", func(t *testing.T) {
+		c := newNumeric()
+		c.add(1, 10)
+		require.Equal(t, []uint32{1}, c.rangeOf(0, 100).ToArray())
+
+		c.add(2, 1)
+		c.add(3, 50)
+		require.Equal(t, []uint32{1, 2, 3}, c.rangeOf(0, 100).ToArray())
+		require.Equal(t, []entry{{1, 2}, {10, 1}, {50, 3}}, c.sorted)
+	})
+
+	t.Run("must skip removed values and compact the column", func(t *testing.T) {
+		c := newNumeric()
+		c.add(1, 10)
+		c.add(2, 20)
+		c.add(3, 30)
+		c.add(4, 40)
+		require.Equal(t, []uint32{1, 2, 3, 4}, c.rangeOf(0, 100).ToArray())
+
+		c.remove(2)
+		require.Equal(t, []uint32{1, 3, 4}, c.rangeOf(0, 100).ToArray())
+		require.Len(t, c.sorted, 4)
+
+		c.remove(3)
+		require.Equal(t, []uint32{1, 4}, c.rangeOf(0, 100).ToArray())
+		require.Len(t, c.sorted, 2)
+		require.Equal(t, 0, c.stale)
+	})
+
+	t.Run("must return value of the document", func(t *testing.T) {
+		c := newNumeric()
+		c.add(1, 10)
+
+		v, ok := c.value(1)
+		require.True(t, ok)
+		require.Equal(t, uint64(10), v)
+
+		_, ok = c.value(2)
+		require.False(t, ok)
+	})
+}
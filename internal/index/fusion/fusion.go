@@ -0,0 +1,118 @@
+package fusion
+
+import (
+	"sort"
+
+	"github.com/invopop/validation"
+)
+
+// Method of combining ranked lists of several retrievers into one
+type Method string
+
+const (
+	// MethodRRF reciprocal rank fusion: documents are scored by the sum of weight / (rank constant + rank)
+	// over the lists they are found in. Used by default
+	MethodRRF Method = "rrf"
+	// MethodLinear weighted sum of the normalized scores of the lists
+	MethodLinear Method = "linear"
+)
+
+func (m Method) Validate() error {
+	return validation.Validate(string(m), validation.In(string(MethodRRF), string(MethodLinear)))
+}
+
+// Normalization of the retriever scores before the linear combination
+type Normalization string
+
+const (
+	// NormalizationMinMax scale the scores of the list to [0, 1]. Used by default
+	NormalizationMinMax Normalization = "min_max"
+	// NormalizationNone use the scores as is
+	NormalizationNone Normalization = "none"
+)
+
+func (n Normalization) Validate() error {
+	return validation.Validate(string(n), validation.In(string(NormalizationMinMax), string(NormalizationNone)))
+}
+
+const (
+	// DefaultRankConstant lower values give more weight to the top ranked documents
+	DefaultRankConstant = 60
+	// DefaultWindowSize number of the top documents of each retriever taken into the fusion
+	DefaultWindowSize = 100
+)
+
+// Hit document found by the retriever
+type Hit struct {
+	Ord   uint32
+	Score float64
+}
+
+// List ranked hits of the retriever, best first, and the weight of the retriever
+type List struct {
+	Hits   []Hit
+	Weight float64
+}
+
+// Top get up to n hits with the highest scores, best first. Of the equal scores the lower ordinal is better
+func Top(hits []Hit, n int) []Hit {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Ord < hits[j].Ord
+	})
+
+	if len(hits) > n {
+		hits = hits[:n]
+	}
+
+	return hits
+}
+
+// RRF fuse the lists by the ranks of the hits
+func RRF(lists []List, rankConstant int) map[uint32]float64 {
+	result := make(map[uint32]float64)
+	for _, l := range lists {
+		for i, h := range l.Hits {
+			result[h.Ord] += l.Weight / float64(rankConstant+i+1)
+		}
+	}
+
+	return result
+}
+
+// Linear fuse the lists by the weighted sum of the normalized scores of the hits
+func Linear(lists []List, normalization Normalization) map[uint32]float64 {
+	result := make(map[uint32]float64)
+	for _, l := range lists {
+		normalize := normalizer(l.Hits, normalization)
+		for _, h := range l.Hits {
+			result[h.Ord] += l.Weight * normalize(h.Score)
+		}
+	}
+
+	return result
+}
+
+func normalizer(hits []Hit, normalization Normalization) func(score float64) float64 {
+	if normalization == NormalizationNone || len(hits) == 0 {
+		return func(score float64) float64 { return score }
+	}
+
+	min, max := hits[0].Score, hits[0].Score
+	for _, h := range hits {
+		if h.Score < min {
+			min = h.Score
+		}
+		if h.Score > max {
+			max = h.Score
+		}
+	}
+	if max == min {
+		// all hits are equally relevant
+		return func(float64) float64 { return 1 }
+	}
+
+	return func(score float64) float64 { return (score - min) / (max - min) }
+}
@@ -0,0 +1,52 @@
+package fusion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Top(t *testing.T) {
+	hits := []Hit{{Ord: 3, Score: 1}, {Ord: 1, Score: 2}, {Ord: 2, Score: 1}, {Ord: 0, Score: 0.5}}
+	require.Equal(t, []Hit{{Ord: 1, Score: 2}, {Ord: 2, Score: 1}, {Ord: 3, Score: 1}}, Top(hits, 3))
+	require.Empty(t, Top(nil, 3))
+}
+
+func Test_RRF(t *testing.T) {
+	lexical := List{Hits: []Hit{{Ord: 1, Score: 10}, {Ord: 2, Score: 5}}, Weight: 1}
+	semantic := List{Hits: []Hit{{Ord: 2, Score: 0.9}, {Ord: 3, Score: 0.8}}, Weight: 2}
+
+	scores := RRF([]List{lexical, semantic}, 60)
+	require.Len(t, scores, 3)
+	require.InDelta(t, 1.0/61, scores[1], 1e-12)
+	require.InDelta(t, 1.0/62+2.0/61, scores[2], 1e-12)
+	require.InDelta(t, 2.0/62, scores[3], 1e-12)
+}
+
+func Test_Linear(t *testing.T) {
+	lexical := List{Hits: []Hit{{Ord: 1, Score: 10}, {Ord: 2, Score: 6}, {Ord: 3, Score: 2}}, Weight: 0.5}
+	semantic := List{Hits: []Hit{{Ord: 3, Score: 0.9}}, Weight: 2}
+
+	t.Run("must normalize scores of every list", func(t *testing.T) {
+		scores := Linear([]List{lexical, semantic}, NormalizationMinMax)
+		require.InDelta(t, 0.5, scores[1], 1e-12)
+		require.InDelta(t, 0.25, scores[2], 1e-12)
+		require.InDelta(t, 2, scores[3], 1e-12)
+	})
+
+	t.Run("must use raw scores without normalization", func(t *testing.T) {
+		scores := Linear([]List{lexical, semantic}, NormalizationNone)
+		require.InDelta(t, 5, scores[1], 1e-12)
+		require.InDelta(t, 3, scores[2], 1e-12)
+		require.InDelta(t, 2.8, scores[3], 1e-12)
+	})
+}
+
+func Test_Validate(t *testing.T) {
+	require.NoError(t, MethodRRF.Validate())
+	require.NoError(t, MethodLinear.Validate())
+	require.Error(t, Method("sum").Validate())
+	require.NoError(t, NormalizationMinMax.Validate())
+	require.NoError(t, NormalizationNone.Validate())
+	require.Error(t, Normalization("l2").Validate())
+}
@@ -0,0 +1,68 @@
+package geo
+
+import (
+	"math"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// BoundingBox area between the corners. The box crosses the antimeridian if its left edge is east of the right one
+type BoundingBox struct {
+	TopLeft     Point
+	BottomRight Point
+}
+
+func (b BoundingBox) Validate() error {
+	if err := b.TopLeft.Validate(); err != nil {
+		return err
+	}
+	if err := b.BottomRight.Validate(); err != nil {
+		return err
+	}
+	if b.TopLeft.Lat < b.BottomRight.Lat {
+		return errs.Errorf("top latitude %v must not be below bottom latitude %v", b.TopLeft.Lat, b.BottomRight.Lat)
+	}
+
+	return nil
+}
+
+// Contains check if the point is inside the box or on its edge
+func (b BoundingBox) Contains(p Point) bool {
+	if p.Lat > b.TopLeft.Lat || p.Lat < b.BottomRight.Lat {
+		return false
+	}
+	if b.crossesDateline() {
+		return p.Lon >= b.TopLeft.Lon || p.Lon <= b.BottomRight.Lon
+	}
+
+	return p.Lon >= b.TopLeft.Lon && p.Lon <= b.BottomRight.Lon
+}
+
+func (b BoundingBox) crossesDateline() bool {
+	return b.TopLeft.Lon > b.BottomRight.Lon
+}
+
+// Around get the smallest box containing all the points within the distance in meters from the center
+func Around(center Point, distance float64) BoundingBox {
+	delta := distance / earthRadius * 180 / math.Pi
+	top, bottom := center.Lat+delta, center.Lat-delta
+
+	// the circle contains a pole, so it covers all the longitudes
+	if top >= 90 || bottom <= -90 || delta >= 90 {
+		return BoundingBox{
+			TopLeft:     Point{Lat: math.Min(top, 90), Lon: -180},
+			BottomRight: Point{Lat: math.Max(bottom, -90), Lon: 180},
+		}
+	}
+
+	lonDelta := math.Asin(math.Sin(distance/earthRadius)/math.Cos(center.Lat*math.Pi/180)) * 180 / math.Pi
+	left, right := center.Lon-lonDelta, center.Lon+lonDelta
+	if left < -180 {
+		left += 360
+	}
+	if right > 180 {
+		right -= 360
+	}
+
+	return BoundingBox{TopLeft: Point{Lat: top, Lon: left}, BottomRight: Point{Lat: bottom, Lon: right}}
+}
@@ -0,0 +1,55 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BoundingBox(t *testing.T) {
+	t.Run("must validate corners", func(t *testing.T) {
+		require.NoError(t, BoundingBox{TopLeft: Point{Lat: 10, Lon: 170}, BottomRight: Point{Lat: -10, Lon: -170}}.Validate())
+		require.Error(t, BoundingBox{TopLeft: Point{Lat: -10, Lon: 0}, BottomRight: Point{Lat: 10, Lon: 1}}.Validate())
+		require.Error(t, BoundingBox{TopLeft: Point{Lat: 10, Lon: 0}, BottomRight: Point{Lat: -10, Lon: 200}}.Validate())
+	})
+
+	t.Run("must check if the box contains the point", func(t *testing.T) {
+		box := BoundingBox{TopLeft: Point{Lat: 10, Lon: -10}, BottomRight: Point{Lat: -10, Lon: 10}}
+		require.True(t, box.Contains(Point{Lat: 0, Lon: 0}))
+		require.True(t, box.Contains(Point{Lat: 10, Lon: 10}))
+		require.False(t, box.Contains(Point{Lat: 11, Lon: 0}))
+		require.False(t, box.Contains(Point{Lat: 0, Lon: 170}))
+
+		crossing := BoundingBox{TopLeft: Point{Lat: 10, Lon: 170}, BottomRight: Point{Lat: -10, Lon: -170}}
+		require.True(t, crossing.Contains(Point{Lat: 0, Lon: 175}))
+		require.True(t, crossing.Contains(Point{Lat: 0, Lon: -175}))
+		require.False(t, crossing.Contains(Point{Lat: 0, Lon: 0}))
+	})
+
+	t.Run("must get the box around the circle", func(t *testing.T) {
+		center := Point{Lat: 52.52, Lon: 13.405}
+		box := Around(center, 10000)
+		for _, p := range []Point{
+			{Lat: box.TopLeft.Lat, Lon: center.Lon},
+			{Lat: box.BottomRight.Lat, Lon: center.Lon},
+		} {
+			require.InDelta(t, 10000, Distance(center, p), 1)
+		}
+		require.Less(t, box.TopLeft.Lon, center.Lon)
+		require.Greater(t, box.BottomRight.Lon, center.Lon)
+		require.GreaterOrEqual(t, Distance(center, Point{Lat: center.Lat, Lon: box.TopLeft.Lon}), 10000.0)
+	})
+
+	t.Run("must cover all the longitudes if the circle contains the pole", func(t *testing.T) {
+		box := Around(Point{Lat: 89.9, Lon: 0}, 100000)
+		require.Equal(t, 90.0, box.TopLeft.Lat)
+		require.Equal(t, -180.0, box.TopLeft.Lon)
+		require.Equal(t, 180.0, box.BottomRight.Lon)
+	})
+
+	t.Run("must cross the antimeridian", func(t *testing.T) {
+		box := Around(Point{Lat: 0, Lon: 179.99}, 10000)
+		require.True(t, box.crossesDateline())
+		require.True(t, box.Contains(Point{Lat: 0, Lon: -179.99}))
+	})
+}
@@ -0,0 +1,88 @@
+package geo
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// earthRadius mean radius in meters
+const earthRadius = 6371008.8
+
+// Distance in meters between the points along the great circle
+func Distance(a Point, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// DefaultUnit distances without the unit are in meters
+const DefaultUnit = "m"
+
+// units lengths of the distance units in meters
+var units = map[string]float64{
+	"mm":  0.001,
+	"cm":  0.01,
+	"m":   1,
+	"km":  1000,
+	"in":  0.0254,
+	"ft":  0.3048,
+	"yd":  0.9144,
+	"mi":  1609.344,
+	"nmi": 1852,
+}
+
+// ParseUnit get the length of the distance unit in meters
+func ParseUnit(s string) (float64, error) {
+	u, ok := units[s]
+	if !ok {
+		return 0, errs.Errorf("unknown distance unit %q", s)
+	}
+
+	return u, nil
+}
+
+// ParseDistance convert the distance like "10km" or the number of meters to meters
+func ParseDistance(v interface{}) (float64, error) {
+	var (
+		d   float64
+		err error
+	)
+
+	switch v := v.(type) {
+	case json.Number:
+		d, err = v.Float64()
+	case string:
+		i := strings.LastIndexAny(v, "0123456789.") + 1
+		unit := strings.TrimSpace(v[i:])
+		if unit == "" {
+			unit = DefaultUnit
+		}
+
+		var u float64
+		if u, err = ParseUnit(unit); err != nil {
+			return 0, err
+		}
+		if d, err = strconv.ParseFloat(v[:i], 64); err != nil {
+			return 0, errs.Errorf("cannot parse distance %q", v)
+		}
+		d *= u
+	default:
+		return 0, errs.Errorf("required distance string or number, got %#v", v)
+	}
+	if err != nil {
+		return 0, errs.Errorf("cannot parse distance %v", v)
+	}
+	if d <= 0 || math.IsInf(d, 0) {
+		return 0, errs.Errorf("distance must be > 0")
+	}
+
+	return d, nil
+}
@@ -0,0 +1,37 @@
+package geo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Distance(t *testing.T) {
+	berlin, paris := Point{Lat: 52.52, Lon: 13.405}, Point{Lat: 48.8566, Lon: 2.3522}
+	require.InDelta(t, 878000, Distance(berlin, paris), 2000)
+	require.Equal(t, 0.0, Distance(berlin, berlin))
+}
+
+func Test_ParseDistance(t *testing.T) {
+	t.Run("must convert distances to meters", func(t *testing.T) {
+		for v, expected := range map[interface{}]float64{
+			json.Number("150"): 150,
+			"150":              150,
+			"1.5km":            1500,
+			"2 mi":             3218.688,
+			"10m":              10,
+		} {
+			d, err := ParseDistance(v)
+			require.NoError(t, err)
+			require.InDelta(t, expected, d, 1e-9, "%v", v)
+		}
+	})
+
+	t.Run("must fail for invalid distances", func(t *testing.T) {
+		for _, v := range []interface{}{"10 parsecs", "km", "0", json.Number("-1"), true} {
+			_, err := ParseDistance(v)
+			require.Error(t, err, "%v", v)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package geo
+
+import (
+	"strings"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+const (
+	geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+	// MaxGeohashLength longer geohashes exceed the precision of float64 coordinates
+	MaxGeohashLength = 12
+)
+
+// DecodeGeohash get the center of the geohash cell
+func DecodeGeohash(s string) (Point, error) {
+	if s == "" || len(s) > MaxGeohashLength {
+		return Point{}, errs.Errorf("geohash must contain from 1 to %d characters", MaxGeohashLength)
+	}
+
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	even := true
+	for _, c := range strings.ToLower(s) {
+		idx := strings.IndexRune(geohashAlphabet, c)
+		if idx < 0 {
+			return Point{}, errs.Errorf("invalid geohash character %q", c)
+		}
+
+		// bits alternate starting with the longitude
+		for bit := 4; bit >= 0; bit-- {
+			set := idx&(1<<bit) != 0
+			if even {
+				mid := (minLon + maxLon) / 2
+				if set {
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if set {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+
+	return Point{Lat: (minLat + maxLat) / 2, Lon: (minLon + maxLon) / 2}, nil
+}
+
+// EncodeGeohash get the geohash of the point with the number of characters
+func EncodeGeohash(p Point, length int) string {
+	key := Encode(p)
+
+	var sb strings.Builder
+	for i := 0; i < length && i < MaxGeohashLength; i++ {
+		sb.WriteByte(geohashAlphabet[key>>(59-5*i)&0x1f])
+	}
+
+	return sb.String()
+}
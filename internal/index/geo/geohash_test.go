@@ -0,0 +1,32 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Geohash(t *testing.T) {
+	t.Run("must decode the center of the cell", func(t *testing.T) {
+		p, err := DecodeGeohash("EZS42")
+		require.NoError(t, err)
+		require.InDelta(t, 42.6049, p.Lat, 0.0001)
+		require.InDelta(t, -5.6030, p.Lon, 0.0001)
+
+		_, err = DecodeGeohash("ezs42ezs42ezs")
+		require.Error(t, err)
+	})
+
+	t.Run("must encode the point", func(t *testing.T) {
+		require.Equal(t, "ezs42", EncodeGeohash(Point{Lat: 42.605, Lon: -5.603}, 5))
+		require.Equal(t, "u33dc", EncodeGeohash(Point{Lat: 52.52, Lon: 13.405}, 5))
+	})
+
+	t.Run("must decode the encoded point", func(t *testing.T) {
+		p := Point{Lat: -33.8688, Lon: 151.2093}
+		decoded, err := DecodeGeohash(EncodeGeohash(p, MaxGeohashLength))
+		require.NoError(t, err)
+		require.InDelta(t, p.Lat, decoded.Lat, 1e-6)
+		require.InDelta(t, p.Lon, decoded.Lon, 1e-6)
+	})
+}
@@ -0,0 +1,130 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// Points are stored as 64-bit keys interleaving 32 bits of the longitude and the latitude starting with the longitude,
+// the same way geohashes do. Every geohash cell covers a continuous range of the keys,
+// so the points inside the area are found by the range lookups of the cells covering it
+
+const (
+	coordBits = 32
+	// maxCoverCells limits the number of cells (and the range lookups) the area is covered with
+	maxCoverCells = 64
+)
+
+// KeyRange inclusive range of the keys
+type KeyRange struct {
+	Min uint64
+	Max uint64
+}
+
+// Encode convert the point to the key
+func Encode(p Point) uint64 {
+	return spread(quantize(p.Lon, 180))<<1 | spread(quantize(p.Lat, 90))
+}
+
+// Decode convert the key to the center of its cell
+func Decode(key uint64) Point {
+	return Point{
+		Lat: dequantize(compact(key), 90),
+		Lon: dequantize(compact(key>>1), 180),
+	}
+}
+
+// Cover get the ranges of the keys of all points inside the box.
+// The ranges may contain the keys of the points outside of the box near its edges
+func (b BoundingBox) Cover() []KeyRange {
+	if b.crossesDateline() {
+		west := BoundingBox{TopLeft: b.TopLeft, BottomRight: Point{Lat: b.BottomRight.Lat, Lon: 180}}
+		east := BoundingBox{TopLeft: Point{Lat: b.TopLeft.Lat, Lon: -180}, BottomRight: b.BottomRight}
+		return merge(append(west.Cover(), east.Cover()...))
+	}
+
+	minX, maxX := quantize(b.TopLeft.Lon, 180), quantize(b.BottomRight.Lon, 180)
+	minY, maxY := quantize(b.BottomRight.Lat, 90), quantize(b.TopLeft.Lat, 90)
+
+	// the most precise level the box is covered with a limited number of cells on
+	level := 0
+	for level < coordBits {
+		shift := coordBits - level - 1
+		cells := (uint64(maxX>>shift) - uint64(minX>>shift) + 1) * (uint64(maxY>>shift) - uint64(minY>>shift) + 1)
+		if cells > maxCoverCells {
+			break
+		}
+		level++
+	}
+
+	shift := coordBits - level
+	size := uint64(1)<<(2*shift) - 1
+	result := make([]KeyRange, 0, maxCoverCells)
+	for x := uint64(minX) >> shift; x <= uint64(maxX)>>shift; x++ {
+		for y := uint64(minY) >> shift; y <= uint64(maxY)>>shift; y++ {
+			min := spread(uint32(x<<shift))<<1 | spread(uint32(y<<shift))
+			result = append(result, KeyRange{Min: min, Max: min | size})
+		}
+	}
+
+	return merge(result)
+}
+
+// merge sort the ranges and join the adjacent ones
+func merge(ranges []KeyRange) []KeyRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Min < ranges[j].Min })
+
+	result := ranges[:0]
+	for _, r := range ranges {
+		if n := len(result); n > 0 && result[n-1].Max != math.MaxUint64 && result[n-1].Max+1 >= r.Min {
+			if r.Max > result[n-1].Max {
+				result[n-1].Max = r.Max
+			}
+			continue
+		}
+		result = append(result, r)
+	}
+
+	return result
+}
+
+// quantize map the coordinate from [-max, max] to the 32-bit cell number
+func quantize(v float64, max float64) uint32 {
+	q := math.Floor((v + max) / (2 * max) * (1 << coordBits))
+	switch {
+	case q < 0:
+		return 0
+	case q > math.MaxUint32:
+		return math.MaxUint32
+	}
+
+	return uint32(q)
+}
+
+func dequantize(q uint32, max float64) float64 {
+	return (float64(q)+0.5)/(1<<coordBits)*(2*max) - max
+}
+
+// spread put the bits of the value to the even positions
+func spread(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+
+	return x
+}
+
+// compact collect the bits of the even positions
+func compact(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+
+	return uint32(x)
+}
@@ -0,0 +1,59 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Encode(t *testing.T) {
+	t.Run("must decode the encoded point", func(t *testing.T) {
+		for _, p := range []Point{{0, 0}, {52.52, 13.405}, {-90, -180}, {90, 180}, {-33.8688, 151.2093}} {
+			decoded := Decode(Encode(p))
+			require.InDelta(t, p.Lat, decoded.Lat, 1e-7)
+			require.InDelta(t, p.Lon, decoded.Lon, 1e-7)
+		}
+	})
+
+	t.Run("must keep the points of the geohash cell together", func(t *testing.T) {
+		a, b := Encode(Point{Lat: 52.52, Lon: 13.40}), Encode(Point{Lat: 52.53, Lon: 13.41})
+		require.Equal(t, a>>40, b>>40)
+	})
+}
+
+func Test_BoundingBox_Cover(t *testing.T) {
+	inside := func(ranges []KeyRange, key uint64) bool {
+		for _, r := range ranges {
+			if key >= r.Min && key <= r.Max {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("must cover the points inside the box", func(t *testing.T) {
+		box := BoundingBox{TopLeft: Point{Lat: 53, Lon: 13}, BottomRight: Point{Lat: 52, Lon: 14}}
+		ranges := box.Cover()
+		require.LessOrEqual(t, len(ranges), maxCoverCells)
+
+		require.True(t, inside(ranges, Encode(Point{Lat: 52.52, Lon: 13.405})))
+		require.True(t, inside(ranges, Encode(Point{Lat: 53, Lon: 13})))
+		require.True(t, inside(ranges, Encode(Point{Lat: 52, Lon: 14})))
+		require.False(t, inside(ranges, Encode(Point{Lat: 48.85, Lon: 2.35})))
+	})
+
+	t.Run("must cover the boxes crossing the antimeridian", func(t *testing.T) {
+		box := BoundingBox{TopLeft: Point{Lat: 10, Lon: 170}, BottomRight: Point{Lat: -10, Lon: -170}}
+		ranges := box.Cover()
+
+		require.True(t, inside(ranges, Encode(Point{Lat: 0, Lon: 175})))
+		require.True(t, inside(ranges, Encode(Point{Lat: 0, Lon: -175})))
+		require.False(t, inside(ranges, Encode(Point{Lat: 0, Lon: 0})))
+	})
+
+	t.Run("must cover the whole world with a single range", func(t *testing.T) {
+		box := BoundingBox{TopLeft: Point{Lat: 90, Lon: -180}, BottomRight: Point{Lat: -90, Lon: 180}}
+		require.Equal(t, []KeyRange{{Min: 0, Max: math.MaxUint64}}, box.Cover())
+	})
+}
@@ -0,0 +1,101 @@
+package geo
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// Point location on the Earth in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func (p Point) Validate() error {
+	if p.Lat < -90 || p.Lat > 90 {
+		return errs.Errorf("latitude %v must be between -90 and 90", p.Lat)
+	}
+	if p.Lon < -180 || p.Lon > 180 {
+		return errs.Errorf("longitude %v must be between -180 and 180", p.Lon)
+	}
+
+	return nil
+}
+
+// Parse get the point from the {"lat": 1, "lon": 2} object, the "lat,lon" string or the geohash.
+// Geohashes give the center of their cells
+func Parse(v interface{}) (Point, error) {
+	var (
+		p   Point
+		err error
+	)
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		p, err = parseObject(v)
+	case string:
+		if strings.Contains(v, ",") {
+			p, err = parseString(v)
+		} else {
+			p, err = DecodeGeohash(v)
+		}
+	default:
+		return Point{}, errs.Errorf("required object with lat and lon, \"lat,lon\" string or geohash, got %#v", v)
+	}
+	if err != nil {
+		return Point{}, err
+	}
+
+	return p, p.Validate()
+}
+
+func parseObject(v map[string]interface{}) (Point, error) {
+	if len(v) != 2 {
+		return Point{}, errs.Errorf("object must contain only lat and lon")
+	}
+
+	lat, err := coordinate(v, "lat")
+	if err != nil {
+		return Point{}, err
+	}
+	lon, err := coordinate(v, "lon")
+	if err != nil {
+		return Point{}, err
+	}
+
+	return Point{Lat: lat, Lon: lon}, nil
+}
+
+func coordinate(v map[string]interface{}, key string) (float64, error) {
+	switch n := v[key].(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	case nil:
+		return 0, errs.Errorf("%s is required", key)
+	}
+
+	return 0, errs.Errorf("%s must be a number, got %#v", key, v[key])
+}
+
+func parseString(v string) (Point, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 2 {
+		return Point{}, errs.Errorf("cannot parse %q as \"lat,lon\"", v)
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return Point{}, errs.Errorf("cannot parse latitude of %q", v)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return Point{}, errs.Errorf("cannot parse longitude of %q", v)
+	}
+
+	return Point{Lat: lat, Lon: lon}, nil
+}
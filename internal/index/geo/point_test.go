@@ -0,0 +1,47 @@
+package geo
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parse(t *testing.T) {
+	t.Run("must parse objects", func(t *testing.T) {
+		p, err := Parse(map[string]interface{}{"lat": json.Number("52.5"), "lon": json.Number("-13.4")})
+		require.NoError(t, err)
+		require.Equal(t, Point{Lat: 52.5, Lon: -13.4}, p)
+	})
+
+	t.Run("must parse strings", func(t *testing.T) {
+		p, err := Parse("52.5, -13.4")
+		require.NoError(t, err)
+		require.Equal(t, Point{Lat: 52.5, Lon: -13.4}, p)
+	})
+
+	t.Run("must parse geohashes", func(t *testing.T) {
+		p, err := Parse("ezs42")
+		require.NoError(t, err)
+		require.InDelta(t, 42.605, p.Lat, 0.01)
+		require.InDelta(t, -5.603, p.Lon, 0.01)
+	})
+
+	t.Run("must fail for invalid values", func(t *testing.T) {
+		for _, v := range []interface{}{
+			json.Number("1"),
+			map[string]interface{}{"lat": json.Number("1")},
+			map[string]interface{}{"lat": json.Number("1"), "lng": json.Number("1")},
+			map[string]interface{}{"lat": "1", "lon": json.Number("1")},
+			map[string]interface{}{"lat": json.Number("91"), "lon": json.Number("1")},
+			"1,2,3",
+			"a,1",
+			"1,181",
+			"ezs4a",
+			"",
+		} {
+			_, err := Parse(v)
+			require.Error(t, err, "%#v", v)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package index
 
 import (
+	"regexp"
+
 	"github.com/f1monkey/search/internal/index/schema"
 	"github.com/invopop/validation"
 )
@@ -10,9 +12,12 @@ type Index struct {
 	Schema schema.Schema `json:"schema"`
 }
 
+// nameRegex index names are used as file names, so they are limited to the characters safe for them
+var nameRegex = regexp.MustCompile("^[a-z0-9_-]+$")
+
 func (i Index) Validate() error {
 	return validation.ValidateStruct(&i,
-		validation.Field(&i.Name, validation.Required),
+		validation.Field(&i.Name, validation.Required, validation.Match(nameRegex).Error("must contain only lowercase letters, digits, underscores and hyphens")),
 		validation.Field(&i.Schema, validation.Required),
 	)
 }
@@ -15,6 +15,17 @@ func Test_Index_Validate(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("must fail if index name is not a safe file name", func(t *testing.T) {
+		for _, name := range []string{".", "..", "../name", "name/name", "Name", "name.dat"} {
+			i := Index{
+				Name:   name,
+				Schema: schema.Schema{Fields: map[string]schema.Field{"field": {Type: schema.TypeBool}}},
+			}
+			err := validation.Validate(i)
+			require.Error(t, err, name)
+		}
+	})
+
 	t.Run("must fail if schema is omitted", func(t *testing.T) {
 		i := Index{Name: "name"}
 		err := validation.Validate(i)
@@ -29,7 +40,7 @@ func Test_Index_Validate(t *testing.T) {
 
 	t.Run("must not fail if index is valid", func(t *testing.T) {
 		i := Index{
-			Name: "name",
+			Name: "name_1-2",
 			Schema: schema.Schema{
 				Fields: map[string]schema.Field{
 					"field": {
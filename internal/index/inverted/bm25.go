@@ -0,0 +1,77 @@
+package inverted
+
+import (
+	"math"
+
+	"github.com/f1monkey/search/internal/index/schema"
+)
+
+// stats keeps per-field statistics required for BM25 scoring
+type stats struct {
+	similarity  schema.BM25
+	freqs       map[string]map[uint32]uint32 // term -> ordinal -> term frequency
+	lengths     map[uint32]uint32            // ordinal -> field length in terms
+	totalLength uint64
+}
+
+func newStats(similarity schema.BM25) *stats {
+	return &stats{
+		similarity: similarity,
+		freqs:      make(map[string]map[uint32]uint32),
+		lengths:    make(map[uint32]uint32),
+	}
+}
+
+func (s *stats) add(ord uint32, tokens []string) {
+	for _, t := range tokens {
+		m, ok := s.freqs[t]
+		if !ok {
+			m = make(map[uint32]uint32)
+			s.freqs[t] = m
+		}
+		m[ord]++
+	}
+
+	s.lengths[ord] = uint32(len(tokens))
+	s.totalLength += uint64(len(tokens))
+}
+
+func (s *stats) remove(ord uint32, tokens []string) {
+	for _, t := range tokens {
+		m, ok := s.freqs[t]
+		if !ok {
+			continue
+		}
+		delete(m, ord)
+		if len(m) == 0 {
+			delete(s.freqs, t)
+		}
+	}
+
+	if l, ok := s.lengths[ord]; ok {
+		s.totalLength -= uint64(l)
+		delete(s.lengths, ord)
+	}
+}
+
+// idf inverse document frequency of the term found in df documents
+func (s *stats) idf(df uint64) float64 {
+	n := float64(len(s.lengths))
+	d := float64(df)
+
+	return math.Log(1 + (n-d+0.5)/(d+0.5))
+}
+
+// tf saturated and length-normalized term frequency of the term in the document
+func (s *stats) tf(term string, ord uint32) float64 {
+	freq := float64(s.freqs[term][ord])
+	if freq == 0 {
+		return 0
+	}
+
+	avgLength := float64(s.totalLength) / float64(len(s.lengths))
+	length := float64(s.lengths[ord])
+	k1, b := s.similarity.K1, s.similarity.B
+
+	return freq * (k1 + 1) / (freq + k1*(1-b+b*length/avgLength))
+}
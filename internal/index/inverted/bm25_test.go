@@ -0,0 +1,38 @@
+package inverted
+
+import (
+	"math"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_stats(t *testing.T) {
+	s := newStats(schema.BM25{K1: 1.2, B: 0.75})
+	s.add(1, []string{"hello", "world", "hello"})
+	s.add(2, []string{"hello"})
+
+	t.Run("must collect term frequencies and field lengths", func(t *testing.T) {
+		require.Equal(t, uint32(2), s.freqs["hello"][1])
+		require.Equal(t, uint32(1), s.freqs["hello"][2])
+		require.Equal(t, map[uint32]uint32{1: 3, 2: 1}, s.lengths)
+		require.Equal(t, uint64(4), s.totalLength)
+	})
+
+	t.Run("must calculate bm25 components", func(t *testing.T) {
+		require.InDelta(t, math.Log(1+(2-1+0.5)/(1+0.5)), s.idf(1), 1e-9)
+
+		avg := 4.0 / 2.0
+		expected := 2 * (1.2 + 1) / (2 + 1.2*(1-0.75+0.75*3/avg))
+		require.InDelta(t, expected, s.tf("hello", 1), 1e-9)
+		require.Equal(t, 0.0, s.tf("world", 2))
+	})
+
+	t.Run("must remove document statistics", func(t *testing.T) {
+		s.remove(1, []string{"hello", "world", "hello"})
+		require.NotContains(t, s.freqs, "world")
+		require.Equal(t, map[uint32]uint32{2: 1}, s.lengths)
+		require.Equal(t, uint64(1), s.totalLength)
+	})
+}
@@ -0,0 +1,199 @@
+package inverted
+
+import (
+	"strconv"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/f1monkey/search/internal/index/analyzer"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// Index maps field terms to posting lists of document ordinals
+type Index struct {
+	fields map[string]*field
+}
+
+type field struct {
+	analyzer analyzer.Func
+	terms    map[string]*roaring.Bitmap
+	stats    *stats
+}
+
+// New create inverted index for all text, keyword, bool and all fields and sub-fields of the schema
+func New(s schema.Schema) (*Index, error) {
+	i := &Index{fields: make(map[string]*field)}
+	if err := i.Extend(s); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Extend add empty posting lists for the fields of the schema not indexed yet.
+// Fields already indexed are kept as is
+func (i *Index) Extend(s schema.Schema) error {
+	fields := make(map[string]*field)
+	for name, f := range s.IndexedFields() {
+		if _, ok := i.fields[name]; ok {
+			continue
+		}
+		switch f.Type {
+		case schema.TypeText, schema.TypeAll:
+			fa, ok := s.Analyzers[f.Analyzer]
+			if !ok {
+				return errs.Errorf("unknown analyzer %q for field %q", f.Analyzer, name)
+			}
+			a, err := fa.Build()
+			if err != nil {
+				return errs.Errorf("analyzer %q build err: %w", f.Analyzer, err)
+			}
+			fields[name] = newField(a, newStats(f.Similarity()))
+		case schema.TypeKeyword, schema.TypeBool:
+			fields[name] = newField(nil, nil)
+		}
+	}
+
+	for name, f := range fields {
+		i.fields[name] = f
+	}
+
+	return nil
+}
+
+func newField(a analyzer.Func, st *stats) *field {
+	return &field{
+		analyzer: a,
+		terms:    make(map[string]*roaring.Bitmap),
+		stats:    st,
+	}
+}
+
+// Add index the document source under the provided ordinal.
+// Values of the sub-fields are taken by their dotted names, see schema.IndexedSource
+func (i *Index) Add(ord uint32, source schema.Source) {
+	for name, f := range i.fields {
+		tokens := f.tokens(source[name])
+		if len(tokens) == 0 {
+			continue
+		}
+
+		for _, term := range tokens {
+			bm, ok := f.terms[term]
+			if !ok {
+				bm = roaring.New()
+				f.terms[term] = bm
+			}
+			bm.Add(ord)
+		}
+
+		if f.stats != nil {
+			f.stats.add(ord, tokens)
+		}
+	}
+}
+
+// Remove the ordinal from all posting lists the document source was indexed to
+func (i *Index) Remove(ord uint32, source schema.Source) {
+	for name, f := range i.fields {
+		tokens := f.tokens(source[name])
+		for _, term := range tokens {
+			bm, ok := f.terms[term]
+			if !ok {
+				continue
+			}
+			bm.Remove(ord)
+			if bm.IsEmpty() {
+				delete(f.terms, term)
+			}
+		}
+
+		if f.stats != nil {
+			f.stats.remove(ord, tokens)
+		}
+	}
+}
+
+// Term get posting list of the term. The result must not be modified
+func (i *Index) Term(fieldName string, term string) *roaring.Bitmap {
+	f, ok := i.fields[fieldName]
+	if !ok {
+		return roaring.New()
+	}
+
+	bm, ok := f.terms[term]
+	if !ok {
+		return roaring.New()
+	}
+
+	return bm
+}
+
+// Analyze split the text to terms the same way field values are indexed
+func (i *Index) Analyze(fieldName string, text string) []string {
+	f, ok := i.fields[fieldName]
+	if !ok {
+		return nil
+	}
+
+	return f.tokens(text)
+}
+
+// Scorer get relevance scorer of the terms for the field.
+// Text fields are scored with BM25, other fields give a constant score for every matched term
+func (i *Index) Scorer(fieldName string, terms []string) func(ord uint32) float64 {
+	f, ok := i.fields[fieldName]
+	if !ok {
+		return func(uint32) float64 { return 0 }
+	}
+
+	if f.stats == nil {
+		return func(ord uint32) float64 {
+			var score float64
+			for _, t := range terms {
+				if bm, ok := f.terms[t]; ok && bm.Contains(ord) {
+					score++
+				}
+			}
+			return score
+		}
+	}
+
+	idfs := make([]float64, len(terms))
+	for n, t := range terms {
+		var df uint64
+		if bm, ok := f.terms[t]; ok {
+			df = bm.GetCardinality()
+		}
+		idfs[n] = f.stats.idf(df)
+	}
+
+	return func(ord uint32) float64 {
+		var score float64
+		for n, t := range terms {
+			score += idfs[n] * f.stats.tf(t, ord)
+		}
+		return score
+	}
+}
+
+func (f *field) tokens(value interface{}) []string {
+	var terms []string
+	switch v := value.(type) {
+	case string:
+		terms = []string{v}
+	case bool:
+		terms = []string{strconv.FormatBool(v)}
+	case []string:
+		// values copied to the field of type all
+		terms = v
+	default:
+		return nil
+	}
+
+	if f.analyzer == nil {
+		return terms
+	}
+
+	return f.analyzer(terms)
+}
@@ -0,0 +1,153 @@
+package inverted
+
+import (
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/analyzer"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchema() schema.Schema {
+	return schema.NewSchema(
+		map[string]schema.Field{
+			"text":    schema.NewField(schema.TypeText, false, "whitespace"),
+			"keyword": schema.NewField(schema.TypeKeyword, false, ""),
+			"bool":    schema.NewField(schema.TypeBool, false, ""),
+			"long":    schema.NewField(schema.TypeLong, false, ""),
+			"all":     schema.NewField(schema.TypeAll, false, "whitespace"),
+		},
+		map[string]schema.FieldAnalyzer{
+			"whitespace": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}},
+		},
+	)
+}
+
+func Test_New(t *testing.T) {
+	t.Run("must fail if text field analyzer is unknown", func(t *testing.T) {
+		_, err := New(schema.NewSchema(
+			map[string]schema.Field{"text": schema.NewField(schema.TypeText, false, "unknown")},
+			nil,
+		))
+		require.Error(t, err)
+	})
+
+	t.Run("must index only text, keyword, bool and all fields", func(t *testing.T) {
+		i, err := New(testSchema())
+		require.NoError(t, err)
+		require.Len(t, i.fields, 4)
+		require.NotContains(t, i.fields, "long")
+	})
+}
+
+func Test_Index_Extend(t *testing.T) {
+	t.Run("must add new fields keeping the indexed ones", func(t *testing.T) {
+		i, err := New(testSchema())
+		require.NoError(t, err)
+		i.Add(1, schema.Source{"keyword": "a"})
+
+		s := testSchema()
+		s.Fields["new"] = schema.NewField(schema.TypeKeyword, false, "")
+		require.NoError(t, i.Extend(s))
+		require.Len(t, i.fields, 5)
+		require.Equal(t, []uint32{1}, i.Term("keyword", "a").ToArray())
+	})
+
+	t.Run("must not change the index if failed to add a field", func(t *testing.T) {
+		i, err := New(testSchema())
+		require.NoError(t, err)
+
+		s := testSchema()
+		s.Fields["new"] = schema.NewField(schema.TypeKeyword, false, "")
+		s.Fields["unknown"] = schema.NewField(schema.TypeText, false, "unknown")
+		require.Error(t, i.Extend(s))
+		require.Len(t, i.fields, 4)
+	})
+}
+
+func Test_Index_Add(t *testing.T) {
+	i, err := New(testSchema())
+	require.NoError(t, err)
+
+	i.Add(1, schema.Source{"text": "hello world", "keyword": "hello world", "bool": true, "all": []string{"hello world", "hello"}})
+	i.Add(2, schema.Source{"text": "hello", "keyword": "hello", "bool": false})
+
+	t.Run("must analyze text fields", func(t *testing.T) {
+		require.Equal(t, []uint32{1, 2}, i.Term("text", "hello").ToArray())
+		require.Equal(t, []uint32{1}, i.Term("text", "world").ToArray())
+		require.True(t, i.Term("text", "hello world").IsEmpty())
+	})
+
+	t.Run("must not analyze keyword fields", func(t *testing.T) {
+		require.Equal(t, []uint32{1}, i.Term("keyword", "hello world").ToArray())
+		require.Equal(t, []uint32{2}, i.Term("keyword", "hello").ToArray())
+		require.True(t, i.Term("keyword", "world").IsEmpty())
+	})
+
+	t.Run("must index bool fields", func(t *testing.T) {
+		require.Equal(t, []uint32{1}, i.Term("bool", "true").ToArray())
+		require.Equal(t, []uint32{2}, i.Term("bool", "false").ToArray())
+	})
+
+	t.Run("must analyze all copied values of all fields", func(t *testing.T) {
+		require.Equal(t, []uint32{1}, i.Term("all", "hello").ToArray())
+		require.Equal(t, []uint32{1}, i.Term("all", "world").ToArray())
+	})
+
+	t.Run("must return empty posting list for unknown field", func(t *testing.T) {
+		require.True(t, i.Term("unknown", "hello").IsEmpty())
+	})
+}
+
+func Test_Index_Remove(t *testing.T) {
+	i, err := New(testSchema())
+	require.NoError(t, err)
+
+	i.Add(1, schema.Source{"text": "hello world", "keyword": "hello"})
+	i.Add(2, schema.Source{"text": "hello", "keyword": "hello"})
+	i.Remove(1, schema.Source{"text": "hello world", "keyword": "hello"})
+
+	require.Equal(t, []uint32{2}, i.Term("text", "hello").ToArray())
+	require.True(t, i.Term("text", "world").IsEmpty())
+	require.NotContains(t, i.fields["text"].terms, "world", "must drop empty posting lists")
+	require.Equal(t, []uint32{2}, i.Term("keyword", "hello").ToArray())
+}
+
+func Test_Index_Analyze(t *testing.T) {
+	i, err := New(testSchema())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"hello", "world"}, i.Analyze("text", "hello world"))
+	require.Equal(t, []string{"hello world"}, i.Analyze("keyword", "hello world"))
+	require.Nil(t, i.Analyze("long", "1"))
+}
+
+func Test_Index_Scorer(t *testing.T) {
+	i, err := New(testSchema())
+	require.NoError(t, err)
+
+	i.Add(1, schema.Source{"text": "hello world", "keyword": "hello"})
+	i.Add(2, schema.Source{"text": "hello hello world foo bar baz", "keyword": "world"})
+	i.Add(3, schema.Source{"text": "world"})
+
+	t.Run("must score rare terms higher", func(t *testing.T) {
+		scorer := i.Scorer("text", []string{"hello"})
+		require.Greater(t, scorer(1), 0.0)
+		require.Equal(t, 0.0, scorer(3))
+
+		require.Greater(t, i.Scorer("text", []string{"foo"})(2), i.Scorer("text", []string{"world"})(2))
+	})
+
+	t.Run("must prefer shorter fields", func(t *testing.T) {
+		scorer := i.Scorer("text", []string{"world"})
+		require.Greater(t, scorer(3), scorer(1))
+		require.Greater(t, scorer(1), scorer(2))
+	})
+
+	t.Run("must give constant score for not analyzed fields", func(t *testing.T) {
+		scorer := i.Scorer("keyword", []string{"hello", "world"})
+		require.Equal(t, 1.0, scorer(1))
+		require.Equal(t, 1.0, scorer(2))
+		require.Equal(t, 0.0, scorer(3))
+	})
+}
@@ -0,0 +1,165 @@
+package ip
+
+import (
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"strings"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// Addr IPv4 or IPv6 address as a 128-bit number. IPv4 addresses are mapped to ::ffff:0:0/96,
+// so the addresses of both versions are compared in the same space
+type Addr struct {
+	Hi uint64
+	Lo uint64
+}
+
+var (
+	// Min the lowest address
+	Min = Addr{}
+	// Max the highest address
+	Max = Addr{Hi: math.MaxUint64, Lo: math.MaxUint64}
+)
+
+// Parse get the address from the IPv4 or IPv6 string
+func Parse(s string) (Addr, error) {
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return Addr{}, errs.Errorf("invalid ip address %q", s)
+	}
+
+	return fromNetip(a), nil
+}
+
+// ParseValue get the address from the document or query value
+func ParseValue(v interface{}) (Addr, error) {
+	s, ok := v.(string)
+	if !ok {
+		return Addr{}, errs.Errorf("required ip address string, got %#v", v)
+	}
+
+	return Parse(s)
+}
+
+// ParseCIDR get the first and the last addresses of the network like "10.0.0.0/8".
+// A single address gives itself as both bounds
+func ParseCIDR(s string) (Addr, Addr, error) {
+	if !strings.Contains(s, "/") {
+		a, err := Parse(s)
+		return a, a, err
+	}
+
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return Addr{}, Addr{}, errs.Errorf("invalid cidr %q", s)
+	}
+
+	bits := p.Bits()
+	if p.Addr().Is4() {
+		bits += 96
+	}
+
+	first := fromNetip(p.Masked().Addr())
+	last := first
+	if bits < 64 {
+		last.Hi |= math.MaxUint64 >> bits
+		last.Lo = math.MaxUint64
+	} else if bits < 128 {
+		last.Lo |= math.MaxUint64 >> (bits - 64)
+	}
+
+	return first, last, nil
+}
+
+func fromNetip(a netip.Addr) Addr {
+	b := a.Unmap().As16()
+
+	return Addr{Hi: binary.BigEndian.Uint64(b[:8]), Lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+func (a Addr) String() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], a.Hi)
+	binary.BigEndian.PutUint64(b[8:], a.Lo)
+
+	return netip.AddrFrom16(b).Unmap().String()
+}
+
+// Compare return -1 if a < b, 1 if a > b and 0 if they are equal
+func (a Addr) Compare(b Addr) int {
+	switch {
+	case a.Hi < b.Hi, a.Hi == b.Hi && a.Lo < b.Lo:
+		return -1
+	case a == b:
+		return 0
+	}
+
+	return 1
+}
+
+// next get the following address. Max has no next address
+func (a Addr) next() Addr {
+	if a.Lo == math.MaxUint64 {
+		return Addr{Hi: a.Hi + 1}
+	}
+
+	return Addr{Hi: a.Hi, Lo: a.Lo + 1}
+}
+
+// prev get the preceding address. Min has no previous address
+func (a Addr) prev() Addr {
+	if a.Lo == 0 {
+		return Addr{Hi: a.Hi - 1, Lo: math.MaxUint64}
+	}
+
+	return Addr{Hi: a.Hi, Lo: a.Lo - 1}
+}
+
+// Bounds convert range bounds to the inclusive range of addresses.
+// The last result is false if no address can match the bounds
+func Bounds(gt, gte, lt, lte interface{}) (Addr, Addr, bool, error) {
+	min, max := Min, Max
+
+	if gte != nil {
+		a, err := ParseValue(gte)
+		if err != nil {
+			return Addr{}, Addr{}, false, err
+		}
+		min = a
+	}
+	if gt != nil {
+		a, err := ParseValue(gt)
+		if err != nil {
+			return Addr{}, Addr{}, false, err
+		}
+		if a == Max {
+			return Addr{}, Addr{}, false, nil
+		}
+		if a.next().Compare(min) > 0 {
+			min = a.next()
+		}
+	}
+	if lte != nil {
+		a, err := ParseValue(lte)
+		if err != nil {
+			return Addr{}, Addr{}, false, err
+		}
+		max = a
+	}
+	if lt != nil {
+		a, err := ParseValue(lt)
+		if err != nil {
+			return Addr{}, Addr{}, false, err
+		}
+		if a == Min {
+			return Addr{}, Addr{}, false, nil
+		}
+		if a.prev().Compare(max) < 0 {
+			max = a.prev()
+		}
+	}
+
+	return min, max, min.Compare(max) <= 0, nil
+}
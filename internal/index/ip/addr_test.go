@@ -0,0 +1,105 @@
+package ip
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, s string) Addr {
+	t.Helper()
+
+	a, err := Parse(s)
+	require.NoError(t, err)
+
+	return a
+}
+
+func Test_Parse(t *testing.T) {
+	t.Run("must parse and format addresses", func(t *testing.T) {
+		for _, s := range []string{"10.0.0.1", "0.0.0.0", "255.255.255.255", "::1", "2001:db8::ff00:42:8329"} {
+			require.Equal(t, s, mustParse(t, s).String())
+		}
+		require.Equal(t, "10.0.0.1", mustParse(t, "::ffff:10.0.0.1").String())
+	})
+
+	t.Run("must fail for invalid addresses", func(t *testing.T) {
+		for _, v := range []interface{}{"10.0.0", "10.0.0.256", "example.com", "", json.Number("1")} {
+			_, err := ParseValue(v)
+			require.Error(t, err, "%#v", v)
+		}
+	})
+
+	t.Run("must compare addresses", func(t *testing.T) {
+		require.Equal(t, -1, mustParse(t, "10.0.0.1").Compare(mustParse(t, "10.0.0.2")))
+		require.Equal(t, 1, mustParse(t, "10.0.1.0").Compare(mustParse(t, "10.0.0.255")))
+		require.Equal(t, 0, mustParse(t, "10.0.0.1").Compare(mustParse(t, "::ffff:10.0.0.1")))
+		require.Equal(t, -1, mustParse(t, "::1").Compare(mustParse(t, "0.0.0.0")))
+		require.Equal(t, -1, mustParse(t, "255.255.255.255").Compare(mustParse(t, "2001:db8::")))
+	})
+}
+
+func Test_ParseCIDR(t *testing.T) {
+	t.Run("must get network bounds", func(t *testing.T) {
+		cases := []struct {
+			cidr  string
+			first string
+			last  string
+		}{
+			{"10.0.0.0/8", "10.0.0.0", "10.255.255.255"},
+			{"192.168.1.77/24", "192.168.1.0", "192.168.1.255"},
+			{"10.0.0.1/32", "10.0.0.1", "10.0.0.1"},
+			{"10.0.0.1", "10.0.0.1", "10.0.0.1"},
+			{"2001:db8::/32", "2001:db8::", "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"},
+			{"2001:db8::/96", "2001:db8::", "2001:db8::ffff:ffff"},
+			{"::/0", "::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		}
+		for _, c := range cases {
+			first, last, err := ParseCIDR(c.cidr)
+			require.NoError(t, err, c.cidr)
+			require.Equal(t, c.first, first.String(), c.cidr)
+			require.Equal(t, c.last, last.String(), c.cidr)
+		}
+	})
+
+	t.Run("must fail for invalid networks", func(t *testing.T) {
+		for _, s := range []string{"10.0.0.0/33", "10.0.0.0/", "/8", "10.0.0/8"} {
+			_, _, err := ParseCIDR(s)
+			require.Error(t, err, s)
+		}
+	})
+}
+
+func Test_Bounds(t *testing.T) {
+	t.Run("must convert exclusive bounds", func(t *testing.T) {
+		min, max, ok, err := Bounds("10.0.0.255", nil, "10.0.2.0", nil)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "10.0.1.0", min.String())
+		require.Equal(t, "10.0.1.255", max.String())
+	})
+
+	t.Run("must use the narrowest bounds", func(t *testing.T) {
+		min, max, ok, err := Bounds("10.0.0.1", "10.0.0.5", "10.0.0.9", "10.0.0.7")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "10.0.0.5", min.String())
+		require.Equal(t, "10.0.0.7", max.String())
+	})
+
+	t.Run("must report empty ranges", func(t *testing.T) {
+		_, _, ok, err := Bounds(nil, "10.0.0.2", "10.0.0.2", nil)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		_, _, ok, err = Bounds(Max.String(), nil, nil, nil)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("must fail for invalid bounds", func(t *testing.T) {
+		_, _, _, err := Bounds(nil, "a", nil, nil)
+		require.Error(t, err)
+	})
+}
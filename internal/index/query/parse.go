@@ -0,0 +1,792 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/f1monkey/search/internal/index/date"
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/fusion"
+	"github.com/f1monkey/search/internal/index/geo"
+	"github.com/f1monkey/search/internal/index/ip"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/index/vector"
+	"github.com/invopop/validation"
+)
+
+const (
+	clauseMatchAll = "match_all"
+	clauseTerm     = "term"
+	clauseTerms    = "terms"
+	clauseMatch    = "match"
+	clauseRange    = "range"
+	clauseBool     = "bool"
+
+	clauseGeoDistance    = "geo_distance"
+	clauseGeoBoundingBox = "geo_bounding_box"
+
+	clauseKnn    = "knn"
+	clauseHybrid = "hybrid"
+)
+
+const (
+	// DefaultK number of the documents found by the knn query by default
+	DefaultK = 10
+	// DefaultNumCandidates minimal number of the candidates considered by the knn query by default
+	DefaultNumCandidates = 100
+	// MaxNumCandidates limits the number of the candidates of the knn query
+	MaxNumCandidates = 10000
+)
+
+// Parse builds a query tree from its JSON representation.
+// Field names and types are checked against the schema, errors are keyed by the path of the invalid clause.
+// Date values are converted to milliseconds since the epoch, date math is resolved against the current time
+func Parse(s schema.Schema, path string, data json.RawMessage) (Query, error) {
+	p := &parser{
+		schema: s,
+		errors: validation.Errors{},
+		now:    time.Now(),
+	}
+
+	q := p.parse(path, data)
+	if len(p.errors) != 0 {
+		return nil, p.errors
+	}
+
+	return q, nil
+}
+
+type parser struct {
+	schema schema.Schema
+	errors validation.Errors
+	now    time.Time
+}
+
+func (p *parser) addErr(path string, format string, args ...interface{}) {
+	p.errors[path] = validation.NewError("", fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parse(path string, data json.RawMessage) Query {
+	name, body, ok := p.single(path, data)
+	if !ok {
+		return nil
+	}
+	path = join(path, name)
+
+	switch name {
+	case clauseMatchAll:
+		return MatchAll{}
+	case clauseTerm:
+		return p.parseTerm(path, body)
+	case clauseTerms:
+		return p.parseTerms(path, body)
+	case clauseMatch:
+		return p.parseMatch(path, body)
+	case clauseRange:
+		return p.parseRange(path, body)
+	case clauseGeoDistance:
+		return p.parseGeoDistance(path, body)
+	case clauseGeoBoundingBox:
+		return p.parseGeoBoundingBox(path, body)
+	case clauseKnn:
+		return p.parseKnn(path, body)
+	case clauseHybrid:
+		return p.parseHybrid(path, body)
+	case clauseBool:
+		return p.parseBool(path, body)
+	}
+
+	p.addErr(path, "unknown query type %q", name)
+
+	return nil
+}
+
+func (p *parser) parseTerm(path string, data json.RawMessage) Query {
+	fieldName, body, ok := p.single(path, data)
+	if !ok {
+		return nil
+	}
+	path = join(path, fieldName)
+
+	f, ok := p.field(path, fieldName, termTypes)
+	if !ok {
+		return nil
+	}
+
+	var value interface{}
+	if obj, ok := p.object(body); ok {
+		if _, ok := obj["value"]; !ok {
+			p.addErr(path, "%q key must be provided", "value")
+			return nil
+		}
+		body = obj["value"]
+	}
+	if err := decode(body, &value); err != nil {
+		p.addErr(path, "invalid value: %s", err)
+		return nil
+	}
+	if f.Type == schema.TypeDate {
+		if value, ok = p.parseDate(path, value, f.DateFormats(), time.UTC, false); !ok {
+			return nil
+		}
+	}
+
+	if !p.checkValue(path, f, value) {
+		return nil
+	}
+
+	return Term{Field: fieldName, Value: value}
+}
+
+func (p *parser) parseTerms(path string, data json.RawMessage) Query {
+	fieldName, body, ok := p.single(path, data)
+	if !ok {
+		return nil
+	}
+	path = join(path, fieldName)
+
+	f, ok := p.field(path, fieldName, termTypes)
+	if !ok {
+		return nil
+	}
+
+	var values []interface{}
+	if err := decode(body, &values); err != nil {
+		p.addErr(path, "must be an array of values")
+		return nil
+	}
+	if len(values) == 0 {
+		p.addErr(path, "cannot be empty")
+		return nil
+	}
+
+	valid := true
+	for i, v := range values {
+		itemPath := join(path, fmt.Sprint(i))
+		if f.Type == schema.TypeDate {
+			if values[i], ok = p.parseDate(itemPath, v, f.DateFormats(), time.UTC, false); !ok {
+				valid = false
+				continue
+			}
+		}
+		if !p.checkValue(itemPath, f, values[i]) {
+			valid = false
+		}
+	}
+	if !valid {
+		return nil
+	}
+
+	return Terms{Field: fieldName, Values: values}
+}
+
+type matchBody struct {
+	Query    *string  `json:"query"`
+	Operator Operator `json:"operator"`
+}
+
+func (p *parser) parseMatch(path string, data json.RawMessage) Query {
+	fieldName, body, ok := p.single(path, data)
+	if !ok {
+		return nil
+	}
+	path = join(path, fieldName)
+
+	if _, ok := p.field(path, fieldName, matchTypes); !ok {
+		return nil
+	}
+
+	result := Match{Field: fieldName, Operator: OperatorOr}
+	if _, ok := p.object(body); ok {
+		mb := matchBody{}
+		if err := decode(body, &mb); err != nil {
+			p.addErr(path, "invalid match body: %s", err)
+			return nil
+		}
+		if mb.Query == nil {
+			p.addErr(path, "%q key must be provided", "query")
+			return nil
+		}
+		result.Query = *mb.Query
+		if mb.Operator != "" {
+			if !mb.Operator.Valid() {
+				p.addErr(join(path, "operator"), "unknown operator %q", mb.Operator)
+				return nil
+			}
+			result.Operator = mb.Operator
+		}
+	} else if err := decode(body, &result.Query); err != nil {
+		p.addErr(path, "must be a string or an object")
+		return nil
+	}
+
+	return result
+}
+
+func (p *parser) parseRange(path string, data json.RawMessage) Query {
+	fieldName, body, ok := p.single(path, data)
+	if !ok {
+		return nil
+	}
+	path = join(path, fieldName)
+
+	f, ok := p.field(path, fieldName, rangeTypes)
+	if !ok {
+		return nil
+	}
+
+	obj, ok := p.object(body)
+	if !ok {
+		p.addErr(path, "must be an object with at least one bound")
+		return nil
+	}
+
+	formats, loc, ok := p.rangeDateSettings(path, f, obj)
+	if !ok {
+		return nil
+	}
+	if len(obj) == 0 {
+		p.addErr(path, "must be an object with at least one bound")
+		return nil
+	}
+
+	result := Range{Field: fieldName}
+	valid := true
+	for key, raw := range obj {
+		var target *interface{}
+		switch key {
+		case "gt":
+			target = &result.Gt
+		case "gte":
+			target = &result.Gte
+		case "lt":
+			target = &result.Lt
+		case "lte":
+			target = &result.Lte
+		default:
+			p.addErr(join(path, key), "unknown key %q", key)
+			valid = false
+			continue
+		}
+
+		if err := decode(raw, target); err != nil {
+			p.addErr(join(path, key), "invalid value: %s", err)
+			valid = false
+			continue
+		}
+		if f.Type == schema.TypeIP {
+			// networks are allowed only by the term queries
+			if _, err := ip.ParseValue(*target); err != nil {
+				p.addErr(join(path, key), "%s", err)
+				valid = false
+				continue
+			}
+		}
+		if f.Type == schema.TypeDate {
+			// rounded upper bounds include the whole unit, rounded lower bounds exclude it for gt
+			roundUp := key == "gt" || key == "lte"
+			if *target, ok = p.parseDate(join(path, key), *target, formats, loc, roundUp); !ok {
+				valid = false
+				continue
+			}
+		}
+		if !p.checkValue(join(path, key), f, *target) {
+			valid = false
+		}
+	}
+	if !valid {
+		return nil
+	}
+
+	return result
+}
+
+// rangeDateSettings take the format and the time zone of the date range bounds from the range body
+func (p *parser) rangeDateSettings(path string, f schema.Field, obj map[string]json.RawMessage) (date.Formats, *time.Location, bool) {
+	formats, loc := f.DateFormats(), time.UTC
+
+	for _, key := range []string{"format", "time_zone"} {
+		raw, ok := obj[key]
+		if !ok {
+			continue
+		}
+		delete(obj, key)
+
+		keyPath := join(path, key)
+		if f.Type != schema.TypeDate {
+			p.addErr(keyPath, "allowed only for date fields")
+			return nil, nil, false
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			p.addErr(keyPath, "must be a string")
+			return nil, nil, false
+		}
+
+		if key == "format" {
+			formats = date.ParseFormats(value)
+			if err := formats.Validate(); err != nil {
+				p.addErr(keyPath, "invalid format: %s", err)
+				return nil, nil, false
+			}
+			continue
+		}
+
+		var err error
+		if loc, err = date.ParseLocation(value); err != nil {
+			p.addErr(keyPath, "%s", err)
+			return nil, nil, false
+		}
+	}
+
+	return formats, loc, true
+}
+
+// parseDate convert the date or the date math expression to milliseconds since the epoch
+func (p *parser) parseDate(path string, value interface{}, formats date.Formats, loc *time.Location, roundUp bool) (interface{}, bool) {
+	ms, err := date.ParseMath(value, formats, p.now, loc, roundUp)
+	if err != nil {
+		p.addErr(path, "invalid date: %s", err)
+		return nil, false
+	}
+
+	return date.Millis(ms), true
+}
+
+// parseGeoDistance parse {"distance": "10km", "<field>": <point>}
+func (p *parser) parseGeoDistance(path string, data json.RawMessage) Query {
+	obj, ok := p.object(data)
+	if !ok {
+		p.addErr(path, "must be an object")
+		return nil
+	}
+
+	raw, ok := obj["distance"]
+	if !ok {
+		p.addErr(join(path, "distance"), "cannot be blank")
+		return nil
+	}
+	delete(obj, "distance")
+
+	var value interface{}
+	if err := decode(raw, &value); err != nil {
+		p.addErr(join(path, "distance"), "invalid value: %s", err)
+		return nil
+	}
+	distance, err := geo.ParseDistance(value)
+	if err != nil {
+		p.addErr(join(path, "distance"), "%s", err)
+		return nil
+	}
+
+	if len(obj) != 1 {
+		p.addErr(path, "must contain the distance and a single field")
+		return nil
+	}
+	for fieldName, body := range obj {
+		fieldPath := join(path, fieldName)
+		if _, ok := p.field(fieldPath, fieldName, geoTypes); !ok {
+			return nil
+		}
+
+		origin, ok := p.parsePoint(fieldPath, body)
+		if !ok {
+			return nil
+		}
+
+		return GeoDistance{Field: fieldName, Origin: origin, Distance: distance}
+	}
+
+	return nil
+}
+
+type boundingBoxBody struct {
+	TopLeft     json.RawMessage `json:"top_left"`
+	BottomRight json.RawMessage `json:"bottom_right"`
+}
+
+// parseGeoBoundingBox parse {"<field>": {"top_left": <point>, "bottom_right": <point>}}
+func (p *parser) parseGeoBoundingBox(path string, data json.RawMessage) Query {
+	fieldName, body, ok := p.single(path, data)
+	if !ok {
+		return nil
+	}
+	path = join(path, fieldName)
+
+	if _, ok := p.field(path, fieldName, geoTypes); !ok {
+		return nil
+	}
+
+	bb := boundingBoxBody{}
+	if err := decode(body, &bb); err != nil {
+		p.addErr(path, "invalid bounding box: %s", err)
+		return nil
+	}
+
+	result := GeoBoundingBox{Field: fieldName}
+	for _, corner := range []struct {
+		key    string
+		raw    json.RawMessage
+		target *geo.Point
+	}{
+		{"top_left", bb.TopLeft, &result.Box.TopLeft},
+		{"bottom_right", bb.BottomRight, &result.Box.BottomRight},
+	} {
+		if corner.raw == nil {
+			p.addErr(join(path, corner.key), "cannot be blank")
+			return nil
+		}
+		if *corner.target, ok = p.parsePoint(join(path, corner.key), corner.raw); !ok {
+			return nil
+		}
+	}
+
+	if err := result.Box.Validate(); err != nil {
+		p.addErr(path, "%s", err)
+		return nil
+	}
+
+	return result
+}
+
+func (p *parser) parsePoint(path string, data json.RawMessage) (geo.Point, bool) {
+	var value interface{}
+	if err := decode(data, &value); err != nil {
+		p.addErr(path, "invalid value: %s", err)
+		return geo.Point{}, false
+	}
+
+	point, err := geo.Parse(value)
+	if err != nil {
+		p.addErr(path, "invalid point: %s", err)
+		return geo.Point{}, false
+	}
+
+	return point, true
+}
+
+func (p *parser) parseKnn(path string, data json.RawMessage) Query {
+	var raw struct {
+		Field         string          `json:"field"`
+		QueryVector   interface{}     `json:"query_vector"`
+		K             *int            `json:"k"`
+		NumCandidates *int            `json:"num_candidates"`
+		Filter        json.RawMessage `json:"filter"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		p.addErr(path, "invalid value: %s", err)
+		return nil
+	}
+
+	if raw.Field == "" {
+		p.addErr(join(path, "field"), "cannot be blank")
+		return nil
+	}
+	f, ok := p.field(join(path, "field"), raw.Field, vectorTypes)
+	if !ok {
+		return nil
+	}
+
+	if raw.QueryVector == nil {
+		p.addErr(join(path, "query_vector"), "cannot be blank")
+		return nil
+	}
+	v, err := vector.Parse(raw.QueryVector, f.Dims, f.VectorSimilarity)
+	if err != nil {
+		p.addErr(join(path, "query_vector"), "%s", err)
+		return nil
+	}
+
+	result := Knn{Field: raw.Field, Vector: v, K: DefaultK}
+	if raw.K != nil {
+		result.K = *raw.K
+	}
+	if result.K < 1 || result.K > MaxNumCandidates {
+		p.addErr(join(path, "k"), "must be between 1 and %d", MaxNumCandidates)
+		return nil
+	}
+
+	result.NumCandidates = DefaultNumCandidates
+	if result.K > result.NumCandidates {
+		result.NumCandidates = result.K
+	}
+	if raw.NumCandidates != nil {
+		result.NumCandidates = *raw.NumCandidates
+	}
+	if result.NumCandidates < result.K || result.NumCandidates > MaxNumCandidates {
+		p.addErr(join(path, "num_candidates"), "must be between k and %d", MaxNumCandidates)
+		return nil
+	}
+
+	if raw.Filter != nil {
+		filter := p.parseList(join(path, "filter"), raw.Filter)
+		if len(filter) == 1 {
+			result.Filter = filter[0]
+		} else if len(filter) > 1 {
+			result.Filter = Bool{Filter: filter}
+		}
+	}
+
+	return result
+}
+
+func (p *parser) parseHybrid(path string, data json.RawMessage) Query {
+	var raw struct {
+		Retrievers []struct {
+			Query  json.RawMessage `json:"query"`
+			Weight *float64        `json:"weight"`
+		} `json:"retrievers"`
+		Fusion        fusion.Method        `json:"fusion"`
+		RankConstant  *int                 `json:"rank_constant"`
+		WindowSize    *int                 `json:"window_size"`
+		Normalization fusion.Normalization `json:"normalization"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		p.addErr(path, "invalid value: %s", err)
+		return nil
+	}
+
+	result := Hybrid{Fusion: raw.Fusion, WindowSize: fusion.DefaultWindowSize}
+	if result.Fusion == "" {
+		result.Fusion = fusion.MethodRRF
+	}
+	if err := result.Fusion.Validate(); err != nil {
+		p.addErr(join(path, "fusion"), "%s", err)
+	}
+
+	// settings of the other fusion method are rejected to make the mistakes visible
+	switch result.Fusion {
+	case fusion.MethodRRF:
+		result.RankConstant = fusion.DefaultRankConstant
+		if raw.RankConstant != nil {
+			result.RankConstant = *raw.RankConstant
+		}
+		if result.RankConstant < 1 {
+			p.addErr(join(path, "rank_constant"), "must be >= 1")
+		}
+		if raw.Normalization != "" {
+			p.addErr(join(path, "normalization"), "allowed only for %q fusion", fusion.MethodLinear)
+		}
+	case fusion.MethodLinear:
+		result.Normalization = raw.Normalization
+		if result.Normalization == "" {
+			result.Normalization = fusion.NormalizationMinMax
+		}
+		if err := result.Normalization.Validate(); err != nil {
+			p.addErr(join(path, "normalization"), "%s", err)
+		}
+		if raw.RankConstant != nil {
+			p.addErr(join(path, "rank_constant"), "allowed only for %q fusion", fusion.MethodRRF)
+		}
+	}
+
+	if raw.WindowSize != nil {
+		result.WindowSize = *raw.WindowSize
+	}
+	if result.WindowSize < 1 || result.WindowSize > MaxNumCandidates {
+		p.addErr(join(path, "window_size"), "must be between 1 and %d", MaxNumCandidates)
+	}
+
+	if len(raw.Retrievers) == 0 {
+		p.addErr(join(path, "retrievers"), "cannot be blank")
+		return nil
+	}
+	for i, r := range raw.Retrievers {
+		retrieverPath := join(path, "retrievers", fmt.Sprint(i))
+		if r.Query == nil {
+			p.addErr(join(retrieverPath, "query"), "cannot be blank")
+			continue
+		}
+
+		retriever := Retriever{Query: p.parse(join(retrieverPath, "query"), r.Query), Weight: 1}
+		if r.Weight != nil {
+			retriever.Weight = *r.Weight
+		}
+		if retriever.Weight < 0 {
+			p.addErr(join(retrieverPath, "weight"), "must be >= 0")
+		}
+		result.Retrievers = append(result.Retrievers, retriever)
+	}
+
+	return result
+}
+
+func (p *parser) parseBool(path string, data json.RawMessage) Query {
+	obj, ok := p.object(data)
+	if !ok {
+		p.addErr(path, "must be an object")
+		return nil
+	}
+
+	result := Bool{}
+	for key, value := range obj {
+		var target *[]Query
+		switch key {
+		case "must":
+			target = &result.Must
+		case "should":
+			target = &result.Should
+		case "must_not":
+			target = &result.MustNot
+		case "filter":
+			target = &result.Filter
+		default:
+			p.addErr(join(path, key), "unknown key %q", key)
+			continue
+		}
+
+		*target = p.parseList(join(path, key), value)
+	}
+
+	return result
+}
+
+func (p *parser) parseList(path string, data json.RawMessage) []Query {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		// a single clause is allowed instead of an array
+		if q := p.parse(path, data); q != nil {
+			return []Query{q}
+		}
+		return nil
+	}
+
+	result := make([]Query, 0, len(items))
+	for i, item := range items {
+		if q := p.parse(join(path, fmt.Sprint(i)), item); q != nil {
+			result = append(result, q)
+		}
+	}
+
+	return result
+}
+
+var numericTypes = []schema.Type{
+	schema.TypeByte,
+	schema.TypeShort,
+	schema.TypeInteger,
+	schema.TypeLong,
+	schema.TypeUnsignedLong,
+	schema.TypeFloat,
+	schema.TypeDouble,
+}
+
+var termTypes = append([]schema.Type{
+	schema.TypeAll,
+	schema.TypeText,
+	schema.TypeKeyword,
+	schema.TypeBool,
+	schema.TypeDate,
+	schema.TypeIP,
+}, numericTypes...)
+
+var rangeTypes = append([]schema.Type{
+	schema.TypeDate,
+	schema.TypeIP,
+}, numericTypes...)
+
+var geoTypes = []schema.Type{
+	schema.TypeGeoPoint,
+}
+
+var vectorTypes = []schema.Type{
+	schema.TypeDenseVector,
+}
+
+var matchTypes = []schema.Type{
+	schema.TypeAll,
+	schema.TypeText,
+	schema.TypeKeyword,
+}
+
+func (p *parser) field(path string, name string, allowed []schema.Type) (schema.Field, bool) {
+	f, ok := p.schema.Field(name)
+	if !ok {
+		p.addErr(path, "unknown field %q", name)
+		return f, false
+	}
+
+	for _, t := range allowed {
+		if f.Type == t {
+			return f, true
+		}
+	}
+
+	p.addErr(path, "field %q of type %q is not supported by this query", name, f.Type)
+
+	return f, false
+}
+
+func (p *parser) checkValue(path string, f schema.Field, value interface{}) bool {
+	var ok bool
+	switch {
+	case f.Type == schema.TypeBool:
+		_, ok = value.(bool)
+	case docvalues.IsNumeric(f.Type):
+		_, err := docvalues.Encode(f.Type, value)
+		ok = err == nil
+	case f.Type == schema.TypeIP:
+		// an address or a network like 10.0.0.0/8
+		var s string
+		if s, ok = value.(string); ok {
+			_, _, err := ip.ParseCIDR(s)
+			ok = err == nil
+		}
+	default:
+		_, ok = value.(string)
+	}
+
+	if !ok {
+		p.addErr(path, "invalid value %#v for field type %q", value, f.Type)
+	}
+
+	return ok
+}
+
+// single decodes an object with exactly one key
+func (p *parser) single(path string, data json.RawMessage) (string, json.RawMessage, bool) {
+	obj, ok := p.object(data)
+	if !ok || len(obj) != 1 {
+		p.addErr(path, "must be an object with a single key")
+		return "", nil, false
+	}
+
+	for k, v := range obj {
+		return k, v, true
+	}
+
+	return "", nil, false
+}
+
+func (p *parser) object(data json.RawMessage) (map[string]json.RawMessage, bool) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil, false
+	}
+
+	obj := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, false
+	}
+
+	return obj, true
+}
+
+func decode(data json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	return dec.Decode(v)
+}
+
+func join(parts ...string) string {
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,362 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/f1monkey/search/internal/index/analyzer"
+	"github.com/f1monkey/search/internal/index/fusion"
+	"github.com/f1monkey/search/internal/index/geo"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchema() schema.Schema {
+	return schema.NewSchema(
+		map[string]schema.Field{
+			"text":    schema.NewField(schema.TypeText, false, "whitespace"),
+			"keyword": schema.NewField(schema.TypeKeyword, false, ""),
+			"bool":    schema.NewField(schema.TypeBool, false, ""),
+			"long":    schema.NewField(schema.TypeLong, false, ""),
+			"date":    schema.NewField(schema.TypeDate, false, ""),
+			"geo":     schema.NewField(schema.TypeGeoPoint, false, ""),
+			"ip":      schema.NewField(schema.TypeIP, false, ""),
+			"vector":  {Type: schema.TypeDenseVector, Dims: 2},
+		},
+		map[string]schema.FieldAnalyzer{
+			"whitespace": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}},
+		},
+	)
+}
+
+func requireErrorKeys(t *testing.T, err error, keys ...string) {
+	t.Helper()
+
+	var ve validation.Errors
+	require.ErrorAs(t, err, &ve)
+	for _, k := range keys {
+		require.Contains(t, ve, k)
+	}
+	require.Len(t, ve, len(keys))
+}
+
+func Test_Parse(t *testing.T) {
+	t.Run("must fail if clause has more than one key", func(t *testing.T) {
+		_, err := Parse(testSchema(), "query", json.RawMessage(`{"term": {"keyword": "a"}, "match_all": {}}`))
+		requireErrorKeys(t, err, "query")
+	})
+
+	t.Run("must fail if clause type is unknown", func(t *testing.T) {
+		_, err := Parse(testSchema(), "query", json.RawMessage(`{"unknown": {}}`))
+		requireErrorKeys(t, err, "query.unknown")
+	})
+
+	t.Run("match_all", func(t *testing.T) {
+		q, err := Parse(testSchema(), "query", json.RawMessage(`{"match_all": {}}`))
+		require.NoError(t, err)
+		require.Equal(t, MatchAll{}, q)
+	})
+
+	t.Run("term", func(t *testing.T) {
+		t.Run("must fail if field is unknown", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"term": {"unknown": "a"}}`))
+			requireErrorKeys(t, err, "query.term.unknown")
+		})
+		t.Run("must fail if value does not match numeric field type", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"term": {"long": "a"}}`))
+			requireErrorKeys(t, err, "query.term.long")
+		})
+		t.Run("must fail if value does not match field type", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"term": {"bool": "true"}}`))
+			requireErrorKeys(t, err, "query.term.bool")
+		})
+		t.Run("must parse short form", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"term": {"keyword": "a"}}`))
+			require.NoError(t, err)
+			require.Equal(t, Term{Field: "keyword", Value: "a"}, q)
+		})
+		t.Run("must parse full form", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"term": {"bool": {"value": true}}}`))
+			require.NoError(t, err)
+			require.Equal(t, Term{Field: "bool", Value: true}, q)
+		})
+		t.Run("must parse numeric value", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"term": {"long": 10}}`))
+			require.NoError(t, err)
+			require.Equal(t, Term{Field: "long", Value: json.Number("10")}, q)
+		})
+	})
+
+	t.Run("term ip", func(t *testing.T) {
+		t.Run("must accept addresses and networks", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"terms": {"ip": ["10.0.0.1", "10.0.0.0/8", "2001:db8::/32"]}}`))
+			require.NoError(t, err)
+			require.Equal(t, Terms{Field: "ip", Values: []interface{}{"10.0.0.1", "10.0.0.0/8", "2001:db8::/32"}}, q)
+		})
+		t.Run("must fail for invalid values", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"terms": {"ip": ["10.0.0.256", "10.0.0.0/33", 1, "10.0.0.1"]}}`))
+			requireErrorKeys(t, err, "query.terms.ip.0", "query.terms.ip.1", "query.terms.ip.2")
+		})
+	})
+
+	t.Run("terms", func(t *testing.T) {
+		t.Run("must fail if values are empty", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"terms": {"keyword": []}}`))
+			requireErrorKeys(t, err, "query.terms.keyword")
+		})
+		t.Run("must report invalid value index", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"terms": {"keyword": ["a", 1]}}`))
+			requireErrorKeys(t, err, "query.terms.keyword.1")
+		})
+		t.Run("must parse values", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"terms": {"keyword": ["a", "b"]}}`))
+			require.NoError(t, err)
+			require.Equal(t, Terms{Field: "keyword", Values: []interface{}{"a", "b"}}, q)
+		})
+	})
+
+	t.Run("match", func(t *testing.T) {
+		t.Run("must fail if field type is not supported", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"match": {"bool": "a"}}`))
+			requireErrorKeys(t, err, "query.match.bool")
+		})
+		t.Run("must fail if operator is unknown", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"match": {"text": {"query": "a", "operator": "xor"}}}`))
+			requireErrorKeys(t, err, "query.match.text.operator")
+		})
+		t.Run("must parse short form", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"match": {"text": "hello world"}}`))
+			require.NoError(t, err)
+			require.Equal(t, Match{Field: "text", Query: "hello world", Operator: OperatorOr}, q)
+		})
+		t.Run("must parse full form", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"match": {"text": {"query": "hello world", "operator": "and"}}}`))
+			require.NoError(t, err)
+			require.Equal(t, Match{Field: "text", Query: "hello world", Operator: OperatorAnd}, q)
+		})
+	})
+
+	t.Run("range", func(t *testing.T) {
+		t.Run("must fail if field type is not numeric", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"keyword": {"gt": 1}}}`))
+			requireErrorKeys(t, err, "query.range.keyword")
+		})
+		t.Run("must fail if bounds are empty", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"long": {}}}`))
+			requireErrorKeys(t, err, "query.range.long")
+		})
+		t.Run("must report invalid bounds", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"long": {"gt": "a", "lte": 1.5, "eq": 1}}}`))
+			requireErrorKeys(t, err, "query.range.long.gt", "query.range.long.lte", "query.range.long.eq")
+		})
+		t.Run("must parse bounds", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"long": {"gte": 1, "lt": 10}}}`))
+			require.NoError(t, err)
+			require.Equal(t, Range{Field: "long", Gte: json.Number("1"), Lt: json.Number("10")}, q)
+		})
+		t.Run("must convert dates to millis", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"date": {"gte": "2023-01-02T00:00:00Z", "lt": 1672704000000}}}`))
+			require.NoError(t, err)
+			require.Equal(t, Range{Field: "date", Gte: json.Number("1672617600000"), Lt: json.Number("1672704000000")}, q)
+		})
+		t.Run("must round date math bounds", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"date": {
+				"gt": "2023-01-02T10:00:00Z||/d",
+				"gte": "2023-01-02T10:00:00Z||/d",
+				"lt": "2023-01-02T10:00:00Z||/d",
+				"lte": "2023-01-02T10:00:00Z||/d"
+			}}}`))
+			require.NoError(t, err)
+			require.Equal(t, Range{
+				Field: "date",
+				Gt:    json.Number("1672703999999"),
+				Gte:   json.Number("1672617600000"),
+				Lt:    json.Number("1672617600000"),
+				Lte:   json.Number("1672703999999"),
+			}, q)
+		})
+		t.Run("must resolve now", func(t *testing.T) {
+			before := time.Now().UnixMilli()
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"date": {"gte": "now-1h"}}}`))
+			require.NoError(t, err)
+			after := time.Now().UnixMilli()
+
+			ms, err := q.(Range).Gte.(json.Number).Int64()
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, ms, before-time.Hour.Milliseconds())
+			require.LessOrEqual(t, ms, after-time.Hour.Milliseconds())
+		})
+		t.Run("must apply format and time zone", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"date": {
+				"gte": "2023-01-02",
+				"lt": "2023-01-02||+1d",
+				"format": "2006-01-02",
+				"time_zone": "+01:00"
+			}}}`))
+			require.NoError(t, err)
+			require.Equal(t, Range{Field: "date", Gte: json.Number("1672614000000"), Lt: json.Number("1672700400000")}, q)
+		})
+		t.Run("must parse ip bounds", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"ip": {"gte": "10.0.0.0", "lt": "::ffff:10.1.0.0"}}}`))
+			require.NoError(t, err)
+			require.Equal(t, Range{Field: "ip", Gte: "10.0.0.0", Lt: "::ffff:10.1.0.0"}, q)
+
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"range": {"ip": {"gte": "10.0.0.0/8", "lt": 1}}}`))
+			requireErrorKeys(t, err, "query.range.ip.gte", "query.range.ip.lt")
+		})
+		t.Run("must report invalid date settings", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"range": {"date": {"gte": "now", "time_zone": "Mars/Olympus"}}}`))
+			requireErrorKeys(t, err, "query.range.date.time_zone")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"range": {"long": {"gte": 1, "format": "2006"}}}`))
+			requireErrorKeys(t, err, "query.range.long.format")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"range": {"date": {"gte": "yesterday", "lt": "now-1x"}}}`))
+			requireErrorKeys(t, err, "query.range.date.gte", "query.range.date.lt")
+		})
+	})
+
+	t.Run("geo_distance", func(t *testing.T) {
+		t.Run("must report invalid distance and field", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"geo_distance": {"geo": "1,2"}}`))
+			requireErrorKeys(t, err, "query.geo_distance.distance")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"geo_distance": {"distance": "1 parsec", "geo": "1,2"}}`))
+			requireErrorKeys(t, err, "query.geo_distance.distance")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"geo_distance": {"distance": "1km", "long": "1,2"}}`))
+			requireErrorKeys(t, err, "query.geo_distance.long")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"geo_distance": {"distance": "1km", "geo": "100,2"}}`))
+			requireErrorKeys(t, err, "query.geo_distance.geo")
+		})
+		t.Run("must parse distance in meters", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"geo_distance": {"distance": "1.5km", "geo": {"lat": 52.52, "lon": 13.405}}}`))
+			require.NoError(t, err)
+			require.Equal(t, GeoDistance{Field: "geo", Origin: geo.Point{Lat: 52.52, Lon: 13.405}, Distance: 1500}, q)
+		})
+	})
+
+	t.Run("geo_bounding_box", func(t *testing.T) {
+		t.Run("must report invalid corners", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"geo_bounding_box": {"geo": {"top_left": "1,2"}}}`))
+			requireErrorKeys(t, err, "query.geo_bounding_box.geo.bottom_right")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"geo_bounding_box": {"geo": {"top_left": "1,2", "bottom_right": "2,3"}}}`))
+			requireErrorKeys(t, err, "query.geo_bounding_box.geo")
+		})
+		t.Run("must parse box", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"geo_bounding_box": {"geo": {"top_left": "53,13", "bottom_right": {"lat": 52, "lon": 14}}}}`))
+			require.NoError(t, err)
+			require.Equal(t, GeoBoundingBox{Field: "geo", Box: geo.BoundingBox{
+				TopLeft:     geo.Point{Lat: 53, Lon: 13},
+				BottomRight: geo.Point{Lat: 52, Lon: 14},
+			}}, q)
+		})
+	})
+
+	t.Run("knn", func(t *testing.T) {
+		t.Run("must report invalid settings", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "keyword", "query_vector": [1, 2]}}`))
+			requireErrorKeys(t, err, "query.knn.field")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "vector", "query_vector": [1, 2, 3]}}`))
+			requireErrorKeys(t, err, "query.knn.query_vector")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "vector", "query_vector": [1, 2], "k": 0}}`))
+			requireErrorKeys(t, err, "query.knn.k")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "vector", "query_vector": [1, 2], "k": 20, "num_candidates": 10}}`))
+			requireErrorKeys(t, err, "query.knn.num_candidates")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "vector", "query_vector": [1, 2], "filter": {"term": {"unknown": "a"}}}}`))
+			requireErrorKeys(t, err, "query.knn.filter.term.unknown")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "vector", "query_vector": [1, 2], "boost": 2}}`))
+			requireErrorKeys(t, err, "query.knn")
+		})
+		t.Run("must parse defaults and filters", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "vector", "query_vector": [1, 0.5]}}`))
+			require.NoError(t, err)
+			require.Equal(t, Knn{Field: "vector", Vector: []float32{1, 0.5}, K: DefaultK, NumCandidates: DefaultNumCandidates}, q)
+
+			q, err = Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "vector", "query_vector": [1, 0.5], "k": 200, "filter": {"term": {"keyword": "a"}}}}`))
+			require.NoError(t, err)
+			require.Equal(t, Knn{Field: "vector", Vector: []float32{1, 0.5}, K: 200, NumCandidates: 200, Filter: Term{Field: "keyword", Value: "a"}}, q)
+
+			q, err = Parse(testSchema(), "query", json.RawMessage(`{"knn": {"field": "vector", "query_vector": [1, 0.5], "k": 5, "num_candidates": 50, "filter": [{"term": {"keyword": "a"}}, {"term": {"bool": true}}]}}`))
+			require.NoError(t, err)
+			require.Equal(t, Knn{Field: "vector", Vector: []float32{1, 0.5}, K: 5, NumCandidates: 50, Filter: Bool{Filter: []Query{
+				Term{Field: "keyword", Value: "a"},
+				Term{Field: "bool", Value: true},
+			}}}, q)
+		})
+	})
+
+	t.Run("hybrid", func(t *testing.T) {
+		t.Run("must report invalid settings and retrievers", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"hybrid": {"retrievers": []}}`))
+			requireErrorKeys(t, err, "query.hybrid.retrievers")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"hybrid": {
+				"retrievers": [{"query": {"match": {"bool": "a"}}}, {"weight": 1}, {"query": {"match_all": {}}, "weight": -1}],
+				"fusion": "sum",
+				"window_size": 0
+			}}`))
+			requireErrorKeys(t, err,
+				"query.hybrid.retrievers.0.query.match.bool",
+				"query.hybrid.retrievers.1.query",
+				"query.hybrid.retrievers.2.weight",
+				"query.hybrid.fusion",
+				"query.hybrid.window_size",
+			)
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"hybrid": {"retrievers": [{"query": {"match_all": {}}}], "rank_constant": 0, "normalization": "none"}}`))
+			requireErrorKeys(t, err, "query.hybrid.rank_constant", "query.hybrid.normalization")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"hybrid": {"retrievers": [{"query": {"match_all": {}}}], "fusion": "linear", "rank_constant": 10, "normalization": "l2"}}`))
+			requireErrorKeys(t, err, "query.hybrid.rank_constant", "query.hybrid.normalization")
+			_, err = Parse(testSchema(), "query", json.RawMessage(`{"hybrid": {"retrievers": [{"query": {"match_all": {}}}], "boost": 1}}`))
+			requireErrorKeys(t, err, "query.hybrid")
+		})
+		t.Run("must parse retrievers with defaults", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"hybrid": {"retrievers": [
+				{"query": {"match": {"text": "a"}}},
+				{"query": {"knn": {"field": "vector", "query_vector": [1, 0]}}, "weight": 2}
+			]}}`))
+			require.NoError(t, err)
+			require.Equal(t, Hybrid{
+				Retrievers: []Retriever{
+					{Query: Match{Field: "text", Query: "a", Operator: OperatorOr}, Weight: 1},
+					{Query: Knn{Field: "vector", Vector: []float32{1, 0}, K: DefaultK, NumCandidates: DefaultNumCandidates}, Weight: 2},
+				},
+				Fusion:       fusion.MethodRRF,
+				RankConstant: fusion.DefaultRankConstant,
+				WindowSize:   fusion.DefaultWindowSize,
+			}, q)
+
+			q, err = Parse(testSchema(), "query", json.RawMessage(`{"hybrid": {"retrievers": [{"query": {"match_all": {}}, "weight": 0.5}], "fusion": "linear", "window_size": 10}}`))
+			require.NoError(t, err)
+			require.Equal(t, Hybrid{
+				Retrievers:    []Retriever{{Query: MatchAll{}, Weight: 0.5}},
+				Fusion:        fusion.MethodLinear,
+				WindowSize:    10,
+				Normalization: fusion.NormalizationMinMax,
+			}, q)
+		})
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		t.Run("must report paths of all invalid clauses", func(t *testing.T) {
+			_, err := Parse(testSchema(), "query", json.RawMessage(`{"bool": {
+				"must": [{"term": {"keyword": "a"}}, {"term": {"unknown": "a"}}],
+				"filter": {"match": {"bool": "a"}},
+				"extra": []
+			}}`))
+			requireErrorKeys(t, err, "query.bool.must.1.term.unknown", "query.bool.filter.match.bool", "query.bool.extra")
+		})
+		t.Run("must parse nested clauses", func(t *testing.T) {
+			q, err := Parse(testSchema(), "query", json.RawMessage(`{"bool": {
+				"must": [{"match": {"text": "a"}}],
+				"should": [{"term": {"keyword": "b"}}],
+				"must_not": {"term": {"bool": false}},
+				"filter": [{"bool": {"must": [{"match_all": {}}]}}]
+			}}`))
+			require.NoError(t, err)
+			require.Equal(t, Bool{
+				Must:    []Query{Match{Field: "text", Query: "a", Operator: OperatorOr}},
+				Should:  []Query{Term{Field: "keyword", Value: "b"}},
+				MustNot: []Query{Term{Field: "bool", Value: false}},
+				Filter:  []Query{Bool{Must: []Query{MatchAll{}}}},
+			}, q)
+		})
+	})
+}
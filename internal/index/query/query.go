@@ -0,0 +1,112 @@
+package query
+
+import (
+	"github.com/f1monkey/search/internal/index/fusion"
+	"github.com/f1monkey/search/internal/index/geo"
+)
+
+// Query node of the parsed query tree
+type Query interface {
+	query()
+}
+
+// MatchAll matches all documents
+type MatchAll struct{}
+
+// Term matches documents containing the exact term
+type Term struct {
+	Field string
+	Value interface{}
+}
+
+// Terms matches documents containing any of the exact terms
+type Terms struct {
+	Field  string
+	Values []interface{}
+}
+
+type Operator string
+
+const (
+	OperatorOr  Operator = "or"
+	OperatorAnd Operator = "and"
+)
+
+func (o Operator) Valid() bool {
+	return o == OperatorOr || o == OperatorAnd
+}
+
+// Match analyzes the query text with the field analyzer and matches documents containing its terms
+type Match struct {
+	Field    string
+	Query    string
+	Operator Operator
+}
+
+// Range matches documents with field values within the bounds. Nil bounds are not applied
+type Range struct {
+	Field string
+	Gt    interface{}
+	Gte   interface{}
+	Lt    interface{}
+	Lte   interface{}
+}
+
+// GeoDistance matches documents with geo points within the distance in meters from the origin
+type GeoDistance struct {
+	Field    string
+	Origin   geo.Point
+	Distance float64
+}
+
+// GeoBoundingBox matches documents with geo points inside the box
+type GeoBoundingBox struct {
+	Field string
+	Box   geo.BoundingBox
+}
+
+// Knn matches K documents with the vectors most similar to the query vector, scored by the similarity.
+// NumCandidates is the number of candidates considered by the approximate search.
+// Only the documents matching the filter are searched if it is set
+type Knn struct {
+	Field         string
+	Vector        []float32
+	K             int
+	NumCandidates int
+	Filter        Query
+}
+
+// Hybrid runs the retrievers independently and fuses their ranked lists, e.g. a lexical match and a knn query.
+// WindowSize top documents of every retriever are fused, the rest are not matched
+type Hybrid struct {
+	Retrievers    []Retriever
+	Fusion        fusion.Method
+	RankConstant  int
+	WindowSize    int
+	Normalization fusion.Normalization
+}
+
+// Retriever query of the hybrid search and its weight in the fusion
+type Retriever struct {
+	Query  Query
+	Weight float64
+}
+
+// Bool combines other queries
+type Bool struct {
+	Must    []Query
+	Should  []Query
+	MustNot []Query
+	Filter  []Query
+}
+
+func (MatchAll) query()       {}
+func (Term) query()           {}
+func (Terms) query()          {}
+func (Match) query()          {}
+func (Range) query()          {}
+func (GeoDistance) query()    {}
+func (GeoBoundingBox) query() {}
+func (Knn) query()            {}
+func (Hybrid) query()         {}
+func (Bool) query()           {}
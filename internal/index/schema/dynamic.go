@@ -0,0 +1,186 @@
+package schema
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/f1monkey/errs"
+	"github.com/invopop/validation"
+)
+
+// Dynamic handling of the document fields not declared in the schema
+type Dynamic string
+
+const (
+	// DynamicStrict reject documents with unknown fields. Used by default
+	DynamicStrict Dynamic = "strict"
+	// DynamicIgnore store unknown fields without indexing them
+	DynamicIgnore Dynamic = "ignore"
+	// DynamicTrue add unknown fields to the schema with the types inferred from their values
+	DynamicTrue Dynamic = "true"
+)
+
+// DefaultAnalyzer strings of dynamic fields are indexed as text with this analyzer if the schema declares it,
+// otherwise they are indexed as keywords
+const DefaultAnalyzer = "default"
+
+func (d Dynamic) Validate() error {
+	return validation.Validate(string(d), validation.In(string(DynamicStrict), string(DynamicIgnore), string(DynamicTrue)))
+}
+
+// UnmarshalJSON accepts boolean values too: true enables dynamic fields, false ignores unknown fields
+func (d *Dynamic) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		if b {
+			*d = DynamicTrue
+		} else {
+			*d = DynamicIgnore
+		}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*d = Dynamic(s)
+
+	return nil
+}
+
+// allowUnknown whether documents can contain fields not declared in the schema
+func (d Dynamic) allowUnknown() bool {
+	return d == DynamicIgnore || d == DynamicTrue
+}
+
+// Extend add the fields of the source not declared in the schema with the types inferred from their values.
+// Returns false if there are no new fields. Null values, empty objects and empty arrays are not added.
+// Fields which type cannot be inferred are reported as validation errors with their full paths
+func (s Schema) Extend(source Source) (Schema, bool, error) {
+	errors := validation.Errors{}
+	fields, changed := extendFields("", s.Fields, source, s.stringType(), errors)
+	if len(errors) > 0 {
+		return s, false, errors
+	}
+	if !changed {
+		return s, false, nil
+	}
+
+	s.Fields = fields
+
+	return s, true, nil
+}
+
+func (s Schema) stringType() Field {
+	if _, ok := s.Analyzers[DefaultAnalyzer]; ok {
+		return Field{Type: TypeText, Analyzer: DefaultAnalyzer}
+	}
+
+	return Field{Type: TypeKeyword}
+}
+
+// extendFields get the copy of the fields extended with the new ones if there are any
+func extendFields(path string, fields map[string]Field, source map[string]interface{}, str Field, errors validation.Errors) (map[string]Field, bool) {
+	var result map[string]Field
+	set := func(name string, f Field) {
+		if result == nil {
+			result = make(map[string]Field, len(fields)+1)
+			for k, v := range fields {
+				result[k] = v
+			}
+		}
+		result[name] = f
+	}
+
+	for name, value := range source {
+		f, ok := fields[name]
+		if !ok {
+			if inferred, ok := inferField(path+name, value, str, errors); ok {
+				set(name, inferred)
+			}
+			continue
+		}
+
+		if children, ok := extendChildren(path+name+".", f, value, str, errors); ok {
+			f.Children = children
+			set(name, f)
+		}
+	}
+
+	if result == nil {
+		return fields, false
+	}
+
+	return result, true
+}
+
+// extendChildren extend the children of the map field with the object or the slice field with the objects of the array.
+// Values of the wrong types are skipped, they are reported by the document validation
+func extendChildren(path string, f Field, value interface{}, str Field, errors validation.Errors) (map[string]Field, bool) {
+	switch f.Type {
+	case TypeMap:
+		if v, ok := value.(map[string]interface{}); ok {
+			return extendFields(path, f.Children, v, str, errors)
+		}
+	case TypeSlice:
+		v, ok := value.([]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		children, changed := f.Children, false
+		for i, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				var c bool
+				children, c = extendFields(path+strconv.Itoa(i)+".", children, obj, str, errors)
+				changed = changed || c
+			}
+		}
+
+		return children, changed
+	}
+
+	return nil, false
+}
+
+// inferField get the field type of the value. Returns false if the value has no type
+func inferField(path string, value interface{}, str Field, errors validation.Errors) (Field, bool) {
+	switch v := value.(type) {
+	case nil:
+		return Field{}, false
+	case bool:
+		return Field{Type: TypeBool}, true
+	case string:
+		return str, true
+	case json.Number:
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return Field{Type: TypeLong}, true
+		}
+		return Field{Type: TypeDouble}, true
+	case map[string]interface{}:
+		children, _ := extendFields(path+".", nil, v, str, errors)
+		if len(children) == 0 {
+			return Field{}, false
+		}
+		return Field{Type: TypeMap, Children: children}, true
+	case []interface{}:
+		var children map[string]Field
+		for i, item := range v {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				errors[path+"."+strconv.Itoa(i)] = errs.Errorf("cannot infer field type of array element %#v, only objects are supported", item)
+				return Field{}, false
+			}
+			children, _ = extendFields(path+"."+strconv.Itoa(i)+".", children, obj, str, errors)
+		}
+		if len(children) == 0 {
+			return Field{}, false
+		}
+		return Field{Type: TypeSlice, Children: children}, true
+	}
+
+	errors[path] = errs.Errorf("cannot infer field type of %#v", value)
+
+	return Field{}, false
+}
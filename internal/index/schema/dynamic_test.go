@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/analyzer"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Dynamic_UnmarshalJSON(t *testing.T) {
+	t.Run("must accept strings and booleans", func(t *testing.T) {
+		cases := map[string]Dynamic{
+			`"strict"`: DynamicStrict,
+			`"ignore"`: DynamicIgnore,
+			`"true"`:   DynamicTrue,
+			`true`:     DynamicTrue,
+			`false`:    DynamicIgnore,
+		}
+		for data, expected := range cases {
+			var d Dynamic
+			require.NoError(t, json.Unmarshal([]byte(data), &d))
+			require.Equal(t, expected, d)
+		}
+	})
+
+	t.Run("must fail on invalid value", func(t *testing.T) {
+		var d Dynamic
+		require.Error(t, json.Unmarshal([]byte(`1`), &d))
+		require.NoError(t, json.Unmarshal([]byte(`"unknown"`), &d))
+		require.Error(t, d.Validate())
+	})
+}
+
+func Test_Schema_Extend(t *testing.T) {
+	s := NewSchema(map[string]Field{
+		"name": {Type: TypeKeyword},
+		"tags": {Type: TypeSlice, Children: map[string]Field{
+			"name": {Type: TypeKeyword},
+		}},
+	}, nil)
+
+	t.Run("must not change schema if all fields are known", func(t *testing.T) {
+		result, changed, err := s.Extend(Source{"name": "a", "tags": []interface{}{map[string]interface{}{"name": "b"}}})
+		require.NoError(t, err)
+		require.False(t, changed)
+		require.Equal(t, s, result)
+	})
+
+	t.Run("must infer field types", func(t *testing.T) {
+		result, changed, err := s.Extend(Source{
+			"name":   "a",
+			"string": "a",
+			"long":   json.Number("1"),
+			"double": json.Number("1.5"),
+			"bool":   true,
+			"null":   nil,
+			"empty":  map[string]interface{}{},
+			"object": map[string]interface{}{"value": json.Number("1")},
+			"tags": []interface{}{
+				map[string]interface{}{"name": "b", "score": json.Number("1")},
+			},
+			"items": []interface{}{
+				map[string]interface{}{"a": "b"},
+				map[string]interface{}{"b": false},
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, changed)
+		require.Equal(t, map[string]Field{
+			"name":   {Type: TypeKeyword},
+			"string": {Type: TypeKeyword},
+			"long":   {Type: TypeLong},
+			"double": {Type: TypeDouble},
+			"bool":   {Type: TypeBool},
+			"object": {Type: TypeMap, Children: map[string]Field{"value": {Type: TypeLong}}},
+			"tags": {Type: TypeSlice, Children: map[string]Field{
+				"name":  {Type: TypeKeyword},
+				"score": {Type: TypeLong},
+			}},
+			"items": {Type: TypeSlice, Children: map[string]Field{
+				"a": {Type: TypeKeyword},
+				"b": {Type: TypeBool},
+			}},
+		}, result.Fields)
+		require.Len(t, s.Fields, 2, "must not modify the original schema")
+		require.Len(t, s.Fields["tags"].Children, 1, "must not modify the original schema")
+		require.NoError(t, result.Validate())
+	})
+
+	t.Run("must infer text fields if default analyzer is defined", func(t *testing.T) {
+		s := NewSchema(map[string]Field{"name": {Type: TypeKeyword}}, map[string]FieldAnalyzer{
+			DefaultAnalyzer: {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}},
+		})
+
+		result, changed, err := s.Extend(Source{"text": "a b"})
+		require.NoError(t, err)
+		require.True(t, changed)
+		require.Equal(t, Field{Type: TypeText, Analyzer: DefaultAnalyzer}, result.Fields["text"])
+	})
+
+	t.Run("must return error if type cannot be inferred", func(t *testing.T) {
+		_, _, err := s.Extend(Source{"values": []interface{}{"a"}, "object": map[string]interface{}{"values": []interface{}{json.Number("1")}}})
+
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "values.0")
+		require.Contains(t, ve, "object.values.0")
+	})
+}
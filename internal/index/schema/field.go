@@ -4,12 +4,15 @@ import (
 	"context"
 
 	"github.com/f1monkey/errs"
+	"github.com/f1monkey/search/internal/index/date"
+	"github.com/f1monkey/search/internal/index/vector"
 	"github.com/invopop/validation"
 )
 
 type Type string
 
 const (
+	// TypeAll catch-all text field indexing the values copied to it from the fields with copy_to
 	TypeAll  Type = "all"
 	TypeBool Type = "bool"
 
@@ -30,10 +33,23 @@ const (
 	// Float types
 	TypeDouble Type = "double" // float64
 	TypeFloat  Type = "float"  // float32
+
+	// TypeDate date and time stored as milliseconds since the epoch, parsed with the field format
+	TypeDate Type = "date"
+
+	// TypeGeoPoint latitude and longitude given as {"lat": 1, "lon": 2} object, "lat,lon" string or geohash
+	TypeGeoPoint Type = "geo_point"
+
+	// TypeIP IPv4 or IPv6 address, IPv4 addresses are compared as IPv4-mapped IPv6 ones
+	TypeIP Type = "ip"
+
+	// TypeDenseVector array of float32 numbers of the fixed length searched by the knn query
+	TypeDenseVector Type = "dense_vector"
 )
 
 func (t Type) Valid() bool {
-	return t == TypeBool ||
+	return t == TypeAll ||
+		t == TypeBool ||
 		t == TypeKeyword ||
 		t == TypeText ||
 		t == TypeSlice ||
@@ -44,7 +60,11 @@ func (t Type) Valid() bool {
 		t == TypeShort ||
 		t == TypeByte ||
 		t == TypeDouble ||
-		t == TypeFloat
+		t == TypeFloat ||
+		t == TypeDate ||
+		t == TypeGeoPoint ||
+		t == TypeIP ||
+		t == TypeDenseVector
 }
 
 type Field struct {
@@ -52,6 +72,44 @@ type Field struct {
 	Required bool             `json:"required"`
 	Children map[string]Field `json:"children"`
 	Analyzer string           `json:"analyzer"`
+	BM25     *BM25            `json:"bm25,omitempty"`
+	// Format of the date field values: "rfc3339", "epoch_millis" or Go time layouts separated by "||"
+	Format string `json:"format,omitempty"`
+	// Dims number of dimensions of the dense vector field
+	Dims int `json:"dims,omitempty"`
+	// VectorSimilarity function the dense vectors are compared with, cosine by default
+	VectorSimilarity vector.Similarity `json:"similarity,omitempty"`
+	// Fields sub-fields indexing the same value with other types or analyzers, addressed as "field.subfield"
+	Fields map[string]Field `json:"fields,omitempty"`
+	// CopyTo names of the fields of type all the value is indexed to
+	CopyTo []string `json:"copy_to,omitempty"`
+}
+
+const (
+	DefaultBM25K1 = 1.2
+	DefaultBM25B  = 0.75
+)
+
+// BM25 relevance scoring settings of the text field
+type BM25 struct {
+	K1 float64 `json:"k1"` // term frequency saturation
+	B  float64 `json:"b"`  // field length normalization
+}
+
+func (b BM25) Validate() error {
+	return validation.ValidateStruct(&b,
+		validation.Field(&b.K1, validation.Min(0.0)),
+		validation.Field(&b.B, validation.Min(0.0), validation.Max(1.0)),
+	)
+}
+
+// Similarity get BM25 settings of the field or the default ones
+func (f Field) Similarity() BM25 {
+	if f.BM25 != nil {
+		return *f.BM25
+	}
+
+	return BM25{K1: DefaultBM25K1, B: DefaultBM25B}
 }
 
 func NewField(fieldType Type, required bool, analyzer string) Field {
@@ -77,17 +135,106 @@ func (f Field) ValidateWithContext(ctx context.Context) error {
 			return err
 		}
 	}
+	if f.Fields != nil {
+		if err := validateKeys("fields", f.Fields); err != nil {
+			return err
+		}
+	}
 
 	return validation.ValidateStructWithContext(ctx, &f,
 		validation.Field(&f.Type, validation.Required, validation.By(validateFieldType())),
+		validation.Field(&f.Required, validation.When(f.Type == TypeAll, validation.Empty.Error("field of type all cannot be required"))),
 		validation.Field(
 			&f.Analyzer,
-			validation.When(f.Type == TypeText, validation.Required),
+			validation.When(f.Type == TypeText || f.Type == TypeAll, validation.Required),
 			validation.WithContext(validateFieldAnalyzers(f.Type))),
 		validation.Field(&f.Children, validation.By(validateFieldChildren(f.Type))),
+		validation.Field(&f.BM25, validation.When(f.Type != TypeText && f.Type != TypeAll, validation.Nil.Error("allowed only for text and all fields"))),
+		validation.Field(&f.Format, validation.By(validateFieldFormat(f.Type))),
+		validation.Field(
+			&f.Dims,
+			validation.When(f.Type == TypeDenseVector, validation.Required, validation.Min(1), validation.Max(vector.MaxDims)),
+			validation.When(f.Type != TypeDenseVector, validation.Empty.Error("allowed only for dense_vector fields")),
+		),
+		validation.Field(
+			&f.VectorSimilarity,
+			validation.When(f.Type != TypeDenseVector, validation.Empty.Error("allowed only for dense_vector fields")),
+		),
+		validation.Field(&f.Fields, validation.By(validateSubFields(f.Type))),
+		validation.Field(
+			&f.CopyTo,
+			validation.When(f.Type != TypeText && f.Type != TypeKeyword, validation.Empty.Error("allowed only for text and keyword fields")),
+			validation.WithContext(validateCopyTo()),
+		),
 	)
 }
 
+// validateCopyTo check the fields the value is copied to are of type all
+func validateCopyTo() validation.RuleWithContextFunc {
+	return func(ctx context.Context, value interface{}) error {
+		s := ctx.Value(ctxKeySchema).(Schema)
+		for _, name := range value.([]string) {
+			target, ok := s.Fields[name]
+			if !ok {
+				return errs.Errorf("unknown field %q", name)
+			}
+			if target.Type != TypeAll {
+				return errs.Errorf("field %q must be of type %q", name, TypeAll)
+			}
+		}
+
+		return nil
+	}
+}
+
+// validateSubFields check the sub-fields can be indexed from the value of the field.
+// Sub-fields hold single values, so they cannot be required, have children, sub-fields or copy_to of their own
+func validateSubFields(t Type) validation.RuleFunc {
+	return func(value interface{}) error {
+		v := value.(map[string]Field)
+		if len(v) == 0 {
+			return nil
+		}
+		if t == TypeSlice || t == TypeMap || t == TypeAll {
+			return errs.Errorf("type %q cannot have sub-fields", t)
+		}
+
+		for name, sub := range v {
+			switch {
+			case sub.Type == TypeSlice || sub.Type == TypeMap:
+				return errs.Errorf("sub-field %q cannot be of type %q", name, sub.Type)
+			case sub.Required:
+				return errs.Errorf("sub-field %q cannot be required", name)
+			case len(sub.Fields) != 0:
+				return errs.Errorf("sub-field %q cannot have sub-fields", name)
+			case len(sub.CopyTo) != 0:
+				return errs.Errorf("sub-field %q cannot be copied to other fields", name)
+			}
+		}
+
+		return nil
+	}
+}
+
+// DateFormats get the formats the values of the date field are parsed with
+func (f Field) DateFormats() date.Formats {
+	return date.ParseFormats(f.Format)
+}
+
+func validateFieldFormat(t Type) validation.RuleFunc {
+	return func(value interface{}) error {
+		v := value.(string)
+		if v == "" {
+			return nil
+		}
+		if t != TypeDate {
+			return errs.Errorf("allowed only for date fields")
+		}
+
+		return date.ParseFormats(v).Validate()
+	}
+}
+
 func validateFieldType() validation.RuleFunc {
 	return func(value interface{}) error {
 		v := value.(Type)
@@ -134,6 +281,16 @@ func validateFieldChildren(t Type) validation.RuleFunc {
 			return errs.Errorf("type %q cannot have children fields", t)
 		}
 
+		// values are copied between the top level fields only
+		for name, child := range v {
+			if child.Type == TypeAll {
+				return errs.Errorf("child field %q cannot be of type %q", name, TypeAll)
+			}
+			if len(child.CopyTo) != 0 {
+				return errs.Errorf("child field %q cannot be copied to other fields", name)
+			}
+		}
+
 		return nil
 	}
 }
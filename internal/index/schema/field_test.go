@@ -0,0 +1,20 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Field_Similarity(t *testing.T) {
+	t.Run("must return default settings", func(t *testing.T) {
+		f := NewField(TypeText, false, "analyzer")
+		require.Equal(t, BM25{K1: DefaultBM25K1, B: DefaultBM25B}, f.Similarity())
+	})
+
+	t.Run("must return field settings", func(t *testing.T) {
+		f := NewField(TypeText, false, "analyzer")
+		f.BM25 = &BM25{K1: 2, B: 0.5}
+		require.Equal(t, BM25{K1: 2, B: 0.5}, f.Similarity())
+	})
+}
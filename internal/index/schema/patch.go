@@ -0,0 +1,131 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/invopop/validation"
+)
+
+// Patch add the fields and analyzers to the schema. Existing ones with the same names are replaced.
+// The dynamic setting is replaced if the patch has it
+func (s Schema) Patch(patch Schema) Schema {
+	dynamic := s.Dynamic
+	if patch.Dynamic != "" {
+		dynamic = patch.Dynamic
+	}
+
+	return Schema{
+		Analyzers: patchMap(s.Analyzers, patch.Analyzers),
+		Fields:    patchMap(s.Fields, patch.Fields),
+		Dynamic:   dynamic,
+	}
+}
+
+func patchMap[T any](m map[string]T, patch map[string]T) map[string]T {
+	if m == nil && patch == nil {
+		return nil
+	}
+
+	result := make(map[string]T, len(m)+len(patch))
+	for k, v := range m {
+		result[k] = v
+	}
+	for k, v := range patch {
+		result[k] = v
+	}
+
+	return result
+}
+
+// ValidateCompatible check that the documents valid for the old schema stay valid for the new one:
+// fields cannot be removed, change their types or become required, new fields must be optional,
+// date fields can only get new formats, dense vector fields cannot change their dimensions and similarity.
+// Sub-fields follow the same rules, new ones must accept all the values of their field.
+// The schema allowing unknown fields cannot become strict as the stored documents could have them
+func ValidateCompatible(old Schema, new Schema) error {
+	result := validation.Errors{}
+	validateCompatibleFields("fields", old.Fields, new.Fields, result)
+	if old.Dynamic.allowUnknown() && !new.Dynamic.allowUnknown() {
+		result["dynamic"] = validation.NewError("", fmt.Sprintf("dynamic cannot be changed from %q to %q", old.Dynamic, DynamicStrict))
+	}
+
+	if len(result) > 0 {
+		return result
+	}
+
+	return nil
+}
+
+func validateCompatibleFields(path string, old map[string]Field, new map[string]Field, result validation.Errors) {
+	for name, oldField := range old {
+		key := path + "." + name
+
+		newField, ok := new[name]
+		if !ok {
+			result[key] = validation.NewError("", "field cannot be removed")
+			continue
+		}
+		if newField.Type != oldField.Type {
+			result[key] = validation.NewError("", fmt.Sprintf("field type cannot be changed from %q to %q", oldField.Type, newField.Type))
+			continue
+		}
+		if newField.Required && !oldField.Required {
+			result[key] = validation.NewError("", "optional field cannot become required")
+			continue
+		}
+		if missing := missingFormat(oldField, newField); missing != "" {
+			result[key] = validation.NewError("", fmt.Sprintf("date format %q cannot be removed", missing))
+			continue
+		}
+		if newField.Dims != oldField.Dims || newField.VectorSimilarity.OrDefault() != oldField.VectorSimilarity.OrDefault() {
+			result[key] = validation.NewError("", "vector dims and similarity cannot be changed")
+			continue
+		}
+
+		validateCompatibleFields(key, oldField.Children, newField.Children, result)
+		validateCompatibleFields(key+".fields", oldField.Fields, newField.Fields, result)
+		for subName, sub := range newField.Fields {
+			if _, ok := oldField.Fields[subName]; !ok && !acceptsValues(newField, sub) {
+				result[key+".fields."+subName] = validation.NewError("", fmt.Sprintf("new sub-field of type %q cannot index all the values of type %q", sub.Type, newField.Type))
+			}
+		}
+	}
+
+	for name, newField := range new {
+		if _, ok := old[name]; !ok && newField.Required {
+			result[path+"."+name] = validation.NewError("", "new field must be optional")
+		}
+	}
+}
+
+// acceptsValues whether any valid value of the field is valid for the sub-field
+func acceptsValues(f Field, sub Field) bool {
+	isString := func(t Type) bool { return t == TypeText || t == TypeKeyword }
+	if isString(f.Type) && isString(sub.Type) {
+		return true
+	}
+	if f.Type != sub.Type {
+		return false
+	}
+
+	return missingFormat(f, sub) == "" && f.Dims == sub.Dims
+}
+
+// missingFormat get the format of the old date field the new one cannot parse values with
+func missingFormat(old Field, new Field) string {
+	if old.Type != TypeDate {
+		return ""
+	}
+
+	formats := make(map[string]struct{})
+	for _, f := range new.DateFormats() {
+		formats[f] = struct{}{}
+	}
+	for _, f := range old.DateFormats() {
+		if _, ok := formats[f]; !ok {
+			return f
+		}
+	}
+
+	return ""
+}
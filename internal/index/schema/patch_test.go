@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/analyzer"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Schema_Patch(t *testing.T) {
+	t.Run("must add and replace fields and analyzers", func(t *testing.T) {
+		s := NewSchema(
+			map[string]Field{"name": {Type: TypeKeyword}, "text": {Type: TypeText, Analyzer: "a"}},
+			map[string]FieldAnalyzer{"a": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}}},
+		)
+		patch := NewSchema(
+			map[string]Field{"text": {Type: TypeText, Analyzer: "b"}, "flag": {Type: TypeBool}},
+			map[string]FieldAnalyzer{"b": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}}},
+		)
+
+		result := s.Patch(patch)
+		require.Equal(t, map[string]Field{
+			"name": {Type: TypeKeyword},
+			"text": {Type: TypeText, Analyzer: "b"},
+			"flag": {Type: TypeBool},
+		}, result.Fields)
+		require.Len(t, result.Analyzers, 2)
+		require.Len(t, s.Fields, 2, "must not modify the original schema")
+	})
+
+	t.Run("must replace dynamic setting only if it is set", func(t *testing.T) {
+		s := Schema{Dynamic: DynamicIgnore}
+		require.Equal(t, DynamicIgnore, s.Patch(Schema{}).Dynamic)
+		require.Equal(t, DynamicTrue, s.Patch(Schema{Dynamic: DynamicTrue}).Dynamic)
+	})
+}
+
+func Test_ValidateCompatible(t *testing.T) {
+	old := NewSchema(map[string]Field{
+		"name":     {Type: TypeKeyword, Required: true},
+		"optional": {Type: TypeKeyword},
+		"tags": {Type: TypeMap, Children: map[string]Field{
+			"value": {Type: TypeKeyword},
+		}},
+	}, nil)
+
+	t.Run("must allow new optional fields and relaxed requirements", func(t *testing.T) {
+		new := NewSchema(map[string]Field{
+			"name":     {Type: TypeKeyword},
+			"optional": {Type: TypeKeyword},
+			"tags": {Type: TypeMap, Children: map[string]Field{
+				"value": {Type: TypeKeyword},
+				"extra": {Type: TypeBool},
+			}},
+			"new": {Type: TypeBool},
+		}, nil)
+		require.NoError(t, ValidateCompatible(old, new))
+	})
+
+	t.Run("must allow only new date formats", func(t *testing.T) {
+		old := NewSchema(map[string]Field{"date": {Type: TypeDate}}, nil)
+
+		err := ValidateCompatible(old, NewSchema(map[string]Field{"date": {Type: TypeDate, Format: "2006-01-02||rfc3339||epoch_millis"}}, nil))
+		require.NoError(t, err)
+
+		err = ValidateCompatible(old, NewSchema(map[string]Field{"date": {Type: TypeDate, Format: "rfc3339"}}, nil))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "fields.date")
+	})
+
+	t.Run("must not allow to change vector settings", func(t *testing.T) {
+		old := NewSchema(map[string]Field{"vector": {Type: TypeDenseVector, Dims: 3}}, nil)
+
+		err := ValidateCompatible(old, NewSchema(map[string]Field{"vector": {Type: TypeDenseVector, Dims: 3, VectorSimilarity: "cosine"}}, nil))
+		require.NoError(t, err)
+
+		for _, f := range []Field{
+			{Type: TypeDenseVector, Dims: 4},
+			{Type: TypeDenseVector, Dims: 3, VectorSimilarity: "l2"},
+		} {
+			err := ValidateCompatible(old, NewSchema(map[string]Field{"vector": f}, nil))
+			var ve validation.Errors
+			require.ErrorAs(t, err, &ve)
+			require.Contains(t, ve, "fields.vector")
+		}
+	})
+
+	t.Run("must allow only sub-fields accepting the field values", func(t *testing.T) {
+		old := NewSchema(map[string]Field{"name": {Type: TypeKeyword, Fields: map[string]Field{"ip": {Type: TypeIP}}}}, nil)
+
+		err := ValidateCompatible(old, NewSchema(map[string]Field{"name": {Type: TypeKeyword, Fields: map[string]Field{
+			"ip":   {Type: TypeIP},
+			"text": {Type: TypeText, Analyzer: "a"},
+			"raw":  {Type: TypeKeyword},
+		}}}, nil))
+		require.NoError(t, err)
+
+		err = ValidateCompatible(old, NewSchema(map[string]Field{"name": {Type: TypeKeyword, Fields: map[string]Field{
+			"date": {Type: TypeDate},
+		}}}, nil))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "fields.name.fields.ip")
+		require.Contains(t, ve, "fields.name.fields.date")
+		require.Len(t, ve, 2)
+	})
+
+	t.Run("must not allow the schema with unknown fields to become strict", func(t *testing.T) {
+		for _, d := range []Dynamic{DynamicIgnore, DynamicTrue} {
+			from := old
+			from.Dynamic = d
+
+			err := ValidateCompatible(from, old)
+			require.Error(t, err)
+
+			var ve validation.Errors
+			require.ErrorAs(t, err, &ve)
+			require.Contains(t, ve, "dynamic")
+
+			to := old
+			to.Dynamic = DynamicStrict
+			require.Error(t, ValidateCompatible(from, to))
+			require.NoError(t, ValidateCompatible(from, from.Patch(Schema{})))
+		}
+
+		relaxed := old
+		relaxed.Dynamic = DynamicTrue
+		require.NoError(t, ValidateCompatible(old, relaxed))
+	})
+
+	t.Run("must reject incompatible changes", func(t *testing.T) {
+		new := NewSchema(map[string]Field{
+			"name":     {Type: TypeText, Required: true},
+			"optional": {Type: TypeKeyword, Required: true},
+			"tags": {Type: TypeMap, Children: map[string]Field{
+				"extra": {Type: TypeBool},
+			}},
+			"new": {Type: TypeBool, Required: true},
+		}, nil)
+
+		err := ValidateCompatible(old, new)
+		require.Error(t, err)
+
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "fields.name")
+		require.Contains(t, ve, "fields.optional")
+		require.Contains(t, ve, "fields.tags.value")
+		require.Contains(t, ve, "fields.new")
+		require.Len(t, ve, 4)
+	})
+}
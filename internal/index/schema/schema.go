@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/f1monkey/search/internal/index/analyzer"
 	"github.com/invopop/validation"
@@ -26,6 +28,7 @@ func (a FieldAnalyzer) Validate() error {
 type Schema struct {
 	Analyzers map[string]FieldAnalyzer `json:"analyzers"`
 	Fields    map[string]Field         `json:"fields"`
+	Dynamic   Dynamic                  `json:"dynamic,omitempty"`
 }
 
 func NewSchema(fields map[string]Field, analyzers map[string]FieldAnalyzer) Schema {
@@ -35,6 +38,82 @@ func NewSchema(fields map[string]Field, analyzers map[string]FieldAnalyzer) Sche
 	}
 }
 
+// Field get the field by name. Sub-fields are addressed by the dotted name like "title.raw"
+func (s Schema) Field(name string) (Field, bool) {
+	if f, ok := s.Fields[name]; ok {
+		return f, true
+	}
+
+	parent, sub, ok := strings.Cut(name, ".")
+	if !ok {
+		return Field{}, false
+	}
+	f, ok := s.Fields[parent].Fields[sub]
+
+	return f, ok
+}
+
+// IndexedFields get the fields together with their sub-fields keyed by the dotted names
+func (s Schema) IndexedFields() map[string]Field {
+	result := make(map[string]Field, len(s.Fields))
+	for name, f := range s.Fields {
+		result[name] = f
+		for subName, sub := range f.Fields {
+			result[name+"."+subName] = sub
+		}
+	}
+
+	return result
+}
+
+// IndexedSource get the source with the values of the fields copied to their sub-fields
+// and the string values of the fields with copy_to collected to the fields of type all in the order of the field names.
+// The source is returned as is if there is nothing to copy
+func (s Schema) IndexedSource(source Source) Source {
+	var result Source
+	set := func(name string, v interface{}) {
+		if result == nil {
+			result = make(Source, len(source)+1)
+			for k, v := range source {
+				result[k] = v
+			}
+		}
+		result[name] = v
+	}
+
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	all := make(map[string][]string)
+	for _, name := range names {
+		f := s.Fields[name]
+		v, ok := source[name]
+		if !ok {
+			continue
+		}
+		for subName := range f.Fields {
+			set(name+"."+subName, v)
+		}
+		if str, ok := v.(string); ok {
+			for _, target := range f.CopyTo {
+				all[target] = append(all[target], str)
+			}
+		}
+	}
+	for name, values := range all {
+		set(name, values)
+	}
+
+	if result == nil {
+		return source
+	}
+
+	return result
+}
+
 func (s Schema) ValidateDoc(doc map[string]interface{}) error {
 	return ValidateDoc(s, doc)
 }
@@ -51,9 +130,18 @@ func (s Schema) Validate() error {
 		return err
 	}
 
+	for name, f := range s.Fields {
+		for subName := range f.Fields {
+			if _, ok := s.Fields[name+"."+subName]; ok {
+				return validation.Errors{"fields": validation.NewError("", fmt.Sprintf("field %q conflicts with the sub-field of %q", name+"."+subName, name))}
+			}
+		}
+	}
+
 	return validation.ValidateStructWithContext(ctx, &s,
 		validation.Field(&s.Fields, validation.Required),
 		validation.Field(&s.Analyzers),
+		validation.Field(&s.Dynamic),
 	)
 }
 
@@ -126,14 +126,126 @@ func Test_Schema_Validate(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("must fail if bm25 settings provided for non-text field", func(t *testing.T) {
+		s := NewSchema(
+			map[string]Field{
+				"name": {Type: TypeKeyword, BM25: &BM25{K1: 1, B: 0.5}},
+			},
+			nil,
+		)
+		err := validation.Validate(s)
+		require.Error(t, err)
+	})
+
+	t.Run("must fail if bm25 settings are out of range", func(t *testing.T) {
+		s := NewSchema(
+			map[string]Field{
+				"name": {Type: TypeText, Analyzer: "analyzer", BM25: &BM25{K1: 1, B: 1.5}},
+			},
+			map[string]FieldAnalyzer{
+				"analyzer": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}},
+			},
+		)
+		err := validation.Validate(s)
+		require.Error(t, err)
+	})
+
+	t.Run("must fail if date format is invalid", func(t *testing.T) {
+		s := NewSchema(map[string]Field{"name": {Type: TypeDate, Format: "rfc3339||"}}, nil)
+		err := validation.Validate(s)
+		require.Error(t, err)
+	})
+
+	t.Run("must fail if format provided for non-date field", func(t *testing.T) {
+		s := NewSchema(map[string]Field{"name": {Type: TypeKeyword, Format: "rfc3339"}}, nil)
+		err := validation.Validate(s)
+		require.Error(t, err)
+	})
+
+	t.Run("must fail if dense vector settings are invalid", func(t *testing.T) {
+		for _, f := range []Field{
+			{Type: TypeDenseVector},
+			{Type: TypeDenseVector, Dims: -1},
+			{Type: TypeDenseVector, Dims: 5000},
+			{Type: TypeDenseVector, Dims: 3, VectorSimilarity: "manhattan"},
+			{Type: TypeKeyword, Dims: 3},
+			{Type: TypeKeyword, VectorSimilarity: "cosine"},
+		} {
+			err := validation.Validate(NewSchema(map[string]Field{"name": f}, nil))
+			require.Error(t, err, "%#v", f)
+		}
+	})
+
+	t.Run("must fail if sub-fields are invalid", func(t *testing.T) {
+		analyzers := map[string]FieldAnalyzer{"analyzer": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}}}
+		for _, f := range []Field{
+			{Type: TypeKeyword, Fields: map[string]Field{"text": {Type: TypeText, Analyzer: "unknown"}}},
+			{Type: TypeKeyword, Fields: map[string]Field{"text": {Type: TypeText}}},
+			{Type: TypeKeyword, Fields: map[string]Field{"raw": {Type: TypeKeyword, Required: true}}},
+			{Type: TypeKeyword, Fields: map[string]Field{"raw": {Type: TypeKeyword, Fields: map[string]Field{"raw": {Type: TypeKeyword}}}}},
+			{Type: TypeKeyword, Fields: map[string]Field{"map": {Type: TypeMap, Children: map[string]Field{"a": {Type: TypeBool}}}}},
+			{Type: TypeMap, Children: map[string]Field{"a": {Type: TypeBool}}, Fields: map[string]Field{"raw": {Type: TypeKeyword}}},
+			{Type: TypeKeyword, Fields: map[string]Field{"": {Type: TypeKeyword}}},
+		} {
+			err := validation.Validate(NewSchema(map[string]Field{"name": f}, analyzers))
+			require.Error(t, err, "%#v", f)
+		}
+
+		err := validation.Validate(NewSchema(map[string]Field{
+			"name":     {Type: TypeKeyword, Fields: map[string]Field{"raw": {Type: TypeKeyword}}},
+			"name.raw": {Type: TypeKeyword},
+		}, nil))
+		require.Error(t, err)
+	})
+
+	t.Run("must fail if copy_to or all fields are invalid", func(t *testing.T) {
+		analyzers := map[string]FieldAnalyzer{"analyzer": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}}}
+		for _, fields := range []map[string]Field{
+			{"name": {Type: TypeKeyword, CopyTo: []string{"unknown"}}},
+			{"name": {Type: TypeKeyword, CopyTo: []string{"other"}}, "other": {Type: TypeKeyword}},
+			{"name": {Type: TypeLong, CopyTo: []string{"all"}}, "all": {Type: TypeAll, Analyzer: "analyzer"}},
+			{"all": {Type: TypeAll}},
+			{"all": {Type: TypeAll, Analyzer: "analyzer", Required: true}},
+			{"all": {Type: TypeAll, Analyzer: "analyzer", Fields: map[string]Field{"raw": {Type: TypeKeyword}}}},
+			{"all": {Type: TypeAll, Analyzer: "analyzer"}, "name": {Type: TypeKeyword, Fields: map[string]Field{
+				"raw": {Type: TypeKeyword, CopyTo: []string{"all"}},
+			}}},
+			{"all": {Type: TypeAll, Analyzer: "analyzer"}, "name": {Type: TypeMap, Children: map[string]Field{
+				"child": {Type: TypeKeyword, CopyTo: []string{"all"}},
+			}}},
+			{"name": {Type: TypeMap, Children: map[string]Field{"all": {Type: TypeAll, Analyzer: "analyzer"}}}},
+		} {
+			err := validation.Validate(NewSchema(fields, analyzers))
+			require.Error(t, err, "%#v", fields)
+		}
+	})
+
+	t.Run("must fail if dynamic setting is invalid", func(t *testing.T) {
+		s := NewSchema(map[string]Field{"name": {Type: TypeKeyword}}, nil)
+		s.Dynamic = "unknown"
+		err := validation.Validate(s)
+		require.Error(t, err)
+	})
+
 	t.Run("must not fail for vaild fields", func(t *testing.T) {
 		s := NewSchema(
 			map[string]Field{
 				"name":  {Type: TypeBool},
-				"name2": {Type: TypeText, Analyzer: "analyzer"},
+				"name2": {Type: TypeText, Analyzer: "analyzer", BM25: &BM25{K1: 2, B: 0}},
 				"name3": {Type: TypeSlice, Children: map[string]Field{
 					"name": {Type: TypeKeyword},
 				}},
+				"name4": {Type: TypeDate, Format: "2006-01-02||epoch_millis"},
+				"name5": {Type: TypeGeoPoint},
+				"name6": {Type: TypeIP},
+				"name7": {Type: TypeDenseVector, Dims: 3},
+				"name8": {Type: TypeDenseVector, Dims: 3, VectorSimilarity: "dot_product"},
+				"name9": {Type: TypeText, Analyzer: "analyzer", Fields: map[string]Field{
+					"raw":   {Type: TypeKeyword},
+					"other": {Type: TypeText, Analyzer: "analyzer"},
+				}, CopyTo: []string{"all"}},
+				"name10": {Type: TypeKeyword, CopyTo: []string{"all"}},
+				"all":    {Type: TypeAll, Analyzer: "analyzer", BM25: &BM25{K1: 1, B: 0.5}},
 			},
 			map[string]FieldAnalyzer{
 				"analyzer": {Analyzers: []analyzer.Analyzer{
@@ -145,3 +257,63 @@ func Test_Schema_Validate(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func Test_Schema_Field(t *testing.T) {
+	s := NewSchema(map[string]Field{
+		"title": {Type: TypeText, Analyzer: "a", Fields: map[string]Field{"raw": {Type: TypeKeyword}}},
+		"count": {Type: TypeLong},
+	}, nil)
+
+	t.Run("must find fields and sub-fields by name", func(t *testing.T) {
+		f, ok := s.Field("count")
+		require.True(t, ok)
+		require.Equal(t, TypeLong, f.Type)
+
+		f, ok = s.Field("title.raw")
+		require.True(t, ok)
+		require.Equal(t, TypeKeyword, f.Type)
+
+		for _, name := range []string{"title.other", "count.raw", "unknown", "unknown.raw", ""} {
+			_, ok = s.Field(name)
+			require.False(t, ok, name)
+		}
+	})
+
+	t.Run("must list sub-fields with dotted names", func(t *testing.T) {
+		require.Equal(t, map[string]Field{
+			"title":     s.Fields["title"],
+			"title.raw": {Type: TypeKeyword},
+			"count":     {Type: TypeLong},
+		}, s.IndexedFields())
+	})
+
+	t.Run("must copy values to sub-fields", func(t *testing.T) {
+		source := Source{"title": "Hello", "count": 1}
+		require.Equal(t, Source{"title": "Hello", "title.raw": "Hello", "count": 1}, s.IndexedSource(source))
+		require.Len(t, source, 2, "must not modify the original source")
+
+		source = Source{"count": 1}
+		require.Equal(t, source, s.IndexedSource(source))
+	})
+
+	t.Run("must collect string values to all fields", func(t *testing.T) {
+		s := NewSchema(map[string]Field{
+			"title": {Type: TypeText, Analyzer: "a", CopyTo: []string{"all"}},
+			"brand": {Type: TypeKeyword, CopyTo: []string{"all", "names"}},
+			"tag":   {Type: TypeKeyword},
+			"all":   {Type: TypeAll, Analyzer: "a"},
+			"names": {Type: TypeAll, Analyzer: "a"},
+		}, nil)
+
+		require.Equal(t, Source{
+			"title": "Phone",
+			"brand": "Acme",
+			"tag":   "sale",
+			"all":   []string{"Acme", "Phone"},
+			"names": []string{"Acme"},
+		}, s.IndexedSource(Source{"title": "Phone", "brand": "Acme", "tag": "sale"}))
+
+		source := Source{"tag": "sale", "title": nil}
+		require.Equal(t, source, s.IndexedSource(source))
+	})
+}
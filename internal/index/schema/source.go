@@ -0,0 +1,30 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// register the dynamic types of decoded JSON values so that documents can be gob-encoded by snapshot storages
+func init() {
+	gob.Register(json.Number(""))
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+type Source map[string]interface{}
+
+// UnmarshalJSON decodes numbers as json.Number to keep them comparable with field bounds
+func (s *Source) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	*s = m
+
+	return nil
+}
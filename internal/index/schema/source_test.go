@@ -0,0 +1,41 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Source_UnmarshalJSON(t *testing.T) {
+	t.Run("must decode numbers as json.Number", func(t *testing.T) {
+		s := Source{}
+		err := json.Unmarshal([]byte(`{"int":10000000000000000000,"float":1.5,"nested":{"value":1}}`), &s)
+		require.NoError(t, err)
+		require.Equal(t, json.Number("10000000000000000000"), s["int"])
+		require.Equal(t, json.Number("1.5"), s["float"])
+		require.Equal(t, map[string]interface{}{"value": json.Number("1")}, s["nested"])
+	})
+
+	t.Run("must fail if value is not an object", func(t *testing.T) {
+		s := Source{}
+		err := json.Unmarshal([]byte(`[1, 2]`), &s)
+		require.Error(t, err)
+	})
+}
+
+func Test_Source_Gob(t *testing.T) {
+	t.Run("must encode and decode all JSON value types", func(t *testing.T) {
+		source := Source{}
+		require.NoError(t, json.Unmarshal([]byte(`{"a":"b","n":1.5,"b":true,"s":[1,"x"],"m":{"k":2}}`), &source))
+
+		buf := bytes.Buffer{}
+		require.NoError(t, gob.NewEncoder(&buf).Encode(source))
+
+		result := Source{}
+		require.NoError(t, gob.NewDecoder(&buf).Decode(&result))
+		require.Equal(t, source, result)
+	})
+}
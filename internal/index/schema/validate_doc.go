@@ -4,23 +4,34 @@ import (
 	"encoding/json"
 	"math"
 	"strconv"
+	"time"
 
 	"github.com/f1monkey/errs"
+	"github.com/f1monkey/search/internal/index/date"
+	"github.com/f1monkey/search/internal/index/geo"
+	"github.com/f1monkey/search/internal/index/ip"
+	"github.com/f1monkey/search/internal/index/vector"
 	"github.com/invopop/validation"
 )
 
-type Source map[string]interface{}
-
+// ValidateDoc check the document against the schema.
+// Errors of the nested fields are reported with the full paths like "tags.2.name".
+// Fields not declared in the schema are allowed only if the schema is not strict
 func ValidateDoc(s Schema, source Source) error {
-	rules := buildRules(s, source)
+	rules := buildRules(s.Fields, source, s.Dynamic.allowUnknown())
+
+	err := rules.Validate(source)
+	if ve, ok := err.(validation.Errors); ok {
+		return flattenErrors("", ve, validation.Errors{})
+	}
 
-	return rules.Validate(source)
+	return err
 }
 
-func buildRules(s Schema, source Source) validation.MapRule {
+func buildRules(fields map[string]Field, source map[string]interface{}, allowUnknown bool) validation.MapRule {
 	var rules []*validation.KeyRules
 
-	for name, f := range s.Fields {
+	for name, f := range fields {
 		var keyRules []validation.Rule
 		if f.Required {
 			keyRules = append(keyRules, validation.Required)
@@ -28,35 +39,182 @@ func buildRules(s Schema, source Source) validation.MapRule {
 			continue
 		}
 
-		switch f.Type {
-		case TypeBool:
-			keyRules = append(keyRules, validation.By(validateBool()))
-		case TypeKeyword:
-			keyRules = append(keyRules, validation.By(validateKeyword()))
-		case TypeText:
-			keyRules = append(keyRules, validation.By(validateText()))
-		case TypeByte:
-			keyRules = append(keyRules, validation.By(validateInt(math.MinInt8, math.MaxInt8)))
-		case TypeShort:
-			keyRules = append(keyRules, validation.By(validateInt(math.MinInt16, math.MaxInt16)))
-		case TypeInteger:
-			keyRules = append(keyRules, validation.By(validateInt(math.MinInt32, math.MaxInt32)))
-		case TypeLong:
-			keyRules = append(keyRules, validation.By(validateInt(math.MinInt64, math.MaxInt64)))
-		case TypeUnsignedLong:
-			keyRules = append(keyRules, validation.By(validateUint(0, math.MaxUint64)))
-		case TypeFloat:
-			keyRules = append(keyRules, validation.By(validateFloat(-1*math.MaxFloat32, math.MaxFloat32)))
-		case TypeDouble:
-			keyRules = append(keyRules, validation.By(validateFloat(-1*math.MaxFloat64, math.MaxFloat64)))
+		keyRules = append(keyRules, typeRules(f, allowUnknown)...)
+		// the value is indexed to the sub-fields too, so it must be valid for their types
+		for _, sub := range f.Fields {
+			keyRules = append(keyRules, typeRules(sub, allowUnknown)...)
 		}
 
 		rules = append(rules, validation.Key(name, keyRules...))
 	}
 
+	if allowUnknown {
+		return validation.Map(rules...).AllowExtraKeys()
+	}
+
 	return validation.Map(rules...)
 }
 
+// typeRules check the value matches the field type
+func typeRules(f Field, allowUnknown bool) []validation.Rule {
+	switch f.Type {
+	case TypeBool:
+		return []validation.Rule{validation.By(validateBool())}
+	case TypeKeyword:
+		return []validation.Rule{validation.By(validateKeyword())}
+	case TypeText:
+		return []validation.Rule{validation.By(validateText())}
+	case TypeByte:
+		return []validation.Rule{validation.By(validateInt(math.MinInt8, math.MaxInt8))}
+	case TypeShort:
+		return []validation.Rule{validation.By(validateInt(math.MinInt16, math.MaxInt16))}
+	case TypeInteger:
+		return []validation.Rule{validation.By(validateInt(math.MinInt32, math.MaxInt32))}
+	case TypeLong:
+		return []validation.Rule{validation.By(validateInt(math.MinInt64, math.MaxInt64))}
+	case TypeUnsignedLong:
+		return []validation.Rule{validation.By(validateUint(0, math.MaxUint64))}
+	case TypeFloat:
+		return []validation.Rule{validation.By(validateFloat(-1*math.MaxFloat32, math.MaxFloat32))}
+	case TypeDouble:
+		return []validation.Rule{validation.By(validateFloat(-1*math.MaxFloat64, math.MaxFloat64))}
+	case TypeDate:
+		return []validation.Rule{validation.By(validateDate(f.DateFormats()))}
+	case TypeGeoPoint:
+		return []validation.Rule{validation.By(validateGeoPoint())}
+	case TypeIP:
+		return []validation.Rule{validation.By(validateIP())}
+	case TypeDenseVector:
+		return []validation.Rule{validation.By(validateVector(f.Dims, f.VectorSimilarity))}
+	case TypeAll:
+		return []validation.Rule{validation.By(validateAll())}
+	case TypeMap:
+		return []validation.Rule{validation.By(validateMap(f.Children, allowUnknown))}
+	case TypeSlice:
+		return []validation.Rule{validation.By(validateSlice(f.Children, allowUnknown))}
+	}
+
+	return nil
+}
+
+// flattenErrors put the errors of the nested fields to the result with the keys joined by dots
+func flattenErrors(prefix string, errors validation.Errors, result validation.Errors) validation.Errors {
+	for k, err := range errors {
+		if ve, ok := err.(validation.Errors); ok {
+			flattenErrors(prefix+k+".", ve, result)
+			continue
+		}
+		result[prefix+k] = err
+	}
+
+	return result
+}
+
+// validateDate check the value matches any of the formats. Values without the time zone are taken as UTC
+func validateDate(formats date.Formats) validation.RuleFunc {
+	return func(v interface{}) error {
+		if v == nil {
+			return nil
+		}
+		if _, err := formats.Parse(v, time.UTC); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func validateGeoPoint() validation.RuleFunc {
+	return func(v interface{}) error {
+		if v == nil {
+			return nil
+		}
+		if _, err := geo.Parse(v); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func validateIP() validation.RuleFunc {
+	return func(v interface{}) error {
+		if v == nil {
+			return nil
+		}
+		if _, err := ip.ParseValue(v); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func validateVector(dims int, similarity vector.Similarity) validation.RuleFunc {
+	return func(v interface{}) error {
+		if v == nil {
+			return nil
+		}
+		if _, err := vector.Parse(v, dims, similarity); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// validateAll reject the values of the fields of type all, they are populated from the fields with copy_to only
+func validateAll() validation.RuleFunc {
+	return func(v interface{}) error {
+		if v == nil {
+			return nil
+		}
+
+		return errs.Errorf("value of the field of type %q is copied from other fields and cannot be set", TypeAll)
+	}
+}
+
+// validateMap validate the object against the children fields
+func validateMap(children map[string]Field, allowUnknown bool) validation.RuleFunc {
+	return func(v interface{}) error {
+		if v == nil {
+			return nil
+		}
+		vv, ok := v.(map[string]interface{})
+		if !ok {
+			return errs.Errorf("required object, got %#v", v)
+		}
+
+		return buildRules(children, vv, allowUnknown).Validate(vv)
+	}
+}
+
+// validateSlice validate every element of the slice as the object with the children fields.
+// Errors are reported by the element positions
+func validateSlice(children map[string]Field, allowUnknown bool) validation.RuleFunc {
+	return func(v interface{}) error {
+		if v == nil {
+			return nil
+		}
+		vv, ok := v.([]interface{})
+		if !ok {
+			return errs.Errorf("required array, got %#v", v)
+		}
+
+		result := validation.Errors{}
+		for i, item := range vv {
+			if err := validateMap(children, allowUnknown)(item); err != nil {
+				result[strconv.Itoa(i)] = err
+			}
+		}
+		if len(result) > 0 {
+			return result
+		}
+
+		return nil
+	}
+}
+
 func validateBool() validation.RuleFunc {
 	return func(v interface{}) error {
 		if v == nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/invopop/validation"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,6 +27,23 @@ func Test_ValidateDoc(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("must fail if value of all field is set", func(t *testing.T) {
+		s := NewSchema(map[string]Field{"all": {Type: TypeAll, Analyzer: "a"}, "name": {Type: TypeKeyword, CopyTo: []string{"all"}}}, nil)
+		require.NoError(t, ValidateDoc(s, map[string]interface{}{"name": "a"}))
+		require.Error(t, ValidateDoc(s, map[string]interface{}{"name": "a", "all": "a"}))
+	})
+
+	t.Run("must check values against sub-field types", func(t *testing.T) {
+		s := NewSchema(map[string]Field{"value": {Type: TypeKeyword, Fields: map[string]Field{"ip": {Type: TypeIP}}}}, nil)
+		require.NoError(t, ValidateDoc(s, map[string]interface{}{"value": "10.0.0.1"}))
+		require.NoError(t, ValidateDoc(s, map[string]interface{}{"value": nil}))
+
+		err := ValidateDoc(s, map[string]interface{}{"value": "localhost"})
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "value")
+	})
+
 	t.Run("must fail if invalid value type provided", func(t *testing.T) {
 		t.Run("bool", func(t *testing.T) {
 			s := NewSchema(map[string]Field{"value": {Type: TypeBool, Required: false}}, nil)
@@ -77,6 +95,50 @@ func Test_ValidateDoc(t *testing.T) {
 			err := ValidateDoc(s, map[string]interface{}{"value": true})
 			require.Error(t, err)
 		})
+		t.Run("date", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeDate, Required: false}}, nil)
+			err := ValidateDoc(s, map[string]interface{}{"value": true})
+			require.Error(t, err)
+			err = ValidateDoc(s, map[string]interface{}{"value": "2023-01-02"})
+			require.Error(t, err)
+		})
+		t.Run("date with format", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeDate, Format: "2006-01-02", Required: false}}, nil)
+			err := ValidateDoc(s, map[string]interface{}{"value": json.Number("1000")})
+			require.Error(t, err)
+		})
+		t.Run("geo_point", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeGeoPoint, Required: false}}, nil)
+			for _, v := range []interface{}{
+				true,
+				"91,0",
+				"ezs4a",
+				map[string]interface{}{"lat": json.Number("1")},
+			} {
+				err := ValidateDoc(s, map[string]interface{}{"value": v})
+				require.Error(t, err, "%#v", v)
+			}
+		})
+		t.Run("ip", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeIP, Required: false}}, nil)
+			for _, v := range []interface{}{json.Number("167772161"), "10.0.0.256", "10.0.0.0/8", "::g"} {
+				err := ValidateDoc(s, map[string]interface{}{"value": v})
+				require.Error(t, err, "%#v", v)
+			}
+		})
+
+		t.Run("dense_vector", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeDenseVector, Dims: 2}}, nil)
+			for _, v := range []interface{}{
+				"1,2",
+				[]interface{}{json.Number("1")},
+				[]interface{}{json.Number("1"), "2"},
+				[]interface{}{json.Number("0"), json.Number("0")},
+			} {
+				err := ValidateDoc(s, map[string]interface{}{"value": v})
+				require.Error(t, err, "%#v", v)
+			}
+		})
 	})
 
 	t.Run("must not fail if nil value provided", func(t *testing.T) {
@@ -183,6 +245,47 @@ func Test_ValidateDoc(t *testing.T) {
 			err := ValidateDoc(s, map[string]interface{}{"value": json.Number("3000000000")})
 			require.NoError(t, err)
 		})
+		t.Run("date", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeDate, Required: true}}, nil)
+			err := ValidateDoc(s, map[string]interface{}{"value": "2023-01-02T03:04:05Z"})
+			require.NoError(t, err)
+			err = ValidateDoc(s, map[string]interface{}{"value": json.Number("1672628645000")})
+			require.NoError(t, err)
+		})
+		t.Run("date with format", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeDate, Format: "2006-01-02", Required: true}}, nil)
+			err := ValidateDoc(s, map[string]interface{}{"value": "2023-01-02"})
+			require.NoError(t, err)
+		})
+		t.Run("geo_point", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeGeoPoint, Required: true}}, nil)
+			for _, v := range []interface{}{
+				map[string]interface{}{"lat": json.Number("52.52"), "lon": json.Number("13.405")},
+				"52.52,13.405",
+				"u33dc0",
+			} {
+				err := ValidateDoc(s, map[string]interface{}{"value": v})
+				require.NoError(t, err, "%#v", v)
+			}
+		})
+		t.Run("ip", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeIP, Required: true}}, nil)
+			for _, v := range []interface{}{"10.0.0.1", "2001:db8::1", "::ffff:10.0.0.1"} {
+				err := ValidateDoc(s, map[string]interface{}{"value": v})
+				require.NoError(t, err, "%#v", v)
+			}
+		})
+
+		t.Run("dense_vector", func(t *testing.T) {
+			s := NewSchema(map[string]Field{"value": {Type: TypeDenseVector, Dims: 2, VectorSimilarity: "l2"}}, nil)
+			for _, v := range [][]interface{}{
+				{json.Number("0.5"), json.Number("-1")},
+				{json.Number("0"), json.Number("0")},
+			} {
+				err := ValidateDoc(s, map[string]interface{}{"value": v})
+				require.NoError(t, err, "%#v", v)
+			}
+		})
 	})
 
 	t.Run("must fail if numeric value is out of range", func(t *testing.T) {
@@ -257,4 +360,88 @@ func Test_ValidateDoc(t *testing.T) {
 			require.Error(t, err)
 		})
 	})
+
+	t.Run("must validate nested fields", func(t *testing.T) {
+		s := NewSchema(map[string]Field{
+			"tags": {Type: TypeSlice, Children: map[string]Field{
+				"name":  {Type: TypeKeyword, Required: true},
+				"score": {Type: TypeInteger},
+			}},
+			"attrs": {Type: TypeMap, Children: map[string]Field{
+				"color": {Type: TypeKeyword},
+				"size": {Type: TypeMap, Children: map[string]Field{
+					"width": {Type: TypeInteger},
+				}},
+			}},
+		}, nil)
+
+		t.Run("valid", func(t *testing.T) {
+			err := ValidateDoc(s, map[string]interface{}{
+				"tags": []interface{}{
+					map[string]interface{}{"name": "a", "score": json.Number("1")},
+					map[string]interface{}{"name": "b"},
+				},
+				"attrs": map[string]interface{}{
+					"color": "red",
+					"size":  map[string]interface{}{"width": json.Number("10")},
+				},
+			})
+			require.NoError(t, err)
+		})
+
+		t.Run("nil", func(t *testing.T) {
+			err := ValidateDoc(s, map[string]interface{}{"tags": nil, "attrs": nil})
+			require.NoError(t, err)
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			err := ValidateDoc(s, map[string]interface{}{
+				"tags": []interface{}{
+					map[string]interface{}{"name": "a"},
+					map[string]interface{}{"name": "b", "score": "high"},
+					map[string]interface{}{"score": json.Number("1")},
+					"c",
+				},
+				"attrs": map[string]interface{}{
+					"color": true,
+					"size":  map[string]interface{}{"width": json.Number("10"), "height": json.Number("10")},
+				},
+			})
+
+			var ve validation.Errors
+			require.ErrorAs(t, err, &ve)
+			require.Len(t, ve, 5)
+			require.Contains(t, ve, "tags.1.score")
+			require.Contains(t, ve, "tags.2.name")
+			require.Contains(t, ve, "tags.3")
+			require.Contains(t, ve, "attrs.color")
+			require.Contains(t, ve, "attrs.size.height")
+		})
+
+		t.Run("not a collection", func(t *testing.T) {
+			err := ValidateDoc(s, map[string]interface{}{"tags": "a", "attrs": []interface{}{}})
+
+			var ve validation.Errors
+			require.ErrorAs(t, err, &ve)
+			require.Contains(t, ve, "tags")
+			require.Contains(t, ve, "attrs")
+		})
+	})
+
+	t.Run("must allow unknown fields if schema is not strict", func(t *testing.T) {
+		fields := map[string]Field{
+			"value": {Type: TypeBool},
+			"attrs": {Type: TypeMap, Children: map[string]Field{"color": {Type: TypeKeyword}}},
+		}
+		doc := map[string]interface{}{
+			"value":   true,
+			"unknown": "a",
+			"attrs":   map[string]interface{}{"color": "red", "size": json.Number("1")},
+		}
+
+		require.Error(t, ValidateDoc(NewSchema(fields, nil), doc))
+		require.Error(t, ValidateDoc(Schema{Fields: fields, Dynamic: DynamicStrict}, doc))
+		require.NoError(t, ValidateDoc(Schema{Fields: fields, Dynamic: DynamicIgnore}, doc))
+		require.NoError(t, ValidateDoc(Schema{Fields: fields, Dynamic: DynamicTrue}, doc))
+	})
 }
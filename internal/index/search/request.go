@@ -0,0 +1,162 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/f1monkey/search/internal/index/aggregation"
+	"github.com/f1monkey/search/internal/index/query"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+)
+
+const (
+	DefaultSize = 10
+
+	// MaxResultWindow limits from + size, use search_after for deep pagination
+	MaxResultWindow = 10000
+)
+
+var defaultSort = []Sort{{Field: FieldScore, Order: OrderDesc, Missing: MissingLast}}
+
+type Request struct {
+	Query       query.Query
+	From        int
+	Size        int
+	Sort        []Sort
+	SearchAfter *Cursor
+	Aggs        map[string]aggregation.Aggregation
+}
+
+// Cursor points to the last hit of the previous page.
+// The document ordinal is used as a tie-breaker to make the order deterministic
+type Cursor struct {
+	Values []SortValue
+	Ord    uint32
+}
+
+type rawRequest struct {
+	Query       json.RawMessage   `json:"query"`
+	From        *int              `json:"from"`
+	Size        *int              `json:"size"`
+	Sort        []json.RawMessage `json:"sort"`
+	SearchAfter []interface{}     `json:"search_after"`
+	Aggs        json.RawMessage   `json:"aggs"`
+	// Aggregations is an alias of Aggs
+	Aggregations json.RawMessage `json:"aggregations"`
+}
+
+// ParseRequest parses the search request and validates it against the schema
+func ParseRequest(s schema.Schema, data []byte) (Request, error) {
+	raw := rawRequest{}
+	if len(data) != 0 {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return Request{}, err
+		}
+	}
+
+	result := Request{
+		Query: query.MatchAll{},
+		Size:  DefaultSize,
+		Sort:  defaultSort,
+	}
+	errors := validation.Errors{}
+
+	if len(raw.Query) != 0 {
+		q, err := query.Parse(s, "query", raw.Query)
+		if err := mergeErrors(errors, err); err != nil {
+			return Request{}, err
+		}
+		result.Query = q
+	}
+
+	if raw.From != nil {
+		result.From = *raw.From
+	}
+	if raw.Size != nil {
+		result.Size = *raw.Size
+	}
+	if result.From < 0 {
+		errors["from"] = validation.NewError("", "must be >= 0")
+	}
+	if result.Size < 0 {
+		errors["size"] = validation.NewError("", "must be >= 0")
+	}
+	if result.From+result.Size > MaxResultWindow {
+		errors["size"] = validation.NewError("", fmt.Sprintf("from + size must be <= %d, use search_after for deep pagination", MaxResultWindow))
+	}
+
+	if len(raw.Sort) != 0 {
+		result.Sort = parseSort(s, raw.Sort, errors)
+	}
+
+	if raw.SearchAfter != nil && len(errors) == 0 {
+		if result.From != 0 {
+			errors["from"] = validation.NewError("", "must be 0 when search_after is used")
+		}
+		result.SearchAfter = parseSearchAfter(result.Sort, raw.SearchAfter, errors)
+	}
+
+	if len(raw.Aggregations) != 0 {
+		raw.Aggs = raw.Aggregations
+	}
+	if len(raw.Aggs) != 0 {
+		aggs, err := aggregation.Parse(s, "aggs", raw.Aggs)
+		if err := mergeErrors(errors, err); err != nil {
+			return Request{}, err
+		}
+		result.Aggs = aggs
+	}
+
+	if len(errors) != 0 {
+		return Request{}, errors
+	}
+
+	return result, nil
+}
+
+// mergeErrors add validation errors to the target, other errors are returned as is
+func mergeErrors(target validation.Errors, err error) error {
+	ve, ok := err.(validation.Errors)
+	if !ok {
+		return err
+	}
+
+	for k, v := range ve {
+		target[k] = v
+	}
+
+	return nil
+}
+
+// parseSearchAfter parses sort values of the last hit followed by its tie-breaker
+func parseSearchAfter(sort []Sort, values []interface{}, errors validation.Errors) *Cursor {
+	if len(values) != len(sort)+1 {
+		errors["search_after"] = validation.NewError("", fmt.Sprintf("must contain %d values: one per sort field and a tie-breaker", len(sort)+1))
+		return nil
+	}
+
+	result := &Cursor{Values: make([]SortValue, 0, len(sort))}
+	for i, s := range sort {
+		v, err := s.parseValue(values[i])
+		if err != nil {
+			errors["search_after."+strconv.Itoa(i)] = validation.NewError("", err.Error())
+			continue
+		}
+		result.Values = append(result.Values, v)
+	}
+
+	n, _ := values[len(sort)].(json.Number)
+	ord, err := strconv.ParseUint(n.String(), 10, 32)
+	if err != nil {
+		errors["search_after."+strconv.Itoa(len(sort))] = validation.NewError("", "tie-breaker must be a document ordinal")
+		return nil
+	}
+	result.Ord = uint32(ord)
+
+	return result
+}
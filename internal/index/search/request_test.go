@@ -0,0 +1,194 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/geo"
+	"github.com/f1monkey/search/internal/index/query"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchema() schema.Schema {
+	return schema.NewSchema(
+		map[string]schema.Field{
+			"keyword": schema.NewField(schema.TypeKeyword, false, ""),
+			"long":    schema.NewField(schema.TypeLong, false, ""),
+			"text":    schema.NewField(schema.TypeText, false, "analyzer"),
+			"geo":     schema.NewField(schema.TypeGeoPoint, false, ""),
+		},
+		nil,
+	)
+}
+
+func Test_ParseRequest(t *testing.T) {
+	t.Run("must match all documents if request is empty", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), nil)
+		require.NoError(t, err)
+		require.Equal(t, Request{Query: query.MatchAll{}, Size: DefaultSize, Sort: defaultSort}, req)
+	})
+
+	t.Run("must fail if request is not a valid JSON", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{`))
+		require.Error(t, err)
+	})
+
+	t.Run("must return validation errors of the query", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"query": {"term": {"unknown": "a"}}}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "query.term.unknown")
+	})
+
+	t.Run("must parse query", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), []byte(`{"query": {"term": {"keyword": "a"}}}`))
+		require.NoError(t, err)
+		require.Equal(t, query.Term{Field: "keyword", Value: "a"}, req.Query)
+	})
+}
+
+func Test_ParseRequest_Pagination(t *testing.T) {
+	t.Run("must parse from and size", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), []byte(`{"from": 20, "size": 5}`))
+		require.NoError(t, err)
+		require.Equal(t, 20, req.From)
+		require.Equal(t, 5, req.Size)
+	})
+
+	t.Run("must fail if from or size is invalid", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"from": -1, "size": -1}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "from")
+		require.Contains(t, ve, "size")
+	})
+
+	t.Run("must fail if result window is too large", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"from": 9999, "size": 2}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "size")
+	})
+}
+
+func Test_ParseRequest_Sort(t *testing.T) {
+	t.Run("must parse all sort forms", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), []byte(`{"sort": [
+			"keyword",
+			{"long": "desc"},
+			{"long": {"order": "asc", "missing": "_first"}},
+			"_score"
+		]}`))
+		require.NoError(t, err)
+		require.Equal(t, []Sort{
+			{Field: "keyword", Type: schema.TypeKeyword, Order: OrderAsc, Missing: MissingLast},
+			{Field: "long", Type: schema.TypeLong, Order: OrderDesc, Missing: MissingLast},
+			{Field: "long", Type: schema.TypeLong, Order: OrderAsc, Missing: MissingFirst},
+			{Field: FieldScore, Order: OrderDesc, Missing: MissingLast},
+		}, req.Sort)
+	})
+
+	t.Run("must parse distance sort", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), []byte(`{"sort": [
+			{"_geo_distance": {"geo": "52.52,13.405"}},
+			{"_geo_distance": {"geo": {"lat": 1, "lon": 2}, "order": "desc", "unit": "km", "missing": "_first"}}
+		]}`))
+		require.NoError(t, err)
+		require.Equal(t, []Sort{
+			{Field: "geo", Type: schema.TypeGeoPoint, Order: OrderAsc, Missing: MissingLast, Origin: geo.Point{Lat: 52.52, Lon: 13.405}, Unit: 1},
+			{Field: "geo", Type: schema.TypeGeoPoint, Order: OrderDesc, Missing: MissingFirst, Origin: geo.Point{Lat: 1, Lon: 2}, Unit: 1000},
+		}, req.Sort)
+	})
+
+	t.Run("must report invalid distance sort clauses", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"sort": [
+			"_geo_distance",
+			{"_geo_distance": {"order": "asc"}},
+			{"_geo_distance": {"long": "1,2"}},
+			{"_geo_distance": {"geo": "1,2", "unit": "parsec"}},
+			{"_geo_distance": {"geo": "100,2"}},
+			"geo"
+		]}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Len(t, ve, 6)
+	})
+
+	t.Run("must report invalid sort clauses", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"sort": [
+			"unknown",
+			"text",
+			{"long": "up"},
+			{"long": {"missing": "middle"}},
+			{"long": "asc", "keyword": "asc"}
+		]}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Len(t, ve, 5)
+		require.Contains(t, ve, "sort.0")
+		require.Contains(t, ve, "sort.4")
+	})
+}
+
+func Test_ParseRequest_SearchAfter(t *testing.T) {
+	t.Run("must parse sort values and tie-breaker", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), []byte(`{"sort": ["keyword", {"long": "desc"}, "_score"], "search_after": ["a", null, 1.5, 10]}`))
+		require.NoError(t, err)
+		require.Equal(t, &Cursor{
+			Values: []SortValue{{Str: "a"}, {Missing: true}, {Score: 1.5}},
+			Ord:    10,
+		}, req.SearchAfter)
+	})
+
+	t.Run("must keep distances in the sort unit", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), []byte(`{"sort": [{"_geo_distance": {"geo": "1,2", "unit": "km"}}], "search_after": [1.5, 10]}`))
+		require.NoError(t, err)
+		require.Equal(t, []SortValue{{Key: docvalues.EncodeFloat(1.5)}}, req.SearchAfter.Values)
+	})
+
+	t.Run("must fail if number of values does not match sort", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"sort": ["keyword"], "search_after": ["a"]}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "search_after")
+	})
+
+	t.Run("must report invalid values", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"sort": ["keyword", "long"], "search_after": [1, "a", -1]}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "search_after.0")
+		require.Contains(t, ve, "search_after.1")
+		require.Contains(t, ve, "search_after.2")
+	})
+
+	t.Run("must fail if from is provided", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"from": 10, "search_after": [1, 1]}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "from")
+	})
+}
+
+func Test_ParseRequest_Aggs(t *testing.T) {
+	t.Run("must parse aggregations", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), []byte(`{"aggs": {"a": {"terms": {"field": "keyword"}}}}`))
+		require.NoError(t, err)
+		require.Contains(t, req.Aggs, "a")
+	})
+
+	t.Run("must accept full key name", func(t *testing.T) {
+		req, err := ParseRequest(testSchema(), []byte(`{"aggregations": {"a": {"max": {"field": "long"}}}}`))
+		require.NoError(t, err)
+		require.Contains(t, req.Aggs, "a")
+	})
+
+	t.Run("must return validation errors of the aggregations", func(t *testing.T) {
+		_, err := ParseRequest(testSchema(), []byte(`{"aggs": {"a": {"terms": {"field": "text"}}}}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "aggs.a.terms.field")
+	})
+}
@@ -0,0 +1,20 @@
+package search
+
+import "github.com/f1monkey/search/internal/index/schema"
+
+type Result struct {
+	Hits         Hits                   `json:"hits"`
+	Aggregations map[string]interface{} `json:"aggregations,omitempty"`
+}
+
+type Hits struct {
+	Total uint64 `json:"total"`
+	Hits  []Hit  `json:"hits"`
+}
+
+type Hit struct {
+	ID     string        `json:"_id"`
+	Score  float64       `json:"_score"`
+	Source schema.Source `json:"_source"`
+	Sort   []interface{} `json:"sort"`
+}
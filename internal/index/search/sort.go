@@ -0,0 +1,323 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/geo"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/invopop/validation"
+)
+
+const (
+	// FieldScore sorts hits by relevance
+	FieldScore = "_score"
+	// FieldGeoDistance sorts hits by the distance between the geo point field value and the origin
+	FieldGeoDistance = "_geo_distance"
+)
+
+type Order string
+
+const (
+	OrderAsc  Order = "asc"
+	OrderDesc Order = "desc"
+)
+
+func (o Order) Valid() bool {
+	return o == OrderAsc || o == OrderDesc
+}
+
+type Missing string
+
+const (
+	MissingFirst Missing = "_first"
+	MissingLast  Missing = "_last"
+)
+
+func (m Missing) Valid() bool {
+	return m == MissingFirst || m == MissingLast
+}
+
+type Sort struct {
+	Field   string
+	Type    schema.Type
+	Order   Order
+	Missing Missing
+	// Origin and Unit of the distance sort by the geo point field, the unit is in meters
+	Origin geo.Point
+	Unit   float64
+}
+
+// SortValue is a comparable representation of the document value used for sorting
+type SortValue struct {
+	Missing bool
+	Key     uint64  // numeric and bool fields, encoded distance in the sort unit for geo point fields
+	Str     string  // keyword fields
+	Score   float64 // relevance score
+}
+
+// Compare sort values of two documents. Missing values are placed according to Missing regardless of Order
+func (s Sort) Compare(a SortValue, b SortValue) int {
+	if a.Missing || b.Missing {
+		switch {
+		case a.Missing && b.Missing:
+			return 0
+		case a.Missing == (s.Missing == MissingFirst):
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	var c int
+	switch {
+	case s.Field == FieldScore:
+		c = compare(a.Score, b.Score)
+	case s.Type == schema.TypeKeyword:
+		c = strings.Compare(a.Str, b.Str)
+	default:
+		c = compare(a.Key, b.Key)
+	}
+
+	if s.Order == OrderDesc {
+		return -c
+	}
+
+	return c
+}
+
+// Value convert sort value to its JSON representation
+func (s Sort) Value(v SortValue) interface{} {
+	switch {
+	case v.Missing:
+		return nil
+	case s.Field == FieldScore:
+		return v.Score
+	case s.Type == schema.TypeKeyword:
+		return v.Str
+	case s.Type == schema.TypeBool:
+		return v.Key == 1
+	case s.Type == schema.TypeGeoPoint:
+		return docvalues.DecodeFloat(v.Key)
+	}
+
+	return docvalues.Decode(s.Type, v.Key)
+}
+
+// parseValue convert JSON representation of the value back to sort value
+func (s Sort) parseValue(v interface{}) (SortValue, error) {
+	if v == nil {
+		return SortValue{Missing: true}, nil
+	}
+
+	switch {
+	case s.Field == FieldScore:
+		n, ok := v.(json.Number)
+		if !ok {
+			return SortValue{}, fmt.Errorf("required number, got %#v", v)
+		}
+		f, err := strconv.ParseFloat(n.String(), 64)
+		if err != nil {
+			return SortValue{}, err
+		}
+		return SortValue{Score: f}, nil
+	case s.Type == schema.TypeKeyword:
+		str, ok := v.(string)
+		if !ok {
+			return SortValue{}, fmt.Errorf("required string, got %#v", v)
+		}
+		return SortValue{Str: str}, nil
+	case s.Type == schema.TypeBool:
+		key, err := docvalues.EncodeBool(v)
+		return SortValue{Key: key}, err
+	case s.Type == schema.TypeGeoPoint:
+		n, ok := v.(json.Number)
+		if !ok {
+			return SortValue{}, fmt.Errorf("required number, got %#v", v)
+		}
+		f, err := strconv.ParseFloat(n.String(), 64)
+		if err != nil {
+			return SortValue{}, err
+		}
+		return SortValue{Key: docvalues.EncodeFloat(f)}, nil
+	}
+
+	key, err := docvalues.Encode(s.Type, v)
+
+	return SortValue{Key: key}, err
+}
+
+func compare[T uint64 | uint32 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+var sortableTypes = map[schema.Type]struct{}{
+	schema.TypeKeyword:      {},
+	schema.TypeBool:         {},
+	schema.TypeByte:         {},
+	schema.TypeShort:        {},
+	schema.TypeInteger:      {},
+	schema.TypeLong:         {},
+	schema.TypeUnsignedLong: {},
+	schema.TypeFloat:        {},
+	schema.TypeDouble:       {},
+	schema.TypeDate:         {},
+}
+
+type sortBody struct {
+	Order   Order   `json:"order"`
+	Missing Missing `json:"missing"`
+}
+
+// parseSort parses list of sort clauses: "field", {"field": "desc"} or {"field": {"order": "desc", "missing": "_first"}}
+func parseSort(s schema.Schema, data []json.RawMessage, errors validation.Errors) []Sort {
+	result := make([]Sort, 0, len(data))
+	for i, item := range data {
+		path := "sort." + strconv.Itoa(i)
+
+		sort, err := parseSortItem(s, item)
+		if err != nil {
+			errors[path] = err
+			continue
+		}
+		result = append(result, sort)
+	}
+
+	return result
+}
+
+func parseSortItem(s schema.Schema, data json.RawMessage) (Sort, error) {
+	var (
+		field string
+		body  json.RawMessage
+	)
+
+	if err := json.Unmarshal(data, &field); err != nil {
+		obj := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &obj); err != nil || len(obj) != 1 {
+			return Sort{}, validation.NewError("", "must be a field name or an object with a single key")
+		}
+		for k, v := range obj {
+			field, body = k, v
+		}
+	}
+
+	if field == FieldGeoDistance {
+		return parseGeoDistanceSort(s, body)
+	}
+
+	result := Sort{Field: field, Order: OrderAsc, Missing: MissingLast}
+	if field == FieldScore {
+		result.Order = OrderDesc
+	} else {
+		f, ok := s.Field(field)
+		if !ok {
+			return Sort{}, validation.NewError("", fmt.Sprintf("unknown field %q", field))
+		}
+		if _, ok := sortableTypes[f.Type]; !ok {
+			return Sort{}, validation.NewError("", fmt.Sprintf("field %q of type %q is not sortable", field, f.Type))
+		}
+		result.Type = f.Type
+	}
+
+	if len(body) != 0 {
+		sb := sortBody{}
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+			if err := json.Unmarshal(body, &sb); err != nil {
+				return Sort{}, validation.NewError("", fmt.Sprintf("invalid sort body: %s", err))
+			}
+		} else if err := json.Unmarshal(body, &sb.Order); err != nil {
+			return Sort{}, validation.NewError("", "order must be a string")
+		}
+
+		if sb.Order != "" {
+			if !sb.Order.Valid() {
+				return Sort{}, validation.NewError("", fmt.Sprintf("unknown order %q", sb.Order))
+			}
+			result.Order = sb.Order
+		}
+		if sb.Missing != "" {
+			if !sb.Missing.Valid() {
+				return Sort{}, validation.NewError("", fmt.Sprintf("unknown missing value %q", sb.Missing))
+			}
+			result.Missing = sb.Missing
+		}
+	}
+
+	return result, nil
+}
+
+// parseGeoDistanceSort parses {"<field>": <origin>, "order": "asc", "unit": "km", "missing": "_last"}
+func parseGeoDistanceSort(s schema.Schema, body json.RawMessage) (Sort, error) {
+	obj := map[string]json.RawMessage{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return Sort{}, validation.NewError("", "distance sort must be an object")
+	}
+
+	result := Sort{Order: OrderAsc, Missing: MissingLast, Type: schema.TypeGeoPoint, Unit: 1}
+	for key, raw := range obj {
+		switch key {
+		case "order":
+			if err := json.Unmarshal(raw, &result.Order); err != nil || !result.Order.Valid() {
+				return Sort{}, validation.NewError("", fmt.Sprintf("unknown order %s", raw))
+			}
+		case "missing":
+			if err := json.Unmarshal(raw, &result.Missing); err != nil || !result.Missing.Valid() {
+				return Sort{}, validation.NewError("", fmt.Sprintf("unknown missing value %s", raw))
+			}
+		case "unit":
+			var unit string
+			if err := json.Unmarshal(raw, &unit); err != nil {
+				return Sort{}, validation.NewError("", "unit must be a string")
+			}
+			u, err := geo.ParseUnit(unit)
+			if err != nil {
+				return Sort{}, validation.NewError("", err.Error())
+			}
+			result.Unit = u
+		default:
+			if result.Field != "" {
+				return Sort{}, validation.NewError("", "distance sort must contain a single field")
+			}
+
+			f, ok := s.Field(key)
+			if !ok {
+				return Sort{}, validation.NewError("", fmt.Sprintf("unknown field %q", key))
+			}
+			if f.Type != schema.TypeGeoPoint {
+				return Sort{}, validation.NewError("", fmt.Sprintf("field %q of type %q is not a geo point", key, f.Type))
+			}
+
+			var value interface{}
+			d := json.NewDecoder(bytes.NewReader(raw))
+			d.UseNumber()
+			if err := d.Decode(&value); err != nil {
+				return Sort{}, validation.NewError("", fmt.Sprintf("invalid origin: %s", err))
+			}
+			origin, err := geo.Parse(value)
+			if err != nil {
+				return Sort{}, validation.NewError("", fmt.Sprintf("invalid origin: %s", err))
+			}
+			result.Field, result.Origin = key, origin
+		}
+	}
+
+	if result.Field == "" {
+		return Sort{}, validation.NewError("", "distance sort must contain a geo point field")
+	}
+
+	return result, nil
+}
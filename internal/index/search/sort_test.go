@@ -0,0 +1,45 @@
+package search
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Sort_Compare(t *testing.T) {
+	t.Run("must compare by order", func(t *testing.T) {
+		asc := Sort{Field: "long", Type: schema.TypeLong, Order: OrderAsc, Missing: MissingLast}
+		require.Equal(t, -1, asc.Compare(SortValue{Key: 1}, SortValue{Key: 2}))
+		require.Equal(t, 0, asc.Compare(SortValue{Key: 1}, SortValue{Key: 1}))
+
+		desc := Sort{Field: "keyword", Type: schema.TypeKeyword, Order: OrderDesc, Missing: MissingLast}
+		require.Equal(t, -1, desc.Compare(SortValue{Str: "b"}, SortValue{Str: "a"}))
+
+		score := Sort{Field: FieldScore, Order: OrderDesc, Missing: MissingLast}
+		require.Equal(t, -1, score.Compare(SortValue{Score: 2}, SortValue{Score: 1}))
+	})
+
+	t.Run("must place missing values regardless of order", func(t *testing.T) {
+		for _, o := range []Order{OrderAsc, OrderDesc} {
+			last := Sort{Field: "long", Type: schema.TypeLong, Order: o, Missing: MissingLast}
+			require.Equal(t, 1, last.Compare(SortValue{Missing: true}, SortValue{Key: 1}))
+			require.Equal(t, -1, last.Compare(SortValue{Key: 1}, SortValue{Missing: true}))
+
+			first := Sort{Field: "long", Type: schema.TypeLong, Order: o, Missing: MissingFirst}
+			require.Equal(t, -1, first.Compare(SortValue{Missing: true}, SortValue{Key: 1}))
+			require.Equal(t, 0, first.Compare(SortValue{Missing: true}, SortValue{Missing: true}))
+		}
+	})
+}
+
+func Test_Sort_Value(t *testing.T) {
+	require.Nil(t, Sort{Field: "long", Type: schema.TypeLong}.Value(SortValue{Missing: true}))
+	require.Equal(t, json.Number("-1"), Sort{Field: "long", Type: schema.TypeLong}.Value(SortValue{Key: docvalues.EncodeInt(-1)}))
+	require.Equal(t, true, Sort{Field: "bool", Type: schema.TypeBool}.Value(SortValue{Key: 1}))
+	require.Equal(t, "a", Sort{Field: "keyword", Type: schema.TypeKeyword}.Value(SortValue{Str: "a"}))
+	require.Equal(t, 1.5, Sort{Field: FieldScore}.Value(SortValue{Score: 1.5}))
+	require.Equal(t, 2.5, Sort{Field: "geo", Type: schema.TypeGeoPoint, Unit: 1000}.Value(SortValue{Key: docvalues.EncodeFloat(2.5)}))
+}
@@ -0,0 +1,282 @@
+package vector
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	// DefaultM number of the neighbors of the graph nodes, the nodes of the bottom layer have twice as many
+	DefaultM = 16
+	// DefaultEfConstruction number of the candidates considered when the node is inserted
+	DefaultEfConstruction = 100
+)
+
+// hnsw hierarchical navigable small world graph for the approximate nearest neighbor search.
+// Removed nodes stay in the graph to keep it connected and are skipped in the results,
+// the graph is rebuilt once they make up half of it
+type hnsw struct {
+	similarity     Similarity
+	m              int
+	efConstruction int
+	levelMult      float64
+	rnd            *rand.Rand
+
+	nodes    map[uint32]*node
+	entry    uint32
+	maxLevel int
+	removed  int
+}
+
+type node struct {
+	vector    []float32
+	neighbors [][]uint32 // by level
+	removed   bool
+}
+
+func newHNSW(similarity Similarity, m int, efConstruction int) *hnsw {
+	return &hnsw{
+		similarity:     similarity,
+		m:              m,
+		efConstruction: efConstruction,
+		levelMult:      1 / math.Log(float64(m)),
+		// the same data always gives the same graph
+		rnd:      rand.New(rand.NewSource(1)),
+		nodes:    make(map[uint32]*node),
+		maxLevel: -1,
+	}
+}
+
+// len number of the nodes which are not removed
+func (g *hnsw) len() int {
+	return len(g.nodes) - g.removed
+}
+
+func (g *hnsw) add(ord uint32, vector []float32) {
+	level := int(math.Floor(-math.Log(1-g.rnd.Float64()) * g.levelMult))
+	n := &node{vector: vector, neighbors: make([][]uint32, level+1)}
+	g.nodes[ord] = n
+
+	if g.maxLevel < 0 {
+		g.entry, g.maxLevel = ord, level
+		return
+	}
+
+	entry := g.entry
+	for l := g.maxLevel; l > level; l-- {
+		entry = g.greedy(vector, entry, l)
+	}
+
+	entries := []uint32{entry}
+	for l := min(level, g.maxLevel); l >= 0; l-- {
+		found := g.searchLayer(vector, entries, g.efConstruction, l, nil)
+		neighbors := closest(found, g.maxNeighbors(l))
+		n.neighbors[l] = neighbors
+		for _, nb := range neighbors {
+			g.connect(nb, ord, l)
+		}
+
+		entries = entries[:0]
+		for _, c := range found {
+			entries = append(entries, c.Ord)
+		}
+	}
+
+	if level > g.maxLevel {
+		g.entry, g.maxLevel = ord, level
+	}
+}
+
+func (g *hnsw) remove(ord uint32) {
+	n, ok := g.nodes[ord]
+	if !ok || n.removed {
+		return
+	}
+	n.removed = true
+	g.removed++
+
+	if g.removed*2 >= len(g.nodes) {
+		g.rebuild()
+	}
+}
+
+// rebuild insert the nodes which are not removed to the new graph
+func (g *hnsw) rebuild() {
+	ords := make([]uint32, 0, g.len())
+	for ord, n := range g.nodes {
+		if !n.removed {
+			ords = append(ords, ord)
+		}
+	}
+	sort.Slice(ords, func(i, j int) bool { return ords[i] < ords[j] })
+
+	rebuilt := newHNSW(g.similarity, g.m, g.efConstruction)
+	for _, ord := range ords {
+		rebuilt.add(ord, g.nodes[ord].vector)
+	}
+	*g = *rebuilt
+}
+
+// search find up to k nodes most similar to the vector among the ef candidates. Only accepted nodes are returned
+func (g *hnsw) search(vector []float32, k int, ef int, accept func(ord uint32) bool) []Neighbor {
+	if g.maxLevel < 0 {
+		return nil
+	}
+
+	entry := g.entry
+	for l := g.maxLevel; l > 0; l-- {
+		entry = g.greedy(vector, entry, l)
+	}
+
+	found := g.searchLayer(vector, []uint32{entry}, max(ef, k), 0, func(ord uint32) bool {
+		return !g.nodes[ord].removed && (accept == nil || accept(ord))
+	})
+	if len(found) > k {
+		found = found[:k]
+	}
+
+	return found
+}
+
+func (g *hnsw) maxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * g.m
+	}
+
+	return g.m
+}
+
+// greedy move from the entry to the most similar neighbor on the level until there are no better ones
+func (g *hnsw) greedy(vector []float32, entry uint32, level int) uint32 {
+	best := g.similarity.Score(vector, g.nodes[entry].vector)
+	for changed := true; changed; {
+		changed = false
+		for _, nb := range g.nodes[entry].neighbors[level] {
+			if score := g.similarity.Score(vector, g.nodes[nb].vector); score > best {
+				best, entry, changed = score, nb, true
+			}
+		}
+	}
+
+	return entry
+}
+
+// searchLayer find up to ef accepted nodes most similar to the vector on the level, ordered by similarity.
+// All nodes are traversed regardless of accept, so the search is not stuck in the rejected regions
+func (g *hnsw) searchLayer(vector []float32, entries []uint32, ef int, level int, accept func(ord uint32) bool) []Neighbor {
+	visited := make(map[uint32]struct{}, ef*4)
+	candidates := &neighborHeap{max: true}
+	results := &neighborHeap{}
+
+	push := func(ord uint32) {
+		visited[ord] = struct{}{}
+		c := Neighbor{Ord: ord, Score: g.similarity.Score(vector, g.nodes[ord].vector)}
+		if results.Len() == ef && c.Score <= results.items[0].Score {
+			return
+		}
+		heap.Push(candidates, c)
+		if accept == nil || accept(ord) {
+			heap.Push(results, c)
+			if results.Len() > ef {
+				heap.Pop(results)
+			}
+		}
+	}
+
+	for _, e := range entries {
+		if _, ok := visited[e]; !ok {
+			push(e)
+		}
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(Neighbor)
+		if results.Len() == ef && c.Score < results.items[0].Score {
+			break
+		}
+
+		for _, nb := range g.nodes[c.Ord].neighbors[level] {
+			if _, ok := visited[nb]; !ok {
+				push(nb)
+			}
+		}
+	}
+
+	return results.sorted()
+}
+
+// connect add the link from the node to the neighbor, keeping only the most similar neighbors if there are too many
+func (g *hnsw) connect(ord uint32, neighbor uint32, level int) {
+	n := g.nodes[ord]
+	n.neighbors[level] = append(n.neighbors[level], neighbor)
+	if len(n.neighbors[level]) <= g.maxNeighbors(level) {
+		return
+	}
+
+	candidates := make([]Neighbor, 0, len(n.neighbors[level]))
+	for _, nb := range n.neighbors[level] {
+		candidates = append(candidates, Neighbor{Ord: nb, Score: g.similarity.Score(n.vector, g.nodes[nb].vector)})
+	}
+	sortNeighbors(candidates)
+	n.neighbors[level] = closest(candidates, g.maxNeighbors(level))
+}
+
+// closest get the ordinals of the first n neighbors sorted by similarity
+func closest(neighbors []Neighbor, n int) []uint32 {
+	if len(neighbors) > n {
+		neighbors = neighbors[:n]
+	}
+
+	result := make([]uint32, 0, len(neighbors))
+	for _, nb := range neighbors {
+		result = append(result, nb.Ord)
+	}
+
+	return result
+}
+
+// neighborHeap min-heap of the neighbors by score, max-heap if max is set. Of the equal scores the lower ordinal is better
+type neighborHeap struct {
+	items []Neighbor
+	max   bool
+}
+
+func (h *neighborHeap) Len() int { return len(h.items) }
+func (h *neighborHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.max {
+		return a.Score > b.Score || (a.Score == b.Score && a.Ord < b.Ord)
+	}
+	return a.Score < b.Score || (a.Score == b.Score && a.Ord > b.Ord)
+}
+func (h *neighborHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *neighborHeap) Push(x interface{}) { h.items = append(h.items, x.(Neighbor)) }
+func (h *neighborHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// sorted get the items ordered by similarity, most similar first
+func (h *neighborHeap) sorted() []Neighbor {
+	result := append([]Neighbor(nil), h.items...)
+	sortNeighbors(result)
+
+	return result
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,122 @@
+package vector
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomVectors(n int, dims int) [][]float32 {
+	rnd := rand.New(rand.NewSource(42))
+	result := make([][]float32, n)
+	for i := range result {
+		result[i] = make([]float32, dims)
+		for j := range result[i] {
+			result[i][j] = rnd.Float32()*2 - 1
+		}
+	}
+
+	return result
+}
+
+// bruteForce ordinals of the k vectors most similar to the query
+func bruteForce(vectors [][]float32, query []float32, k int, accept func(ord uint32) bool) map[uint32]struct{} {
+	h := &neighborHeap{}
+	for i, v := range vectors {
+		if accept != nil && !accept(uint32(i)) {
+			continue
+		}
+		h.items = append(h.items, Neighbor{Ord: uint32(i), Score: SimilarityCosine.Score(query, v)})
+	}
+	found := h.sorted()
+	if len(found) > k {
+		found = found[:k]
+	}
+
+	result := make(map[uint32]struct{}, k)
+	for _, n := range found {
+		result[n.Ord] = struct{}{}
+	}
+
+	return result
+}
+
+func recall(expected map[uint32]struct{}, found []Neighbor) float64 {
+	hits := 0
+	for _, n := range found {
+		if _, ok := expected[n.Ord]; ok {
+			hits++
+		}
+	}
+
+	return float64(hits) / float64(len(expected))
+}
+
+func Test_HNSW_Search(t *testing.T) {
+	vectors := randomVectors(2000, 16)
+	g := newHNSW(SimilarityCosine, DefaultM, DefaultEfConstruction)
+	for i, v := range vectors {
+		g.add(uint32(i), v)
+	}
+	queries := randomVectors(20, 16)
+
+	t.Run("must find most of the nearest neighbors", func(t *testing.T) {
+		total := 0.0
+		for _, q := range queries {
+			found := g.search(q, 10, 100, nil)
+			require.Len(t, found, 10)
+			for i := 1; i < len(found); i++ {
+				require.GreaterOrEqual(t, found[i-1].Score, found[i].Score)
+			}
+			total += recall(bruteForce(vectors, q, 10, nil), found)
+		}
+		require.Greater(t, total/float64(len(queries)), 0.9)
+	})
+
+	t.Run("must return only accepted nodes", func(t *testing.T) {
+		even := func(ord uint32) bool { return ord%2 == 0 }
+		total := 0.0
+		for _, q := range queries {
+			found := g.search(q, 10, 100, even)
+			require.Len(t, found, 10)
+			for _, n := range found {
+				require.True(t, even(n.Ord))
+			}
+			total += recall(bruteForce(vectors, q, 10, even), found)
+		}
+		require.Greater(t, total/float64(len(queries)), 0.9)
+	})
+}
+
+func Test_HNSW_Remove(t *testing.T) {
+	vectors := randomVectors(200, 8)
+	g := newHNSW(SimilarityCosine, DefaultM, DefaultEfConstruction)
+	for i, v := range vectors {
+		g.add(uint32(i), v)
+	}
+
+	t.Run("must skip removed nodes", func(t *testing.T) {
+		found := g.search(vectors[5], 1, 50, nil)
+		require.Equal(t, uint32(5), found[0].Ord)
+
+		g.remove(5)
+		g.remove(5)
+		require.Equal(t, 199, g.len())
+		for _, n := range g.search(vectors[5], 10, 50, nil) {
+			require.NotEqual(t, uint32(5), n.Ord)
+		}
+	})
+
+	t.Run("must rebuild the graph when half of the nodes are removed", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			g.remove(uint32(i))
+		}
+		require.Equal(t, 100, g.len())
+		require.Len(t, g.nodes, 100)
+		require.Zero(t, g.removed)
+
+		found := g.search(vectors[150], 1, 50, nil)
+		require.Equal(t, uint32(150), found[0].Ord)
+	})
+}
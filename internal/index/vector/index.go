@@ -0,0 +1,130 @@
+package vector
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring"
+)
+
+// ExactThreshold searches among this number of vectors or less compare the query with every vector
+// instead of traversing the graph: it is fast enough and always finds the true nearest neighbors
+const ExactThreshold = 1000
+
+// Neighbor the vector found by the search and its similarity to the query
+type Neighbor struct {
+	Ord   uint32
+	Score float64
+}
+
+// Index vectors of the field by document ordinals
+type Index struct {
+	dims       int
+	similarity Similarity
+	vectors    map[uint32][]float32
+	graph      *hnsw
+}
+
+// New index of the vectors with the number of dimensions. Empty similarity means cosine
+func New(dims int, similarity Similarity) *Index {
+	similarity = similarity.OrDefault()
+
+	return &Index{
+		dims:       dims,
+		similarity: similarity,
+		vectors:    make(map[uint32][]float32),
+		graph:      newHNSW(similarity, DefaultM, DefaultEfConstruction),
+	}
+}
+
+// Dims number of the vector dimensions
+func (i *Index) Dims() int {
+	return i.dims
+}
+
+// Similarity function the vectors are compared with
+func (i *Index) Similarity() Similarity {
+	return i.similarity
+}
+
+// Len number of the indexed vectors
+func (i *Index) Len() int {
+	return len(i.vectors)
+}
+
+// Add the vector of the document. Vectors of the existing documents are not replaced
+func (i *Index) Add(ord uint32, vector []float32) {
+	if _, ok := i.vectors[ord]; ok {
+		return
+	}
+
+	i.vectors[ord] = vector
+	i.graph.add(ord, vector)
+}
+
+// Remove the vector of the document
+func (i *Index) Remove(ord uint32) {
+	if _, ok := i.vectors[ord]; !ok {
+		return
+	}
+
+	delete(i.vectors, ord)
+	i.graph.remove(ord)
+}
+
+// Search find up to k vectors most similar to the query, most similar first.
+// The graph is traversed with numCandidates candidates, the more of them the better the recall.
+// If the filter is not nil, only the documents of the filter are returned
+func (i *Index) Search(query []float32, k int, numCandidates int, filter *roaring.Bitmap) []Neighbor {
+	if k <= 0 || len(i.vectors) == 0 {
+		return nil
+	}
+
+	if len(i.vectors) <= ExactThreshold || (filter != nil && filter.GetCardinality() <= ExactThreshold) {
+		return i.exact(query, k, filter)
+	}
+
+	var accept func(ord uint32) bool
+	if filter != nil {
+		accept = filter.Contains
+	}
+
+	return i.graph.search(query, k, max(numCandidates, k), accept)
+}
+
+// exact compare the query with every vector of the filter or of the index if there is no filter
+func (i *Index) exact(query []float32, k int, filter *roaring.Bitmap) []Neighbor {
+	results := &neighborHeap{}
+	add := func(ord uint32, vector []float32) {
+		heap.Push(results, Neighbor{Ord: ord, Score: i.similarity.Score(query, vector)})
+		if results.Len() > k {
+			heap.Pop(results)
+		}
+	}
+
+	if filter != nil {
+		it := filter.Iterator()
+		for it.HasNext() {
+			ord := it.Next()
+			if v, ok := i.vectors[ord]; ok {
+				add(ord, v)
+			}
+		}
+	} else {
+		for ord, v := range i.vectors {
+			add(ord, v)
+		}
+	}
+
+	return results.sorted()
+}
+
+// sortNeighbors order by similarity, ties are broken by ordinals to keep the results stable
+func sortNeighbors(neighbors []Neighbor) {
+	sort.Slice(neighbors, func(a, b int) bool {
+		if neighbors[a].Score != neighbors[b].Score {
+			return neighbors[a].Score > neighbors[b].Score
+		}
+		return neighbors[a].Ord < neighbors[b].Ord
+	})
+}
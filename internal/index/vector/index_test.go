@@ -0,0 +1,52 @@
+package vector
+
+import (
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Index_Search(t *testing.T) {
+	t.Run("must find exact nearest neighbors in small index", func(t *testing.T) {
+		idx := New(2, SimilarityL2)
+		idx.Add(1, []float32{0, 0})
+		idx.Add(2, []float32{1, 1})
+		idx.Add(3, []float32{5, 5})
+		idx.Add(3, []float32{0, 0})
+		require.Equal(t, 3, idx.Len())
+
+		found := idx.Search([]float32{1, 0}, 2, 10, nil)
+		require.Equal(t, []Neighbor{{Ord: 1, Score: 0.5}, {Ord: 2, Score: 0.5}}, found)
+
+		found = idx.Search([]float32{4, 4}, 10, 10, roaring.BitmapOf(1, 3, 100))
+		require.Len(t, found, 2)
+		require.Equal(t, uint32(3), found[0].Ord)
+		require.Equal(t, uint32(1), found[1].Ord)
+
+		idx.Remove(3)
+		idx.Remove(3)
+		require.Equal(t, 2, idx.Len())
+		require.Empty(t, idx.Search([]float32{4, 4}, 10, 10, roaring.BitmapOf(3, 4)))
+		require.Empty(t, New(2, "").Search([]float32{1, 1}, 10, 10, nil))
+	})
+
+	t.Run("must search the graph of large index and compare selective filters exactly", func(t *testing.T) {
+		vectors := randomVectors(3000, 8)
+		idx := New(8, "")
+		require.Equal(t, SimilarityCosine, idx.Similarity())
+		for i, v := range vectors {
+			idx.Add(uint32(i), v)
+		}
+
+		found := idx.Search(vectors[10], 5, 50, nil)
+		require.Len(t, found, 5)
+		require.Equal(t, uint32(10), found[0].Ord)
+
+		filter := roaring.New()
+		filter.AddRange(0, 500)
+		accept := func(ord uint32) bool { return ord < 500 }
+		found = idx.Search(vectors[2000], 10, 10, filter)
+		require.Equal(t, 1.0, recall(bruteForce(vectors, vectors[2000], 10, accept), found))
+	})
+}
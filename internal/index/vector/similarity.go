@@ -0,0 +1,114 @@
+package vector
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/f1monkey/search/pkg/errs"
+	"github.com/invopop/validation"
+)
+
+// Similarity function of the vectors. Scores of all functions are positive and higher for more similar vectors
+type Similarity string
+
+const (
+	// SimilarityCosine cosine of the angle between the vectors scored as (1 + cos) / 2. Used by default
+	SimilarityCosine Similarity = "cosine"
+	// SimilarityDotProduct dot product of the vectors scored as 1 + dot for positive products and 1 / (1 - dot) for negative ones
+	SimilarityDotProduct Similarity = "dot_product"
+	// SimilarityL2 euclidean distance between the vectors scored as 1 / (1 + distance^2)
+	SimilarityL2 Similarity = "l2"
+)
+
+// MaxDims limits the number of vector dimensions
+const MaxDims = 4096
+
+func (s Similarity) Validate() error {
+	return validation.Validate(string(s), validation.In(string(SimilarityCosine), string(SimilarityDotProduct), string(SimilarityL2)))
+}
+
+// OrDefault get the similarity or cosine if it is empty
+func (s Similarity) OrDefault() Similarity {
+	if s == "" {
+		return SimilarityCosine
+	}
+
+	return s
+}
+
+// Score get the similarity of the vectors of the same length
+func (s Similarity) Score(a []float32, b []float32) float64 {
+	switch s.OrDefault() {
+	case SimilarityDotProduct:
+		d := dot(a, b)
+		if d < 0 {
+			return 1 / (1 - d)
+		}
+		return 1 + d
+	case SimilarityL2:
+		var sum float64
+		for i := range a {
+			diff := float64(a[i]) - float64(b[i])
+			sum += diff * diff
+		}
+		return 1 / (1 + sum)
+	}
+
+	na, nb := norm(a), norm(b)
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return (1 + dot(a, b)/(na*nb)) / 2
+}
+
+func dot(a []float32, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+
+	return sum
+}
+
+func norm(v []float32) float64 {
+	return math.Sqrt(dot(v, v))
+}
+
+// Parse get the vector with the number of dimensions from the array of numbers.
+// Zero vectors have no direction, so they are not allowed for the cosine similarity
+func Parse(v interface{}, dims int, similarity Similarity) ([]float32, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, errs.Errorf("required array of numbers, got %#v", v)
+	}
+	if len(items) != dims {
+		return nil, errs.Errorf("vector must have %d dimensions, got %d", dims, len(items))
+	}
+
+	result := make([]float32, len(items))
+	for i, item := range items {
+		var (
+			f   float64
+			err error
+		)
+		switch n := item.(type) {
+		case json.Number:
+			f, err = n.Float64()
+		case float64:
+			f = n
+		default:
+			err = errs.Errorf("not a number")
+		}
+		if err != nil || math.IsNaN(f) || math.Abs(f) > math.MaxFloat32 {
+			return nil, errs.Errorf("element %d must be a finite float32 number, got %#v", i, item)
+		}
+		result[i] = float32(f)
+	}
+
+	if similarity.OrDefault() == SimilarityCosine && norm(result) == 0 {
+		return nil, errs.Errorf("zero vector is not allowed for %q similarity", SimilarityCosine)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package vector
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Similarity_Score(t *testing.T) {
+	t.Run("must score cosine by the angle", func(t *testing.T) {
+		require.InDelta(t, 1, SimilarityCosine.Score([]float32{1, 0}, []float32{2, 0}), 1e-9)
+		require.InDelta(t, 0.5, SimilarityCosine.Score([]float32{1, 0}, []float32{0, 3}), 1e-9)
+		require.InDelta(t, 0, SimilarityCosine.Score([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+		require.Equal(t, SimilarityCosine.Score([]float32{1, 1}, []float32{1, 0}), Similarity("").Score([]float32{1, 1}, []float32{1, 0}))
+	})
+
+	t.Run("must keep dot product scores positive and ordered", func(t *testing.T) {
+		require.InDelta(t, 3, SimilarityDotProduct.Score([]float32{1, 1}, []float32{1, 1}), 1e-9)
+		require.InDelta(t, 1, SimilarityDotProduct.Score([]float32{1, 0}, []float32{0, 1}), 1e-9)
+		require.InDelta(t, 0.5, SimilarityDotProduct.Score([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+	})
+
+	t.Run("must score l2 by the distance", func(t *testing.T) {
+		require.InDelta(t, 1, SimilarityL2.Score([]float32{1, 2}, []float32{1, 2}), 1e-9)
+		require.InDelta(t, 1.0/26, SimilarityL2.Score([]float32{0, 0}, []float32{3, 4}), 1e-9)
+	})
+}
+
+func Test_Similarity_Validate(t *testing.T) {
+	for _, s := range []Similarity{SimilarityCosine, SimilarityDotProduct, SimilarityL2, ""} {
+		require.NoError(t, s.Validate(), s)
+	}
+	require.Error(t, Similarity("manhattan").Validate())
+}
+
+func Test_Parse(t *testing.T) {
+	t.Run("must parse the vector of numbers", func(t *testing.T) {
+		v, err := Parse([]interface{}{json.Number("1"), json.Number("-0.5"), 2.0}, 3, SimilarityCosine)
+		require.NoError(t, err)
+		require.Equal(t, []float32{1, -0.5, 2}, v)
+	})
+
+	t.Run("must allow zero vector only for non-cosine similarity", func(t *testing.T) {
+		zero := []interface{}{json.Number("0"), json.Number("0")}
+		_, err := Parse(zero, 2, SimilarityL2)
+		require.NoError(t, err)
+		_, err = Parse(zero, 2, "")
+		require.Error(t, err)
+	})
+
+	t.Run("must fail for invalid vectors", func(t *testing.T) {
+		for _, v := range []interface{}{
+			"1,2",
+			[]interface{}{json.Number("1")},
+			[]interface{}{json.Number("1"), json.Number("2"), json.Number("3")},
+			[]interface{}{json.Number("1"), "2"},
+			[]interface{}{json.Number("1"), json.Number("1e40")},
+			nil,
+		} {
+			_, err := Parse(v, 2, SimilarityDotProduct)
+			require.Error(t, err, "%v", v)
+		}
+	})
+}
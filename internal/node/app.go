@@ -8,8 +8,10 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"time"
 
 	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/shard"
 	"github.com/f1monkey/search/internal/storage"
 	"github.com/f1monkey/search/pkg/errs"
 	"github.com/go-chi/chi/v5"
@@ -18,8 +20,12 @@ import (
 )
 
 type Node struct {
-	logger *zap.Logger
-	server *http.Server
+	logger          *zap.Logger
+	server          *http.Server
+	indexes         shard.IndexStorage
+	registry        *shard.Registry
+	rewriter        *rewriter
+	rewriteInterval time.Duration
 }
 
 func New(ctx context.Context, logger *zap.Logger) (*Node, error) {
@@ -32,16 +38,53 @@ func New(ctx context.Context, logger *zap.Logger) (*Node, error) {
 		return nil, errs.Errorf("storage path create err: %w", err)
 	}
 
-	indexStorage, err := storage.NewAOFFromPath[string, index.Index](path.Join(storagePath, "indexes.dat"))
+	storageConfig, err := newStorageConfig(logger)
 	if err != nil {
+		return nil, errs.Errorf("storage config err: %w", err)
+	}
+
+	rewriter := newRewriter(logger, storage.RewritePolicy{
+		Ratio:   viper.GetFloat64("storage.rewrite.ratio"),
+		MinSize: int64(viper.GetSizeInBytes("storage.rewrite.min_size")),
+	})
+
+	start := time.Now()
+	indexStoragePath := path.Join(storagePath, "indexes")
+	indexStorage, err := storage.Open[string, index.Index](ctx, indexStoragePath, storageConfig)
+	if err != nil {
+		return nil, errs.Errorf("index storage open err: %w", err)
+	}
+	if s, ok := indexStorage.(rewritable); ok {
+		rewriter.add(indexStoragePath, s)
+	}
+
+	registry := shard.NewRegistry(
+		indexStorage,
+		documentStorageFactory(ctx, storagePath, storageConfig, rewriter),
+		documentStorageRemover(storagePath, rewriter),
+	)
+	if err := registry.Open(); err != nil {
+		registry.Close()
+		indexStorage.Close()
 		return nil, err
 	}
+	logger.Info(
+		"indexes restored",
+		zap.String("engine", string(storageConfig.Engine)),
+		zap.Int("indexes", len(registry.All())),
+		zap.Duration("took", time.Since(start)),
+	)
 
 	mux := chi.NewMux()
-	mux.Route("/indexes", indexesHandler(logger, indexStorage))
+	mux.Route("/indexes", indexesHandler(logger, registry, registry, registry))
+	mux.Post("/_rewrite", rewriteHandler(rewriter))
 
 	return &Node{
-		logger: logger,
+		logger:          logger,
+		indexes:         indexStorage,
+		registry:        registry,
+		rewriter:        rewriter,
+		rewriteInterval: viper.GetDuration("storage.rewrite.interval"),
 		server: &http.Server{
 			Addr:    viper.GetString("node.server.address"),
 			Handler: mux,
@@ -64,6 +107,8 @@ func (n *Node) Start(ctx context.Context) error {
 		}
 	}(ctx)
 
+	n.rewriter.start(ctx, n.rewriteInterval)
+
 	return nil
 }
 
@@ -79,10 +124,82 @@ func (n *Node) Stop(ctx context.Context) error {
 	}
 	n.logger.Info("http server stopped")
 
+	n.rewriter.stop()
+
+	n.logger.Info("storage closing...")
+	if err := n.registry.Close(); err != nil {
+		n.logger.Error("document storage close err", zap.Error(err))
+	}
+	if err := n.indexes.Close(); err != nil {
+		n.logger.Error("index storage close err", zap.Error(err))
+	}
+	n.logger.Info("storage closed")
+
 	n.logger.Info("node stopped")
 	return nil
 }
 
+func newStorageConfig(logger *zap.Logger) (storage.Config, error) {
+	engine, err := storage.ParseEngineKind(viper.GetString("storage.engine"))
+	if err != nil {
+		return storage.Config{}, err
+	}
+	fsync, err := storage.ParseFsyncPolicy(viper.GetString("storage.fsync"))
+	if err != nil {
+		return storage.Config{}, err
+	}
+	recovery, err := storage.ParseRecoveryMode(viper.GetString("storage.recovery"))
+	if err != nil {
+		return storage.Config{}, err
+	}
+
+	return storage.Config{
+		Engine:           engine,
+		SnapshotInterval: viper.GetDuration("storage.snapshot.interval"),
+		AOF: []storage.AOFOption{
+			storage.WithFsync(fsync),
+			storage.WithRecovery(recovery),
+			storage.WithLogger(logger),
+		},
+		Logger: logger,
+	}, nil
+}
+
+func documentStoragePath(storagePath string, indexName string) string {
+	return path.Join(storagePath, "documents", indexName)
+}
+
+func documentStorageFactory(
+	ctx context.Context,
+	storagePath string,
+	cfg storage.Config,
+	rewriter *rewriter,
+) shard.DocumentStorageFactory {
+	return func(indexName string) (shard.DocumentStorage, error) {
+		if err := os.MkdirAll(path.Join(storagePath, "documents"), 0755); err != nil {
+			return nil, errs.Errorf("document storage path create err: %w", err)
+		}
+
+		docs, err := storage.Open[string, index.Document](ctx, documentStoragePath(storagePath, indexName), cfg)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := docs.(rewritable); ok {
+			rewriter.add(documentStoragePath(storagePath, indexName), s)
+		}
+
+		return docs, nil
+	}
+}
+
+func documentStorageRemover(storagePath string, rewriter *rewriter) shard.DocumentStorageRemover {
+	return func(indexName string) error {
+		rewriter.remove(documentStoragePath(storagePath, indexName))
+
+		return storage.Remove(documentStoragePath(storagePath, indexName))
+	}
+}
+
 func panicHandle(ctx context.Context, l *zap.Logger) {
 	if r := recover(); r != nil {
 		err, ok := r.(error)
@@ -0,0 +1,118 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/f1monkey/search/internal/usecase"
+	"github.com/f1monkey/search/pkg/errs"
+	"github.com/go-chi/chi/v5"
+	"github.com/invopop/validation"
+)
+
+type BulkResponse struct {
+	Took   int64                                     `json:"took"`
+	Errors bool                                      `json:"errors"`
+	Items  []map[usecase.BulkAction]BulkResponseItem `json:"items"`
+}
+
+type BulkResponseItem struct {
+	ID     string         `json:"_id,omitempty"`
+	Result string         `json:"result,omitempty"`
+	Status int            `json:"status"`
+	Error  *errorResponse `json:"error,omitempty"`
+}
+
+func bulkHandler(bulk *usecase.Bulk) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		indexName := chi.URLParam(r, "index")
+		defer r.Body.Close()
+
+		items, err := bulk.Process(indexName, r.Body)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+				return
+			}
+
+			handleErr(w, err)
+			return
+		}
+
+		response := BulkResponse{Items: make([]map[usecase.BulkAction]BulkResponseItem, 0, len(items))}
+		for _, item := range items {
+			responseItem := bulkResponseItem(item)
+			if responseItem.Error != nil {
+				response.Errors = true
+			}
+			response.Items = append(response.Items, map[usecase.BulkAction]BulkResponseItem{item.Action: responseItem})
+		}
+		response.Took = time.Since(start).Milliseconds()
+
+		data, err := json.Marshal(response)
+		if err != nil {
+			handleErr(w, errs.Errorf("bulk response marshal err: %w", err))
+			return
+		}
+
+		setContentType(w)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+func bulkResponseItem(item usecase.BulkItem) BulkResponseItem {
+	result := BulkResponseItem{ID: item.ID}
+	if item.Err != nil {
+		var ve validation.Errors
+		var se *json.SyntaxError
+		var te *json.UnmarshalTypeError
+
+		switch {
+		case errors.Is(item.Err, storage.ErrNotFound):
+			result.Status = http.StatusNotFound
+			result.Error = &errorResponse{Message: http.StatusText(http.StatusNotFound)}
+		case errors.Is(item.Err, storage.ErrConflict):
+			result.Status = http.StatusConflict
+			result.Error = &errorResponse{Message: "Version conflict"}
+		case errors.Is(item.Err, storage.ErrAlreadyExists):
+			result.Status = http.StatusBadRequest
+			result.Error = &errorResponse{Message: "Document already exists"}
+		case errors.Is(item.Err, usecase.ErrBulkMalformed):
+			result.Status = http.StatusBadRequest
+			result.Error = &errorResponse{Message: item.Err.Error()}
+		case errors.As(item.Err, &se):
+			result.Status = http.StatusBadRequest
+			result.Error = &errorResponse{Message: "Failed to parse document as JSON"}
+		case errors.As(item.Err, &te):
+			result.Status = http.StatusBadRequest
+			result.Error = &errorResponse{Message: "Invalid document structure"}
+		case errors.As(item.Err, &ve):
+			result.Status = http.StatusUnprocessableEntity
+			result.Error = &errorResponse{Message: "Validation error", Errors: validationErrList(ve)}
+		default:
+			result.Status = http.StatusInternalServerError
+			result.Error = &errorResponse{Message: http.StatusText(http.StatusInternalServerError)}
+		}
+
+		return result
+	}
+
+	switch {
+	case item.Action == usecase.BulkActionDelete:
+		result.Status = http.StatusOK
+		result.Result = "deleted"
+	case item.Created:
+		result.Status = http.StatusCreated
+		result.Result = "created"
+	default:
+		result.Status = http.StatusOK
+		result.Result = "updated"
+	}
+
+	return result
+}
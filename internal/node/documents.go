@@ -0,0 +1,208 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/f1monkey/search/internal/usecase"
+	"github.com/f1monkey/search/pkg/errs"
+	"github.com/go-chi/chi/v5"
+	"github.com/invopop/validation"
+	"go.uber.org/zap"
+)
+
+type documentStorage interface {
+	PutDocument(indexName string, doc index.Document) error
+	UpsertDocument(indexName string, doc index.Document, ifSeqNo uint64) (bool, error)
+	UpdateDocument(indexName string, id string, fields schema.Source, ifSeqNo uint64) error
+	GetDocument(indexName string, id string) (index.Document, storage.Meta, error)
+	DeleteDocument(indexName string, id string, ifSeqNo uint64) error
+	SyncDocuments(indexName string) error
+}
+
+func documentsHandler(logger *zap.Logger, storage documentStorage) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/{id}", documentGetHandler(usecase.NewDocumentGet(storage.GetDocument)))
+		r.Delete("/{id}", documentDeleteHandler(usecase.NewDocumentDelete(logger, storage.DeleteDocument, storage.SyncDocuments)))
+		r.Put("/{id}", documentUpsertHandler(usecase.NewDocumentUpsert(logger, storage.UpsertDocument, storage.SyncDocuments)))
+		r.Post("/{id}", documentPutHandler(usecase.NewDocumentPut(logger, storage.PutDocument, storage.SyncDocuments)))
+	}
+}
+
+func documentPutHandler(documentPutter *usecase.DocumentPut) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		indexName := chi.URLParam(r, "index")
+		id := chi.URLParam(r, "id")
+
+		body, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+
+		if err != nil {
+			handleErr(w, errs.Errorf("body read err: %w", err))
+			return
+		}
+
+		source := schema.Source{}
+		if err := json.Unmarshal(body, &source); err != nil {
+			handleErr(w, errs.Errorf("body unmarshal err: %w", err))
+			return
+		}
+
+		if err := documentPutter.Put(indexName, index.NewDocument(id, source)); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+				return
+			}
+
+			if errors.Is(err, storage.ErrAlreadyExists) {
+				writeSimpleError(w, http.StatusBadRequest, "Document already exists")
+				return
+			}
+
+			var ve validation.Errors
+			if errors.As(err, &ve) {
+				handleErr(w, newRequestValidationErr(ve))
+				return
+			}
+
+			handleErr(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// documentUpsertHandler stores the document replacing the existing one.
+// The change can be conditioned by the If-Match header or if_seq_no query parameter
+func documentUpsertHandler(documentUpserter *usecase.DocumentUpsert) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		indexName := chi.URLParam(r, "index")
+		id := chi.URLParam(r, "id")
+
+		ifSeqNo, err := parseIfSeqNo(r)
+		if err != nil {
+			writeSimpleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+
+		if err != nil {
+			handleErr(w, errs.Errorf("body read err: %w", err))
+			return
+		}
+
+		source := schema.Source{}
+		if err := json.Unmarshal(body, &source); err != nil {
+			handleErr(w, errs.Errorf("body unmarshal err: %w", err))
+			return
+		}
+
+		created, err := documentUpserter.Upsert(indexName, index.NewDocument(id, source), ifSeqNo)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+				return
+			}
+
+			if errors.Is(err, storage.ErrConflict) {
+				writeSimpleError(w, http.StatusConflict, "Version conflict")
+				return
+			}
+
+			var ve validation.Errors
+			if errors.As(err, &ve) {
+				handleErr(w, newRequestValidationErr(ve))
+				return
+			}
+
+			handleErr(w, err)
+			return
+		}
+
+		if created {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func documentDeleteHandler(documentDeleter *usecase.DocumentDelete) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		indexName := chi.URLParam(r, "index")
+		id := chi.URLParam(r, "id")
+
+		ifSeqNo, err := parseIfSeqNo(r)
+		if err != nil {
+			writeSimpleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := documentDeleter.Delete(indexName, id, ifSeqNo); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+				return
+			}
+
+			if errors.Is(err, storage.ErrConflict) {
+				writeSimpleError(w, http.StatusConflict, "Version conflict")
+				return
+			}
+
+			handleErr(w, err)
+			return
+		}
+
+		setContentType(w)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+type DocumentGetResponse struct {
+	ID      string        `json:"_id"`
+	Version uint64        `json:"_version"`
+	SeqNo   uint64        `json:"_seq_no"`
+	Source  schema.Source `json:"_source"`
+}
+
+func documentGetHandler(documentGetter *usecase.DocumentGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		indexName := chi.URLParam(r, "index")
+		id := chi.URLParam(r, "id")
+
+		result, meta, err := documentGetter.Get(indexName, id)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+				return
+			}
+
+			handleErr(w, err)
+			return
+		}
+
+		data, err := json.Marshal(DocumentGetResponse{
+			ID:      result.ID,
+			Version: meta.Version,
+			SeqNo:   meta.SeqNo,
+			Source:  result.Source,
+		})
+		if err != nil {
+			handleErr(w, errs.Errorf("document marshal err: %w", err))
+			return
+		}
+
+		setContentType(w)
+		setETag(w, meta)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
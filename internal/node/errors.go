@@ -40,12 +40,16 @@ func writeError(w http.ResponseWriter, statusCode int, msg string, errors []erro
 }
 
 func writeValidationErr(w http.ResponseWriter, errorList validation.Errors) {
+	writeError(w, http.StatusUnprocessableEntity, "Validation error", validationErrList(errorList))
+}
+
+func validationErrList(errorList validation.Errors) []errorResponseError {
 	responseErrors := make([]errorResponseError, 0, len(errorList))
 	for field, errors := range errorList {
 		responseErrors = append(responseErrors, errorResponseError{Field: field, Error: errors.Error()})
 	}
 
-	writeError(w, http.StatusUnprocessableEntity, "Validation error", responseErrors)
+	return responseErrors
 }
 
 func handleErr(w http.ResponseWriter, err error) {
@@ -60,6 +64,12 @@ func handleErr(w http.ResponseWriter, err error) {
 		return
 	}
 
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		writeSimpleError(w, http.StatusBadRequest, "Invalid request body structure")
+		return
+	}
+
 	log.Println(err) // @todo need error logging? if yes - use app logger here
 
 	writeSimpleError(
@@ -17,17 +17,32 @@ import (
 
 type indexStorage interface {
 	Create(key string, value index.Index) error
+	Update(key string, value index.Index, ifSeqNo uint64) error
 	Get(key string) (index.Index, error)
-	Delete(key string) error
+	GetWithMeta(key string) (index.Index, storage.Meta, error)
+	Delete(key string, ifSeqNo uint64) error
 	All() []index.Index
+	Sync() error
 }
 
-func indexesHandler(logger *zap.Logger, storage indexStorage) func(chi.Router) {
+func indexesHandler(logger *zap.Logger, storage indexStorage, documents documentStorage, searcher indexSearcher) func(chi.Router) {
 	return func(r chi.Router) {
 		r.Get("/", indexListHandler(usecase.NewIndexList(storage.All)))
-		r.Get("/{index}", indexGetHandler(usecase.NewIndexGet(storage.Get)))
-		r.Delete("/{index}", indexDeleteHandler(usecase.NewIndexDelete(logger, storage.Delete)))
-		r.Put("/{index}", indexCreateHandler(usecase.NewIndexCreate(logger, storage.Create)))
+		r.Get("/{index}", indexGetHandler(usecase.NewIndexGet(storage.GetWithMeta)))
+		r.Delete("/{index}", indexDeleteHandler(usecase.NewIndexDelete(logger, storage.Delete, storage.Sync)))
+		r.Put("/{index}", indexCreateHandler(usecase.NewIndexCreate(logger, storage.Create, storage.Sync)))
+		r.Patch("/{index}", indexUpdateHandler(usecase.NewIndexUpdate(logger, storage.GetWithMeta, storage.Update, storage.Sync)))
+		r.Route("/{index}/documents", documentsHandler(logger, documents))
+		r.Post("/{index}/_search", searchHandler(usecase.NewSearch(searcher.Search)))
+		r.Post("/{index}/_bulk", bulkHandler(usecase.NewBulk(
+			logger,
+			storage.Get,
+			documents.PutDocument,
+			documents.UpsertDocument,
+			documents.UpdateDocument,
+			documents.DeleteDocument,
+			documents.SyncDocuments,
+		)))
 	}
 }
 
@@ -70,16 +85,87 @@ func indexCreateHandler(indexCreator *usecase.IndexCreate) http.HandlerFunc {
 	}
 }
 
+// indexUpdateHandler accepts the index definition with the fields and analyzers to add or replace.
+// The change can be conditioned by the If-Match header or if_seq_no query parameter
+func indexUpdateHandler(indexUpdater *usecase.IndexUpdate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "index")
+
+		ifSeqNo, err := parseIfSeqNo(r)
+		if err != nil {
+			writeSimpleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+
+		if err != nil {
+			handleErr(w, errs.Errorf("body read err: %w", err))
+			return
+		}
+
+		patch := index.Index{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			handleErr(w, errs.Errorf("body unmarshal err: %w", err))
+			return
+		}
+
+		result, err := indexUpdater.Update(name, patch.Schema, ifSeqNo)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+				return
+			}
+
+			if errors.Is(err, storage.ErrConflict) {
+				writeSimpleError(w, http.StatusConflict, "Version conflict")
+				return
+			}
+
+			var ve validation.Errors
+			if errors.As(err, &ve) {
+				handleErr(w, newRequestValidationErr(ve))
+				return
+			}
+
+			handleErr(w, err)
+			return
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			handleErr(w, errs.Errorf("index marshal err: %w", err))
+			return
+		}
+
+		setContentType(w)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
 func indexDeleteHandler(indexDeleter *usecase.IndexDelete) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		name := chi.URLParam(r, "index")
 
-		if err := indexDeleter.Delete(name); err != nil {
+		ifSeqNo, err := parseIfSeqNo(r)
+		if err != nil {
+			writeSimpleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := indexDeleter.Delete(name, ifSeqNo); err != nil {
 			if errors.Is(err, storage.ErrNotFound) {
 				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
 				return
 			}
 
+			if errors.Is(err, storage.ErrConflict) {
+				writeSimpleError(w, http.StatusConflict, "Version conflict")
+				return
+			}
+
 			handleErr(w, err)
 			return
 		}
@@ -89,11 +175,17 @@ func indexDeleteHandler(indexDeleter *usecase.IndexDelete) http.HandlerFunc {
 	}
 }
 
+type IndexGetResponse struct {
+	index.Index
+	Version uint64 `json:"_version"`
+	SeqNo   uint64 `json:"_seq_no"`
+}
+
 func indexGetHandler(indexGetter *usecase.IndexGetter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		name := chi.URLParam(r, "index")
 
-		result, err := indexGetter.Get(name)
+		result, meta, err := indexGetter.Get(name)
 		if err != nil {
 			if errors.Is(err, storage.ErrNotFound) {
 				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
@@ -104,13 +196,14 @@ func indexGetHandler(indexGetter *usecase.IndexGetter) http.HandlerFunc {
 			return
 		}
 
-		data, err := json.Marshal(result)
+		data, err := json.Marshal(IndexGetResponse{Index: result, Version: meta.Version, SeqNo: meta.SeqNo})
 		if err != nil {
 			handleErr(w, errs.Errorf("index marshal err: %w", err))
 			return
 		}
 
 		setContentType(w)
+		setETag(w, meta)
 		w.WriteHeader(http.StatusOK)
 		w.Write(data)
 	}
@@ -0,0 +1,183 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/f1monkey/search/pkg/errs"
+	"go.uber.org/zap"
+)
+
+type rewritable interface {
+	NeedsRewrite(policy storage.RewritePolicy) bool
+	Rewrite() error
+	Size() int64
+}
+
+// rewriter keeps track of the opened append-only files and compacts them
+type rewriter struct {
+	logger *zap.Logger
+	policy storage.RewritePolicy
+	quit   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	mtx     sync.Mutex
+	started bool
+	items   map[string]*rewriterItem
+}
+
+type rewriterItem struct {
+	mtx     sync.Mutex
+	storage rewritable
+	removed bool
+}
+
+func newRewriter(logger *zap.Logger, policy storage.RewritePolicy) *rewriter {
+	return &rewriter{
+		logger: logger,
+		policy: policy,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+		items:  make(map[string]*rewriterItem),
+	}
+}
+
+func (r *rewriter) add(name string, s rewritable) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.items[name] = &rewriterItem{storage: s}
+}
+
+// remove stop tracking the file. Waits for the running rewrite of it to complete
+func (r *rewriter) remove(name string) {
+	r.mtx.Lock()
+	item, ok := r.items[name]
+	delete(r.items, name)
+	r.mtx.Unlock()
+
+	if !ok {
+		return
+	}
+
+	item.mtx.Lock()
+	item.removed = true
+	item.mtx.Unlock()
+}
+
+// start run the rewriter in the background until the context is cancelled or the rewriter is stopped
+func (r *rewriter) start(ctx context.Context, interval time.Duration) {
+	r.mtx.Lock()
+	r.started = true
+	r.mtx.Unlock()
+
+	go func() {
+		defer panicHandle(ctx, r.logger)
+		r.run(ctx, interval)
+	}()
+}
+
+// stop the rewriter and wait for the running rewrite to complete. Does not wait if the rewriter was never started
+func (r *rewriter) stop() {
+	r.once.Do(func() { close(r.quit) })
+
+	r.mtx.Lock()
+	started := r.started
+	r.mtx.Unlock()
+
+	if started {
+		<-r.done
+	}
+}
+
+// run check the files periodically and rewrite the ones matching the policy
+func (r *rewriter) run(ctx context.Context, interval time.Duration) {
+	defer close(r.done)
+
+	if interval <= 0 {
+		select {
+		case <-ctx.Done():
+		case <-r.quit:
+		}
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.quit:
+			return
+		case <-ticker.C:
+			r.rewriteAll(false)
+		}
+	}
+}
+
+// rewriteAll rewrite the files matching the policy or all of them if force is true
+func (r *rewriter) rewriteAll(force bool) error {
+	r.mtx.Lock()
+	items := make(map[string]*rewriterItem, len(r.items))
+	for name, item := range r.items {
+		items[name] = item
+	}
+	r.mtx.Unlock()
+
+	var result error
+	for name, item := range items {
+		if err := r.rewrite(name, item, force); err != nil {
+			r.logger.Error("aof rewrite err", zap.String("file", name), zap.Error(err))
+			result = err
+		}
+	}
+
+	return result
+}
+
+func (r *rewriter) rewrite(name string, item *rewriterItem, force bool) error {
+	item.mtx.Lock()
+	defer item.mtx.Unlock()
+
+	if item.removed || (!force && !item.storage.NeedsRewrite(r.policy)) {
+		return nil
+	}
+
+	start := time.Now()
+	before := item.storage.Size()
+	if err := item.storage.Rewrite(); err != nil {
+		if errors.Is(err, storage.ErrClosed) {
+			return nil
+		}
+		return err
+	}
+
+	r.logger.Info(
+		"aof rewritten",
+		zap.String("file", name),
+		zap.Int64("size_before", before),
+		zap.Int64("size_after", item.storage.Size()),
+		zap.Duration("took", time.Since(start)),
+	)
+
+	return nil
+}
+
+func rewriteHandler(r *rewriter) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if err := r.rewriteAll(true); err != nil {
+			handleErr(w, errs.Errorf("aof rewrite err: %w", err))
+			return
+		}
+
+		setContentType(w)
+		w.WriteHeader(http.StatusOK)
+	}
+}
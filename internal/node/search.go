@@ -0,0 +1,70 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/f1monkey/search/internal/index/search"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/f1monkey/search/internal/usecase"
+	"github.com/f1monkey/search/pkg/errs"
+	"github.com/go-chi/chi/v5"
+	"github.com/invopop/validation"
+)
+
+type indexSearcher interface {
+	Search(indexName string, request []byte) (search.Result, error)
+}
+
+type SearchResponse struct {
+	Took int64 `json:"took"`
+	search.Result
+}
+
+func searchHandler(searcher *usecase.Search) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		indexName := chi.URLParam(r, "index")
+
+		body, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+
+		if err != nil {
+			handleErr(w, errs.Errorf("body read err: %w", err))
+			return
+		}
+
+		result, err := searcher.Search(indexName, body)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeSimpleError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+				return
+			}
+
+			var ve validation.Errors
+			if errors.As(err, &ve) {
+				handleErr(w, newRequestValidationErr(ve))
+				return
+			}
+
+			handleErr(w, err)
+			return
+		}
+
+		data, err := json.Marshal(SearchResponse{
+			Took:   time.Since(start).Milliseconds(),
+			Result: result,
+		})
+		if err != nil {
+			handleErr(w, errs.Errorf("search result marshal err: %w", err))
+			return
+		}
+
+		setContentType(w)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
@@ -0,0 +1,43 @@
+package node
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// parseIfSeqNo get the expected sequence number of the changed element
+// from the If-Match header (the ETag of the element) or from the if_seq_no query parameter.
+// Returns zero if no condition is passed
+func parseIfSeqNo(r *http.Request) (uint64, error) {
+	var result uint64
+
+	if header := r.Header.Get("If-Match"); header != "" {
+		seqNo, err := strconv.ParseUint(strings.Trim(header, `"`), 10, 64)
+		if err != nil || seqNo == 0 {
+			return 0, errs.Errorf("invalid If-Match header %q", header)
+		}
+		result = seqNo
+	}
+
+	if param := r.URL.Query().Get("if_seq_no"); param != "" {
+		seqNo, err := strconv.ParseUint(param, 10, 64)
+		if err != nil || seqNo == 0 {
+			return 0, errs.Errorf("invalid if_seq_no %q", param)
+		}
+		if result != 0 && result != seqNo {
+			return 0, errs.Errorf("If-Match header does not match if_seq_no")
+		}
+		result = seqNo
+	}
+
+	return result, nil
+}
+
+// setETag the sequence number of the last change is used as the entity tag
+func setETag(w http.ResponseWriter, meta storage.Meta) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(meta.SeqNo, 10)))
+}
@@ -0,0 +1,355 @@
+package shard
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/index/search"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+type IndexStorage interface {
+	storage.Engine[string, index.Index]
+}
+
+// DocumentStorageFactory opens document storage of the index
+type DocumentStorageFactory func(indexName string) (DocumentStorage, error)
+
+// DocumentStorageRemover removes all persisted documents of the index
+type DocumentStorageRemover func(indexName string) error
+
+// Registry keeps index definitions together with their shards
+type Registry struct {
+	mtx     sync.Mutex
+	indexes IndexStorage
+	factory DocumentStorageFactory
+	remover DocumentStorageRemover
+	shards  map[string]*Shard
+}
+
+func NewRegistry(indexes IndexStorage, factory DocumentStorageFactory, remover DocumentStorageRemover) *Registry {
+	return &Registry{
+		indexes: indexes,
+		factory: factory,
+		remover: remover,
+		shards:  make(map[string]*Shard),
+	}
+}
+
+// Create index and its shard
+func (r *Registry) Create(name string, idx index.Index) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if err := r.indexes.Create(name, idx); err != nil {
+		return err
+	}
+
+	docs, err := r.factory(name)
+	if err != nil {
+		if delErr := r.indexes.Delete(name); delErr != nil {
+			return errs.Errorf("document storage open err: %v, index rollback err: %w", err, delErr)
+		}
+		return errs.Errorf("document storage open err: %w", err)
+	}
+	s, err := New(idx, docs)
+	if err != nil {
+		if rollbackErr := errors.Join(docs.Close(), r.indexes.Delete(name)); rollbackErr != nil {
+			return errs.Errorf("shard create err: %w, rollback err: %w", err, rollbackErr)
+		}
+		return errs.Errorf("shard create err: %w", err)
+	}
+	r.shards[name] = s
+
+	return nil
+}
+
+// Update replace the index definition and reindex the documents of its shard.
+// Non-zero ifSeqNo requires the index to be last changed with this sequence number
+func (r *Registry) Update(name string, idx index.Index, ifSeqNo uint64) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	old, err := r.indexes.Get(name)
+	if err != nil {
+		return err
+	}
+	if err := storage.CheckSeqNo[string, index.Index](r.indexes, name, ifSeqNo); err != nil {
+		return err
+	}
+
+	return r.update(name, old, idx)
+}
+
+// update replace the index definition and reindex the documents of its opened shard.
+// The old definition is restored if the reindex fails. Must be called under the lock
+func (r *Registry) update(name string, old index.Index, idx index.Index) error {
+	if err := r.indexes.Update(name, idx); err != nil {
+		return err
+	}
+
+	s, opened := r.shards[name]
+	if !opened {
+		return nil
+	}
+	if err := s.SetIndex(idx); err != nil {
+		if rollbackErr := r.indexes.Update(name, old); rollbackErr != nil {
+			return errs.Errorf("shard reindex err: %v, index rollback err: %w", err, rollbackErr)
+		}
+		return errs.Errorf("shard reindex err: %w", err)
+	}
+
+	return nil
+}
+
+// Get index definition
+func (r *Registry) Get(name string) (index.Index, error) {
+	return r.indexes.Get(name)
+}
+
+// GetWithMeta get index definition together with its version
+func (r *Registry) GetWithMeta(name string) (index.Index, storage.Meta, error) {
+	return r.indexes.GetWithMeta(name)
+}
+
+// Delete index with all its documents
+func (r *Registry) Delete(name string, ifSeqNo uint64) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if _, err := r.indexes.Get(name); err != nil {
+		return err
+	}
+	if err := storage.CheckSeqNo[string, index.Index](r.indexes, name, ifSeqNo); err != nil {
+		return err
+	}
+	if err := r.indexes.Delete(name); err != nil {
+		return err
+	}
+	s, opened := r.shards[name]
+	delete(r.shards, name)
+
+	var closeErr error
+	if opened {
+		closeErr = s.Close()
+	}
+
+	if err := r.remover(name); err != nil {
+		return errs.Errorf("document storage remove err: %w", errors.Join(err, closeErr))
+	}
+	if closeErr != nil {
+		return errs.Errorf("document storage close err: %w", closeErr)
+	}
+
+	return nil
+}
+
+// Sync wait until the index definition changes are persisted
+func (r *Registry) Sync() error {
+	return r.indexes.Sync()
+}
+
+// Open open the shards of all the stored indexes
+func (r *Registry) Open() error {
+	var names []string
+	r.indexes.Iterate(func(name string, idx index.Index) bool {
+		names = append(names, name)
+		return true
+	})
+
+	for _, name := range names {
+		if _, err := r.Shard(name); err != nil {
+			return errs.Errorf("index %q open err: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Close release the document storages of all the opened shards
+func (r *Registry) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var result error
+	for name, s := range r.shards {
+		if err := s.Close(); err != nil {
+			result = errors.Join(result, errs.Errorf("index %q close err: %w", name, err))
+		}
+		delete(r.shards, name)
+	}
+
+	return result
+}
+
+// All get all index definitions
+func (r *Registry) All() []index.Index {
+	result := make([]index.Index, 0)
+	r.indexes.Iterate(func(name string, idx index.Index) bool {
+		result = append(result, idx)
+		return true
+	})
+
+	return result
+}
+
+// Shard get shard by index name. Shards of the indexes loaded from storage are opened on first access
+func (r *Registry) Shard(name string) (*Shard, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if s, ok := r.shards[name]; ok {
+		return s, nil
+	}
+
+	idx, err := r.indexes.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := r.factory(name)
+	if err != nil {
+		return nil, errs.Errorf("document storage open err: %w", err)
+	}
+	s, err := New(idx, docs)
+	if err != nil {
+		return nil, errs.Errorf("shard create err: %w", err)
+	}
+	r.shards[name] = s
+
+	return s, nil
+}
+
+// withExtension call fn with the extension of the dynamic index definition by the fields of the source missing in its schema.
+// The extension is nil if there are no new fields. The registry stays locked while fn is called with the extension,
+// so the definition cannot be changed until the document is stored
+func (r *Registry) withExtension(name string, s *Shard, source schema.Source, fn func(ext *extension) error) error {
+	if s.Index().Schema.Dynamic != schema.DynamicTrue {
+		return fn(nil)
+	}
+	_, changed, err := s.Index().Schema.Extend(source)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return fn(nil)
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	// the schema could be extended by another document or replaced since it was checked
+	old, err := r.indexes.Get(name)
+	if err != nil {
+		return err
+	}
+	extended, changed, err := old.Schema.Extend(source)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return fn(nil)
+	}
+
+	idx := old
+	idx.Schema = extended
+	if err := idx.Schema.Validate(); err != nil {
+		return err
+	}
+
+	return fn(&extension{
+		index: idx,
+		persist: func() error {
+			if err := r.indexes.Update(name, idx); err != nil {
+				return err
+			}
+			return r.indexes.Sync()
+		},
+		rollback: func() error {
+			return r.indexes.Update(name, old)
+		},
+	})
+}
+
+// PutDocument store the document in the index
+func (r *Registry) PutDocument(indexName string, doc index.Document) error {
+	s, err := r.Shard(indexName)
+	if err != nil {
+		return err
+	}
+
+	return r.withExtension(indexName, s, doc.Source, func(ext *extension) error {
+		return s.put(doc, ext)
+	})
+}
+
+// UpsertDocument store the document in the index replacing the existing one
+func (r *Registry) UpsertDocument(indexName string, doc index.Document, ifSeqNo uint64) (bool, error) {
+	s, err := r.Shard(indexName)
+	if err != nil {
+		return false, err
+	}
+
+	var created bool
+	err = r.withExtension(indexName, s, doc.Source, func(ext *extension) error {
+		created, err = s.upsert(doc, ifSeqNo, ext)
+		return err
+	})
+
+	return created, err
+}
+
+// UpdateDocument merge the fields into the existing document
+func (r *Registry) UpdateDocument(indexName string, id string, fields schema.Source, ifSeqNo uint64) error {
+	s, err := r.Shard(indexName)
+	if err != nil {
+		return err
+	}
+
+	return r.withExtension(indexName, s, fields, func(ext *extension) error {
+		return s.update(id, fields, ifSeqNo, ext)
+	})
+}
+
+// SyncDocuments wait until the document changes of the index are persisted
+func (r *Registry) SyncDocuments(indexName string) error {
+	s, err := r.Shard(indexName)
+	if err != nil {
+		return err
+	}
+
+	return s.Sync()
+}
+
+// GetDocument get the document from the index together with its version
+func (r *Registry) GetDocument(indexName string, id string) (index.Document, storage.Meta, error) {
+	s, err := r.Shard(indexName)
+	if err != nil {
+		return index.Document{}, storage.Meta{}, err
+	}
+
+	return s.Get(id)
+}
+
+// DeleteDocument delete the document from the index
+func (r *Registry) DeleteDocument(indexName string, id string, ifSeqNo uint64) error {
+	s, err := r.Shard(indexName)
+	if err != nil {
+		return err
+	}
+
+	return s.Delete(id, ifSeqNo)
+}
+
+// Search execute the search request against the index
+func (r *Registry) Search(indexName string, request []byte) (search.Result, error) {
+	s, err := r.Shard(indexName)
+	if err != nil {
+		return search.Result{}, err
+	}
+
+	return s.Search(request)
+}
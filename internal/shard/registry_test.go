@@ -0,0 +1,387 @@
+package shard
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(removed *[]string) *Registry {
+	return NewRegistry(
+		storage.NewFile[string, index.Index](),
+		func(indexName string) (DocumentStorage, error) {
+			return storage.NewFile[string, index.Document](), nil
+		},
+		func(indexName string) error {
+			if removed != nil {
+				*removed = append(*removed, indexName)
+			}
+			return nil
+		},
+	)
+}
+
+type closeTrackingStorage struct {
+	*storage.File[string, index.Document]
+	closed bool
+}
+
+func (s *closeTrackingStorage) Close() error {
+	s.closed = true
+	return nil
+}
+
+func newCloseTrackingRegistry(opened map[string]*closeTrackingStorage) *Registry {
+	return NewRegistry(
+		storage.NewFile[string, index.Index](),
+		func(indexName string) (DocumentStorage, error) {
+			s := &closeTrackingStorage{File: storage.NewFile[string, index.Document]()}
+			opened[indexName] = s
+			return s, nil
+		},
+		func(indexName string) error { return nil },
+	)
+}
+
+func Test_Registry_Create(t *testing.T) {
+	t.Run("must return err if index already exists", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		require.NoError(t, r.Create("name", testIndex()))
+		err := r.Create("name", testIndex())
+		require.ErrorIs(t, err, storage.ErrAlreadyExists)
+	})
+
+	t.Run("must rollback index if failed to open document storage", func(t *testing.T) {
+		r := NewRegistry(
+			storage.NewFile[string, index.Index](),
+			func(indexName string) (DocumentStorage, error) {
+				return nil, fmt.Errorf("error")
+			},
+			func(indexName string) error { return nil },
+		)
+		require.Error(t, r.Create("name", testIndex()))
+
+		_, err := r.Get("name")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must rollback index and close document storage if failed to create shard", func(t *testing.T) {
+		opened := make(map[string]*closeTrackingStorage)
+		r := newCloseTrackingRegistry(opened)
+		idx := testIndex()
+		idx.Schema.Fields["text"] = schema.NewField(schema.TypeText, false, "unknown")
+		require.Error(t, r.Create("name", idx))
+
+		_, err := r.Get("name")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		require.True(t, opened["name"].closed)
+		require.NoError(t, r.Create("name", testIndex()), "must allow to create the index again")
+	})
+
+	t.Run("must create index with shard", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		require.NoError(t, r.Create("name", testIndex()))
+
+		s, err := r.Shard("name")
+		require.NoError(t, err)
+		require.Equal(t, testIndex(), s.Index())
+	})
+}
+
+func Test_Registry_Update(t *testing.T) {
+	t.Run("must return err if index not found", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		err := r.Update("name", testIndex(), 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must update index and reindex its shard", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		require.NoError(t, r.Create("name", testIndex()))
+		require.NoError(t, r.PutDocument("name", index.NewDocument("1", schema.Source{"keyword": "a"})))
+
+		idx := testIndex()
+		idx.Schema.Fields["flag"] = schema.NewField(schema.TypeBool, false, "")
+		require.NoError(t, r.Update("name", idx, 0))
+
+		result, err := r.Get("name")
+		require.NoError(t, err)
+		require.Equal(t, idx, result)
+
+		s, err := r.Shard("name")
+		require.NoError(t, err)
+		require.Equal(t, idx, s.Index())
+		require.NoError(t, r.PutDocument("name", index.NewDocument("2", schema.Source{"flag": true})))
+	})
+
+	t.Run("must return conflict err if index was changed", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		require.NoError(t, r.Create("name", testIndex()))
+		require.NoError(t, r.Update("name", testIndex(), 1))
+
+		require.ErrorIs(t, r.Update("name", testIndex(), 1), storage.ErrConflict)
+
+		_, meta, err := r.GetWithMeta("name")
+		require.NoError(t, err)
+		require.Equal(t, storage.Meta{Version: 2, SeqNo: 2}, meta)
+	})
+
+	t.Run("must rollback index if failed to reindex shard", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		require.NoError(t, r.Create("name", testIndex()))
+
+		idx := testIndex()
+		idx.Schema.Fields["keyword"] = schema.NewField(schema.TypeText, false, "unknown")
+		require.Error(t, r.Update("name", idx, 0))
+
+		result, err := r.Get("name")
+		require.NoError(t, err)
+		require.Equal(t, testIndex(), result)
+	})
+}
+
+func Test_Registry_Delete(t *testing.T) {
+	t.Run("must return err if index not found", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		err := r.Delete("name", 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must return conflict err if index was changed", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		require.NoError(t, r.Create("name", testIndex()))
+		require.NoError(t, r.Update("name", testIndex(), 0))
+
+		require.ErrorIs(t, r.Delete("name", 1), storage.ErrConflict)
+		_, err := r.Get("name")
+		require.NoError(t, err)
+	})
+
+	t.Run("must delete index with its documents", func(t *testing.T) {
+		var removed []string
+		r := newTestRegistry(&removed)
+		require.NoError(t, r.Create("name", testIndex()))
+		require.NoError(t, r.Delete("name", 0))
+
+		_, err := r.Shard("name")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		require.Equal(t, []string{"name"}, removed)
+	})
+
+	t.Run("must close document storage of deleted index", func(t *testing.T) {
+		opened := make(map[string]*closeTrackingStorage)
+		r := newCloseTrackingRegistry(opened)
+		require.NoError(t, r.Create("name", testIndex()))
+		require.NoError(t, r.Delete("name", 0))
+		require.True(t, opened["name"].closed)
+	})
+}
+
+func Test_Registry_Open(t *testing.T) {
+	t.Run("must open shards of all stored indexes", func(t *testing.T) {
+		indexes := storage.NewFile[string, index.Index]()
+		idx2 := testIndex()
+		idx2.Name = "name2"
+		require.NoError(t, indexes.Create("name", testIndex()))
+		require.NoError(t, indexes.Create("name2", idx2))
+
+		var opened []string
+		r := NewRegistry(
+			indexes,
+			func(indexName string) (DocumentStorage, error) {
+				opened = append(opened, indexName)
+				return storage.NewFile[string, index.Document](), nil
+			},
+			func(indexName string) error { return nil },
+		)
+
+		require.NoError(t, r.Open())
+		require.ElementsMatch(t, []string{"name", "name2"}, opened)
+	})
+
+	t.Run("must return err if failed to open document storage", func(t *testing.T) {
+		indexes := storage.NewFile[string, index.Index]()
+		require.NoError(t, indexes.Create("name", testIndex()))
+
+		r := NewRegistry(
+			indexes,
+			func(indexName string) (DocumentStorage, error) {
+				return nil, fmt.Errorf("error")
+			},
+			func(indexName string) error { return nil },
+		)
+
+		require.Error(t, r.Open())
+	})
+}
+
+func Test_Registry_Close(t *testing.T) {
+	t.Run("must close document storages of all opened shards", func(t *testing.T) {
+		opened := make(map[string]*closeTrackingStorage)
+		r := newCloseTrackingRegistry(opened)
+		require.NoError(t, r.Create("name", testIndex()))
+		require.NoError(t, r.Create("name2", testIndex()))
+
+		require.NoError(t, r.Close())
+		require.True(t, opened["name"].closed)
+		require.True(t, opened["name2"].closed)
+	})
+}
+
+func Test_Registry_Shard(t *testing.T) {
+	t.Run("must return err if index not found", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		_, err := r.Shard("name")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must open shard of the index loaded from storage", func(t *testing.T) {
+		indexes := storage.NewFile[string, index.Index]()
+		require.NoError(t, indexes.Create("name", testIndex()))
+
+		r := NewRegistry(
+			indexes,
+			func(indexName string) (DocumentStorage, error) {
+				return storage.NewFile[string, index.Document](), nil
+			},
+			func(indexName string) error { return nil },
+		)
+
+		s, err := r.Shard("name")
+		require.NoError(t, err)
+		require.Equal(t, testIndex(), s.Index())
+	})
+}
+
+func Test_Registry_Documents(t *testing.T) {
+	r := newTestRegistry(nil)
+	require.NoError(t, r.Create("name", testIndex()))
+
+	t.Run("must return err if index not found", func(t *testing.T) {
+		err := r.PutDocument("unknown", index.NewDocument("1", schema.Source{}))
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		_, _, err = r.GetDocument("unknown", "1")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		err = r.DeleteDocument("unknown", "1", 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		_, err = r.UpsertDocument("unknown", index.NewDocument("1", schema.Source{}), 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		err = r.UpdateDocument("unknown", "1", schema.Source{}, 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		err = r.SyncDocuments("unknown")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must put, get and delete document", func(t *testing.T) {
+		doc := index.NewDocument("1", schema.Source{"bool": true})
+		require.NoError(t, r.PutDocument("name", doc))
+
+		result, _, err := r.GetDocument("name", "1")
+		require.NoError(t, err)
+		require.Equal(t, doc, result)
+
+		require.NoError(t, r.DeleteDocument("name", "1", 0))
+		_, _, err = r.GetDocument("name", "1")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must upsert and update document", func(t *testing.T) {
+		created, err := r.UpsertDocument("name", index.NewDocument("2", schema.Source{"bool": true}), 0)
+		require.NoError(t, err)
+		require.True(t, created)
+
+		require.NoError(t, r.UpdateDocument("name", "2", schema.Source{"keyword": "a"}, 0))
+
+		result, _, err := r.GetDocument("name", "2")
+		require.NoError(t, err)
+		require.Equal(t, index.NewDocument("2", schema.Source{"bool": true, "keyword": "a"}), result)
+	})
+}
+
+func Test_Registry_DynamicFields(t *testing.T) {
+	t.Run("must reject unknown fields of strict index", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		require.NoError(t, r.Create("name", testIndex()))
+
+		err := r.PutDocument("name", index.NewDocument("1", schema.Source{"unknown": true}))
+		require.Error(t, err)
+	})
+
+	t.Run("must store unknown fields of ignoring index without indexing them", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		idx := testIndex()
+		idx.Schema.Dynamic = schema.DynamicIgnore
+		require.NoError(t, r.Create("name", idx))
+
+		require.NoError(t, r.PutDocument("name", index.NewDocument("1", schema.Source{"unknown": "value"})))
+
+		result, err := r.Get("name")
+		require.NoError(t, err)
+		require.Equal(t, idx, result)
+
+		doc, _, err := r.GetDocument("name", "1")
+		require.NoError(t, err)
+		require.Equal(t, schema.Source{"unknown": "value"}, doc.Source)
+
+		_, err = r.Search("name", []byte(`{"query":{"term":{"unknown":"value"}}}`))
+		require.Error(t, err, "must not index unknown fields")
+	})
+
+	t.Run("must add inferred fields to dynamic index", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		idx := testIndex()
+		idx.Schema.Dynamic = schema.DynamicTrue
+		require.NoError(t, r.Create("name", idx))
+
+		require.NoError(t, r.PutDocument("name", index.NewDocument("1", schema.Source{"new": "value", "count": json.Number("1")})))
+		require.NoError(t, r.UpdateDocument("name", "1", schema.Source{"flag": true}, 0))
+
+		result, err := r.Get("name")
+		require.NoError(t, err)
+		require.Equal(t, schema.TypeKeyword, result.Schema.Fields["new"].Type)
+		require.Equal(t, schema.TypeLong, result.Schema.Fields["count"].Type)
+		require.Equal(t, schema.TypeBool, result.Schema.Fields["flag"].Type)
+
+		res, err := r.Search("name", []byte(`{"query":{"term":{"new":"value"}}}`))
+		require.NoError(t, err)
+		require.Len(t, res.Hits.Hits, 1)
+
+		err = r.PutDocument("name", index.NewDocument("2", schema.Source{"count": json.Number("1.5")}))
+		require.Error(t, err, "must validate values against the inferred types")
+	})
+
+	t.Run("must not extend schema if the document is rejected", func(t *testing.T) {
+		r := newTestRegistry(nil)
+		idx := testIndex()
+		idx.Schema.Dynamic = schema.DynamicTrue
+		idx.Schema.Fields["keyword"] = schema.Field{Type: schema.TypeKeyword, Required: true}
+		require.NoError(t, r.Create("name", idx))
+		require.NoError(t, r.PutDocument("name", index.NewDocument("1", schema.Source{"keyword": "a"})))
+		_, meta, err := r.GetDocument("name", "1")
+		require.NoError(t, err)
+
+		err = r.PutDocument("name", index.NewDocument("2", schema.Source{"new1": "value"}))
+		require.Error(t, err, "required field is missing")
+		err = r.PutDocument("name", index.NewDocument("1", schema.Source{"keyword": "a", "new2": "value"}))
+		require.ErrorIs(t, err, storage.ErrAlreadyExists)
+		_, err = r.UpsertDocument("name", index.NewDocument("1", schema.Source{"keyword": "a", "new3": "value"}), meta.SeqNo+1)
+		require.ErrorIs(t, err, storage.ErrConflict)
+		err = r.UpdateDocument("name", "1", schema.Source{"new4": "value"}, meta.SeqNo+1)
+		require.ErrorIs(t, err, storage.ErrConflict)
+		err = r.UpdateDocument("name", "2", schema.Source{"new5": "value"}, 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		result, err := r.Get("name")
+		require.NoError(t, err)
+		require.Equal(t, idx, result)
+
+		s, err := r.Shard("name")
+		require.NoError(t, err)
+		require.Equal(t, idx, s.Index())
+	})
+}
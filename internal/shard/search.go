@@ -0,0 +1,383 @@
+package shard
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/f1monkey/search/internal/index/aggregation"
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/fusion"
+	"github.com/f1monkey/search/internal/index/geo"
+	"github.com/f1monkey/search/internal/index/ip"
+	"github.com/f1monkey/search/internal/index/query"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/index/search"
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// Search parse the request against index schema and execute it
+func (s *Shard) Search(data []byte) (search.Result, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	req, err := search.ParseRequest(s.index.Schema, data)
+	if err != nil {
+		return search.Result{}, err
+	}
+
+	m := s.compile(req.Query)
+
+	docs := make([]sortedDoc, 0, m.docs.GetCardinality())
+	it := m.docs.Iterator()
+	for it.HasNext() {
+		ord := it.Next()
+		score := m.score(ord)
+		d := sortedDoc{ord: ord, score: score, values: s.sortValues(req.Sort, ord, score)}
+		if req.SearchAfter != nil && compareDocs(req.Sort, d, sortedDoc{ord: req.SearchAfter.Ord, values: req.SearchAfter.Values}) <= 0 {
+			continue
+		}
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return compareDocs(req.Sort, docs[i], docs[j]) < 0
+	})
+
+	if req.From >= len(docs) {
+		docs = nil
+	} else {
+		docs = docs[req.From:]
+	}
+	if len(docs) > req.Size {
+		docs = docs[:req.Size]
+	}
+
+	hits := make([]search.Hit, 0, len(docs))
+	for _, d := range docs {
+		doc, err := s.docs.Get(s.ids[d.ord])
+		if err != nil {
+			return search.Result{}, errs.Errorf("document %q get err: %w", s.ids[d.ord], err)
+		}
+
+		sortValues := make([]interface{}, 0, len(req.Sort)+1)
+		for i, srt := range req.Sort {
+			sortValues = append(sortValues, srt.Value(d.values[i]))
+		}
+		sortValues = append(sortValues, d.ord)
+
+		hits = append(hits, search.Hit{ID: doc.ID, Score: d.score, Source: doc.Source, Sort: sortValues})
+	}
+
+	aggs, err := aggregation.Run(req.Aggs, m.docs, s.values)
+	if err != nil {
+		return search.Result{}, err
+	}
+
+	return search.Result{
+		Hits: search.Hits{
+			Total: m.docs.GetCardinality(),
+			Hits:  hits,
+		},
+		Aggregations: aggs,
+	}, nil
+}
+
+type sortedDoc struct {
+	ord    uint32
+	score  float64
+	values []search.SortValue
+}
+
+// compareDocs compare documents by sort values, the ordinal is used as a tie-breaker
+func compareDocs(sort []search.Sort, a sortedDoc, b sortedDoc) int {
+	for i, s := range sort {
+		if c := s.Compare(a.values[i], b.values[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a.ord < b.ord:
+		return -1
+	case a.ord > b.ord:
+		return 1
+	}
+
+	return 0
+}
+
+func (s *Shard) sortValues(sort []search.Sort, ord uint32, score float64) []search.SortValue {
+	result := make([]search.SortValue, 0, len(sort))
+	for _, srt := range sort {
+		var v search.SortValue
+		switch {
+		case srt.Field == search.FieldScore:
+			v.Score = score
+		case srt.Type == schema.TypeKeyword:
+			str, ok := s.values.Keyword(srt.Field, ord)
+			v.Str, v.Missing = str, !ok
+		case srt.Type == schema.TypeGeoPoint:
+			key, ok := s.values.Numeric(srt.Field, ord)
+			if ok {
+				// compared in the sort unit, so the distances returned as the search_after cursor match exactly
+				v.Key = docvalues.EncodeFloat(geo.Distance(srt.Origin, geo.Decode(key)) / srt.Unit)
+			}
+			v.Missing = !ok
+		default:
+			key, ok := s.values.Numeric(srt.Field, ord)
+			v.Key, v.Missing = key, !ok
+		}
+		result = append(result, v)
+	}
+
+	return result
+}
+
+// matcher is a compiled query: the documents it matches and their relevance scorer
+type matcher struct {
+	docs  *roaring.Bitmap
+	score func(ord uint32) float64
+}
+
+func constantScore(score float64) func(uint32) float64 {
+	return func(uint32) float64 { return score }
+}
+
+// compile find documents matching the query and prepare their scorer
+func (s *Shard) compile(q query.Query) matcher {
+	switch q := q.(type) {
+	case query.MatchAll:
+		return matcher{docs: s.all.Clone(), score: constantScore(1)}
+	case query.Term:
+		return matcher{docs: s.term(q.Field, q.Value), score: constantScore(1)}
+	case query.Terms:
+		bms := make([]*roaring.Bitmap, 0, len(q.Values))
+		for _, v := range q.Values {
+			bms = append(bms, s.term(q.Field, v))
+		}
+		return matcher{docs: roaring.FastOr(bms...), score: constantScore(1)}
+	case query.Range:
+		return matcher{docs: s.rangeOf(q), score: constantScore(1)}
+	case query.GeoDistance:
+		docs := s.geoBox(q.Field, geo.Around(q.Origin, q.Distance), func(p geo.Point) bool {
+			return geo.Distance(q.Origin, p) <= q.Distance
+		})
+		return matcher{docs: docs, score: constantScore(1)}
+	case query.GeoBoundingBox:
+		return matcher{docs: s.geoBox(q.Field, q.Box, q.Box.Contains), score: constantScore(1)}
+	case query.Match:
+		return s.compileMatch(q)
+	case query.Knn:
+		return s.compileKnn(q)
+	case query.Hybrid:
+		return s.compileHybrid(q)
+	case query.Bool:
+		return s.compileBool(q)
+	}
+
+	return matcher{docs: roaring.New(), score: constantScore(0)}
+}
+
+func (s *Shard) compileMatch(q query.Match) matcher {
+	terms := s.inverted.Analyze(q.Field, q.Query)
+	if len(terms) == 0 {
+		return matcher{docs: roaring.New(), score: constantScore(0)}
+	}
+
+	bms := make([]*roaring.Bitmap, 0, len(terms))
+	for _, t := range terms {
+		bms = append(bms, s.inverted.Term(q.Field, t))
+	}
+
+	var docs *roaring.Bitmap
+	if q.Operator == query.OperatorAnd {
+		docs = roaring.FastAnd(bms...)
+	} else {
+		docs = roaring.FastOr(bms...)
+	}
+
+	return matcher{docs: docs, score: s.inverted.Scorer(q.Field, terms)}
+}
+
+// compileKnn find the nearest neighbors among the documents matching the filter, scored by their similarity
+func (s *Shard) compileKnn(q query.Knn) matcher {
+	idx, ok := s.vectors[q.Field]
+	if !ok {
+		return matcher{docs: roaring.New(), score: constantScore(0)}
+	}
+
+	var filter *roaring.Bitmap
+	if q.Filter != nil {
+		filter = s.compile(q.Filter).docs
+	}
+
+	docs := roaring.New()
+	scores := make(map[uint32]float64, q.K)
+	for _, n := range idx.Search(q.Vector, q.K, q.NumCandidates, filter) {
+		docs.Add(n.Ord)
+		scores[n.Ord] = n.Score
+	}
+
+	return matcher{docs: docs, score: func(ord uint32) float64 { return scores[ord] }}
+}
+
+// compileHybrid rank the documents of every retriever by their own scores and fuse the top ones
+func (s *Shard) compileHybrid(q query.Hybrid) matcher {
+	lists := make([]fusion.List, 0, len(q.Retrievers))
+	for _, r := range q.Retrievers {
+		m := s.compile(r.Query)
+		hits := make([]fusion.Hit, 0, m.docs.GetCardinality())
+		it := m.docs.Iterator()
+		for it.HasNext() {
+			ord := it.Next()
+			hits = append(hits, fusion.Hit{Ord: ord, Score: m.score(ord)})
+		}
+		lists = append(lists, fusion.List{Hits: fusion.Top(hits, q.WindowSize), Weight: r.Weight})
+	}
+
+	var scores map[uint32]float64
+	if q.Fusion == fusion.MethodLinear {
+		scores = fusion.Linear(lists, q.Normalization)
+	} else {
+		scores = fusion.RRF(lists, q.RankConstant)
+	}
+
+	docs := roaring.New()
+	for ord := range scores {
+		docs.Add(ord)
+	}
+
+	return matcher{docs: docs, score: func(ord uint32) float64 { return scores[ord] }}
+}
+
+// compileBool combine clauses: must and should clauses contribute to the score, filter and must_not do not
+func (s *Shard) compileBool(q query.Bool) matcher {
+	var docs *roaring.Bitmap
+	intersect := func(m matcher) {
+		if docs == nil {
+			docs = m.docs
+		} else {
+			docs.And(m.docs)
+		}
+	}
+
+	scoring := make([]matcher, 0, len(q.Must)+len(q.Should))
+	for _, item := range q.Must {
+		m := s.compile(item)
+		scoring = append(scoring, matcher{docs: m.docs.Clone(), score: m.score})
+		intersect(m)
+	}
+	for _, item := range q.Filter {
+		intersect(s.compile(item))
+	}
+
+	should := make([]matcher, 0, len(q.Should))
+	for _, item := range q.Should {
+		should = append(should, s.compile(item))
+	}
+	scoring = append(scoring, should...)
+
+	if docs == nil {
+		if len(should) == 0 {
+			docs = s.all.Clone()
+		} else {
+			docs = roaring.New()
+			for _, m := range should {
+				docs.Or(m.docs)
+			}
+		}
+	}
+
+	for _, item := range q.MustNot {
+		docs.AndNot(s.compile(item).docs)
+	}
+
+	if len(q.Must) == 0 && len(q.Should) == 0 && len(q.Filter) == 0 {
+		// empty bool or bool with must_not clauses only
+		return matcher{docs: docs, score: constantScore(1)}
+	}
+
+	return matcher{
+		docs: docs,
+		score: func(ord uint32) float64 {
+			var score float64
+			for _, m := range scoring {
+				if m.docs.Contains(ord) {
+					score += m.score(ord)
+				}
+			}
+			return score
+		},
+	}
+}
+
+// term get documents containing the exact field value. Ip values may be networks like 10.0.0.0/8
+func (s *Shard) term(field string, value interface{}) *roaring.Bitmap {
+	f, _ := s.index.Schema.Field(field)
+	t := f.Type
+	if t == schema.TypeIP {
+		str, _ := value.(string)
+		first, last, err := ip.ParseCIDR(str)
+		if err != nil {
+			return roaring.New()
+		}
+		return s.values.IPRange(field, first, last)
+	}
+	if docvalues.IsNumeric(t) {
+		key, err := docvalues.Encode(t, value)
+		if err != nil {
+			return roaring.New()
+		}
+		return s.values.Range(field, key, key)
+	}
+
+	return s.inverted.Term(field, termString(value)).Clone()
+}
+
+func (s *Shard) rangeOf(q query.Range) *roaring.Bitmap {
+	f, _ := s.index.Schema.Field(q.Field)
+	t := f.Type
+	if t == schema.TypeIP {
+		min, max, ok, err := ip.Bounds(q.Gt, q.Gte, q.Lt, q.Lte)
+		if err != nil || !ok {
+			return roaring.New()
+		}
+		return s.values.IPRange(q.Field, min, max)
+	}
+
+	min, max, ok, err := docvalues.Bounds(t, q.Gt, q.Gte, q.Lt, q.Lte)
+	if err != nil || !ok {
+		return roaring.New()
+	}
+
+	return s.values.Range(q.Field, min, max)
+}
+
+// geoBox get documents with the geo points inside the box matching the condition.
+// The candidates are found by the ranges of the cells covering the box
+func (s *Shard) geoBox(field string, box geo.BoundingBox, match func(p geo.Point) bool) *roaring.Bitmap {
+	result := roaring.New()
+	for _, r := range box.Cover() {
+		it := s.values.Range(field, r.Min, r.Max).Iterator()
+		for it.HasNext() {
+			ord := it.Next()
+			if key, ok := s.values.Numeric(field, ord); ok && match(geo.Decode(key)) {
+				result.Add(ord)
+			}
+		}
+	}
+
+	return result
+}
+
+func termString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	return fmt.Sprint(v)
+}
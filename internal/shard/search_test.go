@@ -0,0 +1,505 @@
+package shard
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/analyzer"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func newSearchShard(t *testing.T, docs ...index.Document) *Shard {
+	s, err := New(index.Index{
+		Name: "name",
+		Schema: schema.NewSchema(
+			map[string]schema.Field{
+				"title":  {Type: schema.TypeText, Analyzer: "whitespace", CopyTo: []string{"all"}},
+				"tag":    {Type: schema.TypeKeyword, CopyTo: []string{"all"}},
+				"all":    schema.NewField(schema.TypeAll, false, "whitespace"),
+				"bool":   schema.NewField(schema.TypeBool, false, ""),
+				"price":  schema.NewField(schema.TypeDouble, false, ""),
+				"count":  schema.NewField(schema.TypeInteger, false, ""),
+				"place":  schema.NewField(schema.TypeGeoPoint, false, ""),
+				"ip":     schema.NewField(schema.TypeIP, false, ""),
+				"vector": {Type: schema.TypeDenseVector, Dims: 2, VectorSimilarity: "l2"},
+				"name": {Type: schema.TypeText, Analyzer: "whitespace", Fields: map[string]schema.Field{
+					"raw": {Type: schema.TypeKeyword},
+				}},
+			},
+			map[string]schema.FieldAnalyzer{
+				"whitespace": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}},
+			},
+		),
+	}, storage.NewFile[string, index.Document]())
+	require.NoError(t, err)
+
+	for _, doc := range docs {
+		require.NoError(t, s.Put(doc))
+	}
+
+	return s
+}
+
+func searchIDs(t *testing.T, s *Shard, request string) []string {
+	t.Helper()
+
+	result, err := s.Search([]byte(request))
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(result.Hits.Hits))
+	for _, h := range result.Hits.Hits {
+		ids = append(ids, h.ID)
+	}
+
+	return ids
+}
+
+func Test_Shard_Search(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"title": "hello world", "tag": "a", "bool": true}),
+		index.NewDocument("2", schema.Source{"title": "hello", "tag": "b", "bool": false}),
+		index.NewDocument("3", schema.Source{"title": "world", "tag": "a"}),
+	)
+
+	t.Run("must return validation err for invalid query", func(t *testing.T) {
+		_, err := s.Search([]byte(`{"query": {"term": {"unknown": "a"}}}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+	})
+
+	t.Run("must match all documents by default", func(t *testing.T) {
+		require.Equal(t, []string{"1", "2", "3"}, searchIDs(t, s, ``))
+	})
+
+	t.Run("term", func(t *testing.T) {
+		require.Equal(t, []string{"1", "3"}, searchIDs(t, s, `{"query": {"term": {"tag": "a"}}}`))
+		require.Equal(t, []string{"2"}, searchIDs(t, s, `{"query": {"term": {"bool": false}}}`))
+	})
+
+	t.Run("terms", func(t *testing.T) {
+		require.Equal(t, []string{"1", "2", "3"}, searchIDs(t, s, `{"query": {"terms": {"tag": ["a", "b"]}}}`))
+	})
+
+	t.Run("match", func(t *testing.T) {
+		require.Equal(t, []string{"1", "2", "3"}, searchIDs(t, s, `{"query": {"match": {"title": "hello world"}}}`))
+		require.Equal(t, []string{"1"}, searchIDs(t, s, `{"query": {"match": {"title": {"query": "hello world", "operator": "and"}}}}`))
+		require.Empty(t, searchIDs(t, s, `{"query": {"match": {"title": ""}}}`))
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		require.Equal(t, []string{"1"}, searchIDs(t, s, `{"query": {"bool": {
+			"must": [{"match": {"title": "hello"}}],
+			"filter": [{"term": {"tag": "a"}}]
+		}}}`))
+		require.Equal(t, []string{"2", "3"}, searchIDs(t, s, `{"query": {"bool": {
+			"should": [{"term": {"tag": "b"}}, {"match": {"title": "world"}}],
+			"must_not": [{"term": {"bool": true}}]
+		}}}`))
+		require.Equal(t, []string{"1", "2", "3"}, searchIDs(t, s, `{"query": {"bool": {}}}`))
+	})
+
+	t.Run("must not return deleted documents", func(t *testing.T) {
+		s := newSearchShard(t,
+			index.NewDocument("1", schema.Source{"tag": "a"}),
+			index.NewDocument("2", schema.Source{"tag": "a"}),
+		)
+		require.NoError(t, s.Delete("1", 0))
+
+		result, err := s.Search([]byte(`{"query": {"term": {"tag": "a"}}}`))
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), result.Hits.Total)
+		require.Equal(t, "2", result.Hits.Hits[0].ID)
+	})
+}
+
+func Test_Shard_Search_Score(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"title": "hello world and everyone else", "tag": "a"}),
+		index.NewDocument("2", schema.Source{"title": "hello", "tag": "b"}),
+		index.NewDocument("3", schema.Source{"title": "world hello", "tag": "a"}),
+		index.NewDocument("4", schema.Source{"title": "goodbye", "tag": "a"}),
+	)
+
+	t.Run("must sort hits by bm25 score", func(t *testing.T) {
+		result, err := s.Search([]byte(`{"query": {"match": {"title": "hello world"}}}`))
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), result.Hits.Total)
+
+		hits := result.Hits.Hits
+		require.Equal(t, "3", hits[0].ID)
+		require.Equal(t, "1", hits[1].ID)
+		require.Equal(t, "2", hits[2].ID)
+		require.Greater(t, hits[0].Score, hits[1].Score)
+	})
+
+	t.Run("must not score filter clauses", func(t *testing.T) {
+		result, err := s.Search([]byte(`{"query": {"bool": {"filter": [{"term": {"tag": "a"}}]}}}`))
+		require.NoError(t, err)
+		require.Len(t, result.Hits.Hits, 3)
+		for _, h := range result.Hits.Hits {
+			require.Equal(t, 0.0, h.Score)
+		}
+	})
+
+	t.Run("must sum scores of must and should clauses", func(t *testing.T) {
+		result, err := s.Search([]byte(`{"query": {"bool": {
+			"must": [{"match": {"title": "hello"}}],
+			"should": [{"term": {"tag": "b"}}]
+		}}}`))
+		require.NoError(t, err)
+		require.Equal(t, "2", result.Hits.Hits[0].ID)
+		require.Greater(t, result.Hits.Hits[0].Score, 1.0)
+	})
+}
+
+func Test_Shard_Search_Range(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"price": json.Number("9.99"), "count": json.Number("1")}),
+		index.NewDocument("2", schema.Source{"price": json.Number("10"), "count": json.Number("-5")}),
+		index.NewDocument("3", schema.Source{"price": json.Number("25.5"), "count": json.Number("100")}),
+		index.NewDocument("4", schema.Source{"tag": "a"}),
+	)
+
+	t.Run("must match documents within bounds", func(t *testing.T) {
+		require.Equal(t, []string{"2", "3"}, searchIDs(t, s, `{"query": {"range": {"price": {"gte": 10}}}}`))
+		require.Equal(t, []string{"1"}, searchIDs(t, s, `{"query": {"range": {"price": {"lt": 10}}}}`))
+		require.Equal(t, []string{"1", "2"}, searchIDs(t, s, `{"query": {"range": {"count": {"gt": -10, "lte": 1}}}}`))
+		require.Empty(t, searchIDs(t, s, `{"query": {"range": {"count": {"gt": 1, "lt": 2}}}}`))
+	})
+
+	t.Run("must match numeric terms", func(t *testing.T) {
+		require.Equal(t, []string{"3"}, searchIDs(t, s, `{"query": {"term": {"count": 100}}}`))
+		require.Equal(t, []string{"1", "2"}, searchIDs(t, s, `{"query": {"terms": {"price": [9.99, 10]}}}`))
+	})
+
+	t.Run("must return validation err for invalid bounds", func(t *testing.T) {
+		_, err := s.Search([]byte(`{"query": {"range": {"count": {"gt": "a"}}}}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "query.range.count.gt")
+	})
+
+	t.Run("must not match removed documents", func(t *testing.T) {
+		require.NoError(t, s.Delete("2", 0))
+		require.Equal(t, []string{"3"}, searchIDs(t, s, `{"query": {"range": {"price": {"gte": 10}}}}`))
+	})
+}
+
+func Test_Shard_Search_Geo(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("berlin", schema.Source{"place": map[string]interface{}{"lat": json.Number("52.52"), "lon": json.Number("13.405")}}),
+		index.NewDocument("potsdam", schema.Source{"place": "52.39,13.065"}),
+		index.NewDocument("paris", schema.Source{"place": "u09tvw0"}),
+		index.NewDocument("suva", schema.Source{"place": "-18.14,178.44"}),
+		index.NewDocument("apia", schema.Source{"place": "-13.83,-171.76"}),
+		index.NewDocument("none", schema.Source{"tag": "a"}),
+	)
+
+	t.Run("must match documents within distance", func(t *testing.T) {
+		require.Equal(t, []string{"berlin"}, searchIDs(t, s, `{"query": {"geo_distance": {"distance": "20km", "place": "52.5,13.4"}}}`))
+		require.Equal(t, []string{"berlin", "potsdam"}, searchIDs(t, s, `{"query": {"geo_distance": {"distance": "30km", "place": "52.5,13.4"}}}`))
+		require.Equal(t, []string{"berlin", "potsdam", "paris"}, searchIDs(t, s, `{"query": {"geo_distance": {"distance": "1000km", "place": "52.5,13.4"}}}`))
+		require.Equal(t, []string{"suva", "apia"}, searchIDs(t, s, `{"query": {"geo_distance": {"distance": "1200km", "place": "-16,-178"}}}`))
+	})
+
+	t.Run("must match documents inside bounding box", func(t *testing.T) {
+		require.Equal(t, []string{"berlin", "potsdam"}, searchIDs(t, s, `{"query": {"geo_bounding_box": {"place": {"top_left": "53,13", "bottom_right": "52,14"}}}}`))
+		require.Equal(t, []string{"suva", "apia"}, searchIDs(t, s, `{"query": {"geo_bounding_box": {"place": {"top_left": "0,170", "bottom_right": "-20,-170"}}}}`))
+		require.Empty(t, searchIDs(t, s, `{"query": {"geo_bounding_box": {"place": {"top_left": "0,-170", "bottom_right": "-20,170"}}}}`))
+	})
+
+	t.Run("must sort by distance", func(t *testing.T) {
+		require.Equal(t, []string{"potsdam", "berlin", "paris", "apia", "suva", "none"}, searchIDs(t, s, `{"sort": [{"_geo_distance": {"place": "52.3,13"}}]}`))
+
+		result, err := s.Search([]byte(`{"sort": [{"_geo_distance": {"place": "52.52,13.405", "unit": "km", "order": "desc"}}], "size": 1, "query": {"geo_distance": {"distance": "1000km", "place": "52.5,13.4"}}}`))
+		require.NoError(t, err)
+		require.Equal(t, "paris", result.Hits.Hits[0].ID)
+		require.InDelta(t, 878, result.Hits.Hits[0].Sort[0], 2)
+	})
+
+	t.Run("must paginate by distance in any unit with search_after", func(t *testing.T) {
+		for _, unit := range []string{"m", "km", "mi", "nmi"} {
+			var (
+				ids   []string
+				after []interface{}
+			)
+			for {
+				req := map[string]interface{}{
+					"sort": []interface{}{map[string]interface{}{"_geo_distance": map[string]interface{}{"place": "52.3,13", "unit": unit}}},
+					"size": 1,
+				}
+				if after != nil {
+					req["search_after"] = after
+				}
+				data, err := json.Marshal(req)
+				require.NoError(t, err)
+
+				result, err := s.Search(data)
+				require.NoError(t, err)
+				if len(result.Hits.Hits) == 0 {
+					break
+				}
+				ids = append(ids, result.Hits.Hits[0].ID)
+				after = result.Hits.Hits[0].Sort
+				require.LessOrEqual(t, len(ids), 6, "must not repeat hits in %q", unit)
+			}
+			require.Equal(t, []string{"potsdam", "berlin", "paris", "apia", "suva", "none"}, ids, unit)
+		}
+	})
+}
+
+func Test_Shard_Search_IP(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"ip": "10.0.0.1"}),
+		index.NewDocument("2", schema.Source{"ip": "10.1.2.3"}),
+		index.NewDocument("3", schema.Source{"ip": "192.168.1.10"}),
+		index.NewDocument("4", schema.Source{"ip": "2001:db8::1"}),
+		index.NewDocument("5", schema.Source{"tag": "a"}),
+	)
+
+	t.Run("must match exact addresses and networks", func(t *testing.T) {
+		require.Equal(t, []string{"1"}, searchIDs(t, s, `{"query": {"term": {"ip": "::ffff:10.0.0.1"}}}`))
+		require.Equal(t, []string{"1", "2"}, searchIDs(t, s, `{"query": {"term": {"ip": "10.0.0.0/8"}}}`))
+		require.Equal(t, []string{"3", "4"}, searchIDs(t, s, `{"query": {"terms": {"ip": ["192.168.0.0/16", "2001:db8::/32"]}}}`))
+		require.Empty(t, searchIDs(t, s, `{"query": {"term": {"ip": "10.0.0.2"}}}`))
+	})
+
+	t.Run("must match ranges", func(t *testing.T) {
+		require.Equal(t, []string{"2", "3"}, searchIDs(t, s, `{"query": {"range": {"ip": {"gt": "10.0.0.1", "lte": "192.168.1.10"}}}}`))
+		require.Equal(t, []string{"4"}, searchIDs(t, s, `{"query": {"range": {"ip": {"gte": "::1:0:0:0"}}}}`))
+	})
+
+	t.Run("must aggregate addresses", func(t *testing.T) {
+		result, err := s.Search([]byte(`{"size": 0, "query": {"term": {"ip": "10.0.0.0/8"}}, "aggs": {"ips": {"terms": {"field": "ip"}}}}`))
+		require.NoError(t, err)
+		data, err := json.Marshal(result.Aggregations)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"ips": {"buckets": [{"key": "10.0.0.1", "doc_count": 1}, {"key": "10.1.2.3", "doc_count": 1}]}}`, string(data))
+	})
+}
+
+func Test_Shard_Search_Knn(t *testing.T) {
+	vec := func(x string, y string) []interface{} { return []interface{}{json.Number(x), json.Number(y)} }
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"vector": vec("0", "0"), "tag": "a"}),
+		index.NewDocument("2", schema.Source{"vector": vec("1", "0"), "tag": "b"}),
+		index.NewDocument("3", schema.Source{"vector": vec("3", "3"), "tag": "a"}),
+		index.NewDocument("4", schema.Source{"vector": vec("10", "10"), "tag": "b"}),
+		index.NewDocument("5", schema.Source{"tag": "a"}),
+	)
+
+	t.Run("must find nearest neighbors ordered by similarity", func(t *testing.T) {
+		require.Equal(t, []string{"2", "1"}, searchIDs(t, s, `{"query": {"knn": {"field": "vector", "query_vector": [1, 0.1], "k": 2}}}`))
+		require.Equal(t, []string{"4", "3", "2", "1"}, searchIDs(t, s, `{"query": {"knn": {"field": "vector", "query_vector": [9, 9]}}}`))
+
+		result, err := s.Search([]byte(`{"query": {"knn": {"field": "vector", "query_vector": [3, 4], "k": 1}}}`))
+		require.NoError(t, err)
+		require.InDelta(t, 0.5, result.Hits.Hits[0].Score, 1e-9)
+	})
+
+	t.Run("must search only filtered documents", func(t *testing.T) {
+		require.Equal(t, []string{"3", "1"}, searchIDs(t, s, `{"query": {"knn": {"field": "vector", "query_vector": [9, 9], "filter": {"term": {"tag": "a"}}}}}`))
+		require.Equal(t, []string{"3"}, searchIDs(t, s, `{"query": {"bool": {"must": {"knn": {"field": "vector", "query_vector": [9, 9], "k": 2}}, "filter": {"term": {"tag": "a"}}}}}`))
+	})
+
+	t.Run("must follow document changes", func(t *testing.T) {
+		_, err := s.Upsert(index.NewDocument("1", schema.Source{"vector": vec("20", "20")}), 0)
+		require.NoError(t, err)
+		require.NoError(t, s.Delete("4", 0))
+		require.Equal(t, []string{"1", "3"}, searchIDs(t, s, `{"query": {"knn": {"field": "vector", "query_vector": [19, 19], "k": 2}}}`))
+	})
+}
+
+func Test_Shard_Search_Hybrid(t *testing.T) {
+	vec := func(x string, y string) []interface{} { return []interface{}{json.Number(x), json.Number(y)} }
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"title": "red apple red", "vector": vec("5", "5")}),
+		index.NewDocument("2", schema.Source{"title": "red car", "vector": vec("1", "0")}),
+		index.NewDocument("3", schema.Source{"title": "green tree", "vector": vec("0", "0")}),
+		index.NewDocument("4", schema.Source{"title": "blue sky", "vector": vec("9", "9")}),
+	)
+	lexical := `{"query": {"match": {"title": "red"}}}`
+	semantic := `{"query": {"knn": {"field": "vector", "query_vector": [0, 0], "k": 2}}}`
+
+	t.Run("must fuse ranked lists with reciprocal rank fusion", func(t *testing.T) {
+		require.Equal(t, []string{"1", "2"}, searchIDs(t, s, lexical))
+		require.Equal(t, []string{"3", "2"}, searchIDs(t, s, semantic))
+
+		request := `{"query": {"hybrid": {"retrievers": [` + lexical + `, ` + semantic + `], "rank_constant": 1}}}`
+		result, err := s.Search([]byte(request))
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), result.Hits.Total)
+		require.Equal(t, "2", result.Hits.Hits[0].ID)
+		require.InDelta(t, 2.0/3, result.Hits.Hits[0].Score, 1e-9)
+		require.Equal(t, []string{"2", "1", "3"}, searchIDs(t, s, request))
+	})
+
+	t.Run("must apply retriever weights and window size", func(t *testing.T) {
+		require.Equal(t, []string{"2", "3", "1"}, searchIDs(t, s, `{"query": {"hybrid": {"retrievers": [`+lexical+`, {"query": {"knn": {"field": "vector", "query_vector": [0, 0], "k": 2}}, "weight": 3}]}}}`))
+		require.Equal(t, []string{"1", "3"}, searchIDs(t, s, `{"query": {"hybrid": {"retrievers": [`+lexical+`, `+semantic+`], "window_size": 1}}}`))
+	})
+
+	t.Run("must combine normalized scores linearly", func(t *testing.T) {
+		result, err := s.Search([]byte(`{"query": {"hybrid": {"retrievers": [` + lexical + `, {"query": {"knn": {"field": "vector", "query_vector": [0, 0], "k": 2}}, "weight": 0.5}], "fusion": "linear"}}}`))
+		require.NoError(t, err)
+		ids := make([]string, 0, len(result.Hits.Hits))
+		for _, h := range result.Hits.Hits {
+			ids = append(ids, h.ID)
+		}
+		require.Equal(t, []string{"1", "3", "2"}, ids)
+		require.InDelta(t, 1, result.Hits.Hits[0].Score, 1e-9)
+		require.InDelta(t, 0.5, result.Hits.Hits[1].Score, 1e-9)
+	})
+}
+
+func Test_Shard_Search_MultiFields(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"name": "New York"}),
+		index.NewDocument("2", schema.Source{"name": "York"}),
+		index.NewDocument("3", schema.Source{"name": "New Jersey"}),
+	)
+
+	t.Run("must search sub-fields by dotted name", func(t *testing.T) {
+		require.Equal(t, []string{"2", "1"}, searchIDs(t, s, `{"query": {"match": {"name": "York"}}}`))
+		require.Equal(t, []string{"2"}, searchIDs(t, s, `{"query": {"term": {"name.raw": "York"}}}`))
+		require.Equal(t, []string{"3", "1", "2"}, searchIDs(t, s, `{"sort": [{"name.raw": "asc"}]}`))
+	})
+
+	t.Run("must aggregate sub-fields", func(t *testing.T) {
+		result, err := s.Search([]byte(`{"size": 0, "query": {"match": {"name": "New"}}, "aggs": {"names": {"terms": {"field": "name.raw"}}}}`))
+		require.NoError(t, err)
+		data, err := json.Marshal(result.Aggregations)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"names": {"buckets": [{"key": "New Jersey", "doc_count": 1}, {"key": "New York", "doc_count": 1}]}}`, string(data))
+	})
+
+	t.Run("must remove sub-field values with the document", func(t *testing.T) {
+		_, err := s.Upsert(index.NewDocument("2", schema.Source{"name": "Boston"}), 0)
+		require.NoError(t, err)
+		require.Empty(t, searchIDs(t, s, `{"query": {"term": {"name.raw": "York"}}}`))
+		require.Equal(t, []string{"2"}, searchIDs(t, s, `{"query": {"term": {"name.raw": "Boston"}}}`))
+	})
+
+	t.Run("must report unknown sub-fields", func(t *testing.T) {
+		_, err := s.Search([]byte(`{"query": {"term": {"name.unknown": "York"}}}`))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "query.term.name.unknown")
+	})
+}
+
+func Test_Shard_Search_CopyTo(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"title": "red apple", "tag": "fruit"}),
+		index.NewDocument("2", schema.Source{"title": "fruit salad", "tag": "dish"}),
+		index.NewDocument("3", schema.Source{"title": "green tea", "tag": "drink", "name": "fruit"}),
+	)
+
+	t.Run("must search values copied from all fields", func(t *testing.T) {
+		require.Equal(t, []string{"1", "2"}, searchIDs(t, s, `{"query": {"match": {"all": "fruit"}}}`))
+		require.Equal(t, []string{"1"}, searchIDs(t, s, `{"query": {"match": {"all": {"query": "apple fruit", "operator": "and"}}}}`))
+		require.Equal(t, []string{"3"}, searchIDs(t, s, `{"query": {"term": {"all": "drink"}}}`))
+	})
+
+	t.Run("must reindex copied values on update", func(t *testing.T) {
+		require.NoError(t, s.Update("1", schema.Source{"tag": "snack"}, 0))
+		require.Equal(t, []string{"2"}, searchIDs(t, s, `{"query": {"match": {"all": "fruit"}}}`))
+		require.Equal(t, []string{"1"}, searchIDs(t, s, `{"query": {"match": {"all": "snack"}}}`))
+	})
+
+	t.Run("must reject documents setting all field", func(t *testing.T) {
+		err := s.Put(index.NewDocument("4", schema.Source{"all": "fruit"}))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "all")
+	})
+}
+
+func Test_Shard_Search_Sort(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"tag": "b", "price": json.Number("10"), "bool": true}),
+		index.NewDocument("2", schema.Source{"tag": "a", "price": json.Number("5")}),
+		index.NewDocument("3", schema.Source{"tag": "c", "bool": false}),
+		index.NewDocument("4", schema.Source{"tag": "a", "price": json.Number("20"), "bool": true}),
+	)
+
+	t.Run("must sort by field values", func(t *testing.T) {
+		require.Equal(t, []string{"2", "4", "1", "3"}, searchIDs(t, s, `{"sort": ["tag"]}`))
+		require.Equal(t, []string{"3", "1", "2", "4"}, searchIDs(t, s, `{"sort": [{"tag": "desc"}]}`))
+		require.Equal(t, []string{"2", "1", "4", "3"}, searchIDs(t, s, `{"sort": ["price"]}`))
+		require.Equal(t, []string{"4", "1", "2", "3"}, searchIDs(t, s, `{"sort": [{"price": "desc"}]}`))
+		require.Equal(t, []string{"3", "4", "1", "2"}, searchIDs(t, s, `{"sort": [{"price": {"order": "desc", "missing": "_first"}}]}`))
+		require.Equal(t, []string{"3", "1", "4", "2"}, searchIDs(t, s, `{"sort": ["bool"]}`))
+	})
+
+	t.Run("must sort by several fields with ordinal tie-breaker", func(t *testing.T) {
+		require.Equal(t, []string{"4", "2", "1", "3"}, searchIDs(t, s, `{"sort": ["tag", {"price": "desc"}]}`))
+		require.Equal(t, []string{"1", "4", "3", "2"}, searchIDs(t, s, `{"sort": [{"bool": "desc"}]}`))
+	})
+
+	t.Run("must return sort values with tie-breaker", func(t *testing.T) {
+		result, err := s.Search([]byte(`{"sort": ["tag", "price"], "size": 1}`))
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"a", json.Number("5"), s.ords["2"]}, result.Hits.Hits[0].Sort)
+	})
+
+	t.Run("must paginate with from and size", func(t *testing.T) {
+		require.Equal(t, []string{"4", "1"}, searchIDs(t, s, `{"sort": ["tag"], "from": 1, "size": 2}`))
+		require.Empty(t, searchIDs(t, s, `{"sort": ["tag"], "from": 10}`))
+
+		result, err := s.Search([]byte(`{"size": 0}`))
+		require.NoError(t, err)
+		require.Empty(t, result.Hits.Hits)
+		require.Equal(t, uint64(4), result.Hits.Total)
+	})
+
+	t.Run("must paginate with search_after", func(t *testing.T) {
+		var (
+			ids   []string
+			after []interface{}
+		)
+		for {
+			req := map[string]interface{}{"sort": []string{"bool"}, "size": 1}
+			if after != nil {
+				req["search_after"] = after
+			}
+			data, err := json.Marshal(req)
+			require.NoError(t, err)
+
+			result, err := s.Search(data)
+			require.NoError(t, err)
+			if len(result.Hits.Hits) == 0 {
+				break
+			}
+			ids = append(ids, result.Hits.Hits[0].ID)
+			after = result.Hits.Hits[0].Sort
+		}
+		require.Equal(t, []string{"3", "1", "4", "2"}, ids)
+	})
+}
+
+func Test_Shard_Search_Aggs(t *testing.T) {
+	s := newSearchShard(t,
+		index.NewDocument("1", schema.Source{"tag": "a", "price": json.Number("10")}),
+		index.NewDocument("2", schema.Source{"tag": "b", "price": json.Number("20")}),
+		index.NewDocument("3", schema.Source{"tag": "a", "price": json.Number("30")}),
+	)
+
+	result, err := s.Search([]byte(`{
+		"query": {"range": {"price": {"gt": 10}}},
+		"size": 0,
+		"aggs": {"tags": {"terms": {"field": "tag"}, "aggs": {"max_price": {"max": {"field": "price"}}}}}
+	}`))
+	require.NoError(t, err)
+
+	data, err := json.Marshal(result.Aggregations)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"tags": {"buckets": [
+		{"key": "a", "doc_count": 1, "max_price": {"value": 30}},
+		{"key": "b", "doc_count": 1, "max_price": {"value": 20}}
+	]}}`, string(data))
+}
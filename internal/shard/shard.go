@@ -0,0 +1,398 @@
+package shard
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/docvalues"
+	"github.com/f1monkey/search/internal/index/inverted"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/index/vector"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/f1monkey/search/pkg/errs"
+	"github.com/invopop/validation"
+)
+
+type DocumentStorage interface {
+	storage.Engine[string, index.Document]
+}
+
+// Shard holds documents of a single index together with their search structures
+type Shard struct {
+	mtx      sync.RWMutex
+	index    index.Index
+	docs     DocumentStorage
+	ords     map[string]uint32
+	ids      map[uint32]string
+	nextOrd  uint32
+	all      *roaring.Bitmap
+	inverted *inverted.Index
+	values   *docvalues.Index
+	vectors  map[string]*vector.Index
+}
+
+// New create shard and index all documents from the storage
+func New(idx index.Index, docs DocumentStorage) (*Shard, error) {
+	s := &Shard{docs: docs}
+	if err := s.load(idx); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SetIndex replace the index definition and reindex all documents keeping their ordinals
+func (s *Shard) SetIndex(idx index.Index) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.load(idx)
+}
+
+// extension the index definition extended with the new fields of the document being stored
+type extension struct {
+	index index.Index
+	// persist store the extended definition. Called once the document passed all the checks
+	persist func() error
+	// rollback restore the previous definition if the shard failed to apply the extended one
+	rollback func() error
+}
+
+// extend replace the index definition with the one declaring new fields and index the documents for these fields only.
+// The search structures of the existing fields and the document ordinals are kept as is. Must be called under the lock
+func (s *Shard) extend(idx index.Index) error {
+	if err := s.inverted.Extend(idx.Schema); err != nil {
+		return err
+	}
+	s.values.Extend(idx.Schema)
+
+	existing := s.index.Schema.IndexedFields()
+	added := make(map[string]struct{})
+	for name, f := range idx.Schema.IndexedFields() {
+		if _, ok := existing[name]; ok {
+			continue
+		}
+		added[name] = struct{}{}
+		if f.Type == schema.TypeDenseVector {
+			s.vectors[name] = vector.New(f.Dims, f.VectorSimilarity)
+		}
+	}
+	s.index = idx
+	if len(added) == 0 {
+		return nil
+	}
+
+	// documents could be stored with these fields before they were added, e.g. when unknown fields were ignored
+	s.docs.Iterate(func(id string, doc index.Document) bool {
+		source := idx.Schema.IndexedSource(doc.Source)
+		fields := make(schema.Source)
+		for name := range added {
+			if v, ok := source[name]; ok {
+				fields[name] = v
+			}
+		}
+		if len(fields) > 0 {
+			s.indexSource(s.ords[id], fields)
+		}
+		return true
+	})
+
+	return nil
+}
+
+// load reset the search structures for the index definition and add all documents from the storage to them.
+// Documents keep their ordinals on reload. On the first load the ordinals are assigned in the order
+// of the last changes of the documents, the same way they were assigned when the documents were stored
+func (s *Shard) load(idx index.Index) error {
+	inv, err := inverted.New(idx.Schema)
+	if err != nil {
+		return err
+	}
+
+	reload := s.ords != nil
+	var docs []index.Document
+	if !reload {
+		if docs, err = s.docsByChange(); err != nil {
+			return err
+		}
+	}
+
+	s.index = idx
+	s.inverted = inv
+	s.values = docvalues.New(idx.Schema)
+	s.vectors = make(map[string]*vector.Index)
+	for name, f := range idx.Schema.IndexedFields() {
+		if f.Type == schema.TypeDenseVector {
+			s.vectors[name] = vector.New(f.Dims, f.VectorSimilarity)
+		}
+	}
+
+	if reload {
+		s.docs.Iterate(func(id string, doc index.Document) bool {
+			s.indexSource(s.ords[id], idx.Schema.IndexedSource(doc.Source))
+			return true
+		})
+		return nil
+	}
+
+	s.ords = make(map[string]uint32, len(docs))
+	s.ids = make(map[uint32]string, len(docs))
+	s.all = roaring.New()
+	for _, doc := range docs {
+		s.add(doc)
+	}
+
+	return nil
+}
+
+// docsByChange get all documents from the storage sorted by the sequence numbers of their last changes
+func (s *Shard) docsByChange() ([]index.Document, error) {
+	var docs []index.Document
+	s.docs.Iterate(func(id string, doc index.Document) bool {
+		docs = append(docs, doc)
+		return true
+	})
+
+	seqNos := make(map[string]uint64, len(docs))
+	for _, doc := range docs {
+		_, meta, err := s.docs.GetWithMeta(doc.ID)
+		if err != nil {
+			return nil, errs.Errorf("document %q get err: %w", doc.ID, err)
+		}
+		seqNos[doc.ID] = meta.SeqNo
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		if seqNos[docs[i].ID] != seqNos[docs[j].ID] {
+			return seqNos[docs[i].ID] < seqNos[docs[j].ID]
+		}
+		return docs[i].ID < docs[j].ID
+	})
+
+	return docs, nil
+}
+
+// Index get index definition
+func (s *Shard) Index() index.Index {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.index
+}
+
+// Put validate the document against index schema and store it
+func (s *Shard) Put(doc index.Document) error {
+	return s.put(doc, nil)
+}
+
+func (s *Shard) put(doc index.Document, ext *extension) error {
+	if err := validation.Validate(doc); err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, err := s.docs.Get(doc.ID); err == nil {
+		return storage.ErrAlreadyExists
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	if err := s.accept(doc.Source, ext); err != nil {
+		return err
+	}
+
+	if err := s.docs.Create(doc.ID, doc); err != nil {
+		return err
+	}
+	s.add(doc)
+
+	return nil
+}
+
+// Upsert validate the document and store it replacing the existing one. Returns true if the document was created.
+// Non-zero ifSeqNo requires the existing document to be last changed with this sequence number
+func (s *Shard) Upsert(doc index.Document, ifSeqNo uint64) (bool, error) {
+	return s.upsert(doc, ifSeqNo, nil)
+}
+
+func (s *Shard) upsert(doc index.Document, ifSeqNo uint64, ext *extension) (bool, error) {
+	if err := validation.Validate(doc); err != nil {
+		return false, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := storage.CheckSeqNo[string, index.Document](s.docs, doc.ID, ifSeqNo); err != nil {
+		return false, err
+	}
+
+	if err := s.accept(doc.Source, ext); err != nil {
+		return false, err
+	}
+
+	return s.replace(doc)
+}
+
+// Update merge the fields into the existing document
+func (s *Shard) Update(id string, fields schema.Source, ifSeqNo uint64) error {
+	return s.update(id, fields, ifSeqNo, nil)
+}
+
+func (s *Shard) update(id string, fields schema.Source, ifSeqNo uint64, ext *extension) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	existing, err := s.docs.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := storage.CheckSeqNo[string, index.Document](s.docs, id, ifSeqNo); err != nil {
+		return err
+	}
+
+	source := make(schema.Source, len(existing.Source)+len(fields))
+	for k, v := range existing.Source {
+		source[k] = v
+	}
+	for k, v := range fields {
+		source[k] = v
+	}
+
+	if err := s.accept(source, ext); err != nil {
+		return err
+	}
+
+	_, err = s.replace(index.NewDocument(id, source))
+
+	return err
+}
+
+// accept validate the source against the index schema. If there is an extension, the source is validated against it
+// and the extended definition is persisted and applied to the shard. Must be called under the lock right before storing the document
+func (s *Shard) accept(source schema.Source, ext *extension) error {
+	if ext == nil {
+		return s.index.Schema.ValidateDoc(source)
+	}
+
+	if err := ext.index.Schema.ValidateDoc(source); err != nil {
+		return err
+	}
+	if err := ext.persist(); err != nil {
+		return err
+	}
+	if err := s.extend(ext.index); err != nil {
+		if rollbackErr := ext.rollback(); rollbackErr != nil {
+			return errs.Errorf("shard extend err: %v, index rollback err: %w", err, rollbackErr)
+		}
+		return errs.Errorf("shard extend err: %w", err)
+	}
+
+	return nil
+}
+
+// Sync wait until the document changes are persisted
+func (s *Shard) Sync() error {
+	return s.docs.Sync()
+}
+
+// Close release the document storage
+func (s *Shard) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.docs.Close()
+}
+
+// Get document by id together with its version
+func (s *Shard) Get(id string) (index.Document, storage.Meta, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.docs.GetWithMeta(id)
+}
+
+// Delete document by id
+func (s *Shard) Delete(id string, ifSeqNo uint64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	doc, err := s.docs.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := storage.CheckSeqNo[string, index.Document](s.docs, id, ifSeqNo); err != nil {
+		return err
+	}
+
+	if err := s.docs.Delete(id); err != nil {
+		return err
+	}
+	s.remove(doc)
+
+	return nil
+}
+
+func (s *Shard) replace(doc index.Document) (bool, error) {
+	existing, err := s.docs.Get(doc.ID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return false, err
+	}
+
+	created := err != nil
+	if created {
+		if err := s.docs.Create(doc.ID, doc); err != nil {
+			return false, err
+		}
+	} else {
+		if err := s.docs.Update(doc.ID, doc); err != nil {
+			return false, err
+		}
+		s.remove(existing)
+	}
+	s.add(doc)
+
+	return created, nil
+}
+
+func (s *Shard) add(doc index.Document) {
+	ord := s.nextOrd
+	s.nextOrd++
+
+	s.ords[doc.ID] = ord
+	s.ids[ord] = doc.ID
+	s.all.Add(ord)
+	s.indexSource(ord, s.index.Schema.IndexedSource(doc.Source))
+}
+
+// indexSource add the values of the source fields to the search structures under the ordinal
+func (s *Shard) indexSource(ord uint32, source schema.Source) {
+	s.inverted.Add(ord, source)
+	s.values.Add(ord, source)
+	for name, idx := range s.vectors {
+		if v, err := vector.Parse(source[name], idx.Dims(), idx.Similarity()); err == nil {
+			idx.Add(ord, v)
+		}
+	}
+}
+
+func (s *Shard) remove(doc index.Document) {
+	ord, ok := s.ords[doc.ID]
+	if !ok {
+		return
+	}
+
+	s.inverted.Remove(ord, s.index.Schema.IndexedSource(doc.Source))
+	s.values.Remove(ord)
+	for _, idx := range s.vectors {
+		idx.Remove(ord)
+	}
+	delete(s.ords, doc.ID)
+	delete(s.ids, ord)
+	s.all.Remove(ord)
+}
@@ -0,0 +1,283 @@
+package shard
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/analyzer"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func testIndex() index.Index {
+	return index.Index{
+		Name: "name",
+		Schema: schema.NewSchema(
+			map[string]schema.Field{
+				"bool":    schema.NewField(schema.TypeBool, false, ""),
+				"keyword": schema.NewField(schema.TypeKeyword, false, ""),
+				"long":    schema.NewField(schema.TypeLong, false, ""),
+			},
+			nil,
+		),
+	}
+}
+
+func newTestShard(t *testing.T) *Shard {
+	s, err := New(testIndex(), storage.NewFile[string, index.Document]())
+	require.NoError(t, err)
+
+	return s
+}
+
+func Test_New(t *testing.T) {
+	t.Run("must index documents from the storage", func(t *testing.T) {
+		docs := storage.NewFile[string, index.Document]()
+		require.NoError(t, docs.Create("1", index.NewDocument("1", schema.Source{"keyword": "value"})))
+
+		s, err := New(testIndex(), docs)
+		require.NoError(t, err)
+		require.Equal(t, []uint32{s.ords["1"]}, s.inverted.Term("keyword", "value").ToArray())
+	})
+
+	t.Run("must assign ordinals in the order of the last document changes", func(t *testing.T) {
+		docs := storage.NewFile[string, index.Document]()
+		require.NoError(t, docs.Create("b", index.NewDocument("b", schema.Source{"keyword": "value"})))
+		require.NoError(t, docs.Create("c", index.NewDocument("c", schema.Source{"keyword": "value"})))
+		require.NoError(t, docs.Create("a", index.NewDocument("a", schema.Source{"keyword": "value"})))
+		require.NoError(t, docs.Update("b", index.NewDocument("b", schema.Source{"keyword": "value"})))
+
+		for i := 0; i < 5; i++ {
+			s, err := New(testIndex(), docs)
+			require.NoError(t, err)
+			require.Equal(t, map[string]uint32{"c": 0, "a": 1, "b": 2}, s.ords)
+		}
+	})
+}
+
+func Test_Shard_SetIndex(t *testing.T) {
+	t.Run("must reindex documents with the new definition", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a"})))
+
+		idx := testIndex()
+		idx.Schema.Fields["keyword"] = schema.NewField(schema.TypeText, false, "whitespace")
+		idx.Schema.Analyzers = map[string]schema.FieldAnalyzer{
+			"whitespace": {Analyzers: []analyzer.Analyzer{{Type: analyzer.TokenizerWhitespace}}},
+		}
+		require.NoError(t, s.SetIndex(idx))
+		require.NoError(t, s.Put(index.NewDocument("2", schema.Source{"keyword": "a b"})))
+
+		require.Equal(t, idx, s.Index())
+		require.ElementsMatch(t, []uint32{s.ords["1"], s.ords["2"]}, s.inverted.Term("keyword", "a").ToArray())
+		require.Equal(t, []uint32{s.ords["2"]}, s.inverted.Term("keyword", "b").ToArray())
+	})
+
+	t.Run("must keep document ordinals", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a"})))
+		require.NoError(t, s.Put(index.NewDocument("2", schema.Source{"keyword": "a"})))
+		require.NoError(t, s.Delete("1", 0))
+
+		require.NoError(t, s.SetIndex(testIndex()))
+		require.Equal(t, map[string]uint32{"2": 1}, s.ords)
+		require.Equal(t, []uint32{1}, s.inverted.Term("keyword", "a").ToArray())
+
+		require.NoError(t, s.Put(index.NewDocument("3", schema.Source{"keyword": "a"})))
+		require.Equal(t, uint32(2), s.ords["3"])
+	})
+
+	t.Run("must keep the previous definition if failed to build the new one", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a"})))
+
+		idx := testIndex()
+		idx.Schema.Fields["keyword"] = schema.NewField(schema.TypeText, false, "unknown")
+		require.Error(t, s.SetIndex(idx))
+
+		require.Equal(t, testIndex(), s.Index())
+		require.Equal(t, []uint32{s.ords["1"]}, s.inverted.Term("keyword", "a").ToArray())
+	})
+}
+
+func Test_Shard_extend(t *testing.T) {
+	t.Run("must index only the new fields keeping document ordinals", func(t *testing.T) {
+		docs := storage.NewFile[string, index.Document]()
+		require.NoError(t, docs.Create("1", index.NewDocument("1", schema.Source{"keyword": "a", "new": "b"})))
+		s, err := New(testIndex(), docs)
+		require.NoError(t, err)
+		require.NoError(t, s.Put(index.NewDocument("2", schema.Source{"keyword": "a"})))
+		ords := map[string]uint32{"1": s.ords["1"], "2": s.ords["2"]}
+
+		idx := testIndex()
+		idx.Schema.Fields["new"] = schema.NewField(schema.TypeKeyword, false, "")
+		require.NoError(t, s.extend(idx))
+
+		require.Equal(t, idx, s.Index())
+		require.Equal(t, ords, s.ords)
+		require.ElementsMatch(t, []uint32{ords["1"], ords["2"]}, s.inverted.Term("keyword", "a").ToArray())
+		require.Equal(t, []uint32{ords["1"]}, s.inverted.Term("new", "b").ToArray())
+
+		v, ok := s.values.Keyword("new", ords["1"])
+		require.True(t, ok)
+		require.Equal(t, "b", v)
+	})
+}
+
+func Test_Shard_Put(t *testing.T) {
+	t.Run("must return err if document id is empty", func(t *testing.T) {
+		s := newTestShard(t)
+		err := s.Put(index.NewDocument("", schema.Source{"bool": true}))
+		require.Error(t, err)
+	})
+
+	t.Run("must return validation err if document does not match schema", func(t *testing.T) {
+		s := newTestShard(t)
+		err := s.Put(index.NewDocument("1", schema.Source{"bool": "true"}))
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+	})
+
+	t.Run("must return err if document already exists", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"bool": true})))
+		err := s.Put(index.NewDocument("1", schema.Source{"bool": false}))
+		require.ErrorIs(t, err, storage.ErrAlreadyExists)
+	})
+
+	t.Run("must store valid document", func(t *testing.T) {
+		s := newTestShard(t)
+		doc := index.NewDocument("1", schema.Source{"keyword": "value", "long": json.Number("1")})
+		require.NoError(t, s.Put(doc))
+
+		result, _, err := s.Get("1")
+		require.NoError(t, err)
+		require.Equal(t, doc, result)
+		require.Equal(t, []uint32{s.ords["1"]}, s.inverted.Term("keyword", "value").ToArray())
+	})
+}
+
+func Test_Shard_Upsert(t *testing.T) {
+	t.Run("must return validation err if document does not match schema", func(t *testing.T) {
+		s := newTestShard(t)
+		_, err := s.Upsert(index.NewDocument("1", schema.Source{"bool": "true"}), 0)
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+	})
+
+	t.Run("must create document if it does not exist", func(t *testing.T) {
+		s := newTestShard(t)
+		created, err := s.Upsert(index.NewDocument("1", schema.Source{"keyword": "a"}), 0)
+		require.NoError(t, err)
+		require.True(t, created)
+		require.Equal(t, []uint32{s.ords["1"]}, s.inverted.Term("keyword", "a").ToArray())
+	})
+
+	t.Run("must replace existing document", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a"})))
+
+		doc := index.NewDocument("1", schema.Source{"keyword": "b"})
+		created, err := s.Upsert(doc, 0)
+		require.NoError(t, err)
+		require.False(t, created)
+
+		result, _, err := s.Get("1")
+		require.NoError(t, err)
+		require.Equal(t, doc, result)
+		require.True(t, s.inverted.Term("keyword", "a").IsEmpty())
+		require.Equal(t, []uint32{s.ords["1"]}, s.inverted.Term("keyword", "b").ToArray())
+	})
+}
+
+func Test_Shard_Versions(t *testing.T) {
+	t.Run("must return document version", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a"})))
+		require.NoError(t, s.Update("1", schema.Source{"keyword": "b"}, 0))
+
+		_, meta, err := s.Get("1")
+		require.NoError(t, err)
+		require.Equal(t, storage.Meta{Version: 2, SeqNo: 2}, meta)
+	})
+
+	t.Run("must reject stale writes", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a"})))
+		require.NoError(t, s.Update("1", schema.Source{"keyword": "b"}, 1))
+
+		_, err := s.Upsert(index.NewDocument("1", schema.Source{"keyword": "c"}), 1)
+		require.ErrorIs(t, err, storage.ErrConflict)
+		require.ErrorIs(t, s.Update("1", schema.Source{"keyword": "c"}, 1), storage.ErrConflict)
+		require.ErrorIs(t, s.Delete("1", 1), storage.ErrConflict)
+
+		result, _, err := s.Get("1")
+		require.NoError(t, err)
+		require.Equal(t, schema.Source{"keyword": "b"}, result.Source)
+	})
+
+	t.Run("must reject conditional upsert of missing document", func(t *testing.T) {
+		s := newTestShard(t)
+		_, err := s.Upsert(index.NewDocument("1", schema.Source{"keyword": "a"}), 1)
+		require.ErrorIs(t, err, storage.ErrConflict)
+	})
+
+	t.Run("must accept writes with the current sequence number", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a"})))
+
+		created, err := s.Upsert(index.NewDocument("1", schema.Source{"keyword": "b"}), 1)
+		require.NoError(t, err)
+		require.False(t, created)
+		require.NoError(t, s.Delete("1", 2))
+	})
+}
+
+func Test_Shard_Update(t *testing.T) {
+	t.Run("must return err if document not found", func(t *testing.T) {
+		s := newTestShard(t)
+		err := s.Update("1", schema.Source{"keyword": "a"}, 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must return validation err if merged document does not match schema", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a"})))
+		err := s.Update("1", schema.Source{"long": "a"}, 0)
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+	})
+
+	t.Run("must merge fields into existing document", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"keyword": "a", "bool": true})))
+		require.NoError(t, s.Update("1", schema.Source{"keyword": "b", "long": json.Number("1")}, 0))
+
+		result, _, err := s.Get("1")
+		require.NoError(t, err)
+		require.Equal(t, schema.Source{"keyword": "b", "bool": true, "long": json.Number("1")}, result.Source)
+		require.Equal(t, []uint32{s.ords["1"]}, s.inverted.Term("keyword", "b").ToArray())
+	})
+}
+
+func Test_Shard_Delete(t *testing.T) {
+	t.Run("must return err if document not found", func(t *testing.T) {
+		s := newTestShard(t)
+		err := s.Delete("1", 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must delete document", func(t *testing.T) {
+		s := newTestShard(t)
+		require.NoError(t, s.Put(index.NewDocument("1", schema.Source{"bool": true})))
+		require.NoError(t, s.Delete("1", 0))
+
+		_, _, err := s.Get("1")
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		require.True(t, s.inverted.Term("bool", "true").IsEmpty())
+		require.NotContains(t, s.ords, "1")
+	})
+}
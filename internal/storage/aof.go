@@ -1,44 +1,84 @@
 package storage
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/f1monkey/search/pkg/errs"
+	"go.uber.org/zap"
 )
 
 type aofData[K comparable, V any] struct {
-	Key       K    `json:"key"`
-	Value     *V   `json:"value,omitempty"`
-	IsDeleted bool `json:"isDeleted,omitempty"`
+	Key       K      `json:"key"`
+	Value     *V     `json:"value,omitempty"`
+	Version   uint64 `json:"version,omitempty"`
+	SeqNo     uint64 `json:"seqNo,omitempty"`
+	IsUpdated bool   `json:"isUpdated,omitempty"`
+	IsDeleted bool   `json:"isDeleted,omitempty"`
+	// IsCheckpoint the record carries only the sequence number the storage had when the file was rewritten
+	IsCheckpoint bool `json:"isCheckpoint,omitempty"`
 }
 
 // AOF append-only file storage
 type AOF[K comparable, V any] struct {
-	mtx   sync.RWMutex
-	file  *os.File
-	items map[K]V
+	mtx      sync.RWMutex
+	file     *os.File
+	items    map[K]V
+	versions versions[K]
+
+	path     string
+	size     int64
+	baseSize int64
+	rewrite  *bytes.Buffer
+
+	fsync    FsyncPolicy
+	syncer   *syncer
+	recovery RecoveryMode
+	logger   *zap.Logger
+	closed   bool
 }
 
-func NewAOF[K comparable, V any](f *os.File) *AOF[K, V] {
-	return &AOF[K, V]{
-		items: make(map[K]V),
-		file:  f,
+func NewAOF[K comparable, V any](f *os.File, opts ...AOFOption) *AOF[K, V] {
+	o := newAOFOptions(opts)
+
+	var size int64
+	if stat, err := f.Stat(); err == nil {
+		size = stat.Size()
+	}
+
+	s := &AOF[K, V]{
+		items:    make(map[K]V),
+		versions: newVersions[K](),
+		file:     f,
+		path:     f.Name(),
+		size:     size,
+		baseSize: size,
+		fsync:    o.fsync,
+		recovery: o.recovery,
+		logger:   o.logger,
 	}
+
+	if s.fsync == FsyncEverySec {
+		s.syncer = newSyncer()
+		go s.syncLoop(o.fsyncInterval)
+	}
+
+	return s
 }
 
-func NewAOFFromPath[K comparable, V any](path string) (*AOF[K, V], error) {
+func NewAOFFromPath[K comparable, V any](path string, opts ...AOFOption) (*AOF[K, V], error) {
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
 	if err != nil {
 		return nil, errs.Errorf("err open file %q: %w", path, err)
 	}
 
-	// @todo close file on app stop
-
-	return NewAOF[K, V](f), nil
+	return NewAOF[K, V](f, opts...), nil
 }
 
 // Create element
@@ -50,12 +90,7 @@ func (s *AOF[K, V]) Create(key K, value V) error {
 		return ErrAlreadyExists
 	}
 
-	if err := s.writeData(aofData[K, V]{Key: key, Value: &value}); err != nil {
-		return err
-	}
-	s.items[key] = value
-
-	return nil
+	return s.set(key, value, false)
 }
 
 // Get element from storage
@@ -72,16 +107,70 @@ func (s *AOF[K, V]) Get(key K) (V, error) {
 	return empty, ErrNotFound
 }
 
-// Delete element from storage
-func (s *AOF[K, V]) Delete(key K) error {
+// GetWithMeta get element from storage together with its version
+func (s *AOF[K, V]) GetWithMeta(key K) (V, Meta, error) {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
 
+	if value, ok := s.items[key]; ok {
+		return value, s.versions.get(key), nil
+	}
+
+	var empty V
+
+	return empty, Meta{}, ErrNotFound
+}
+
+// Update replace the existing element
+func (s *AOF[K, V]) Update(key K, value V) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.items[key]; !ok {
+		return ErrNotFound
+	}
+
+	return s.set(key, value, true)
+}
+
+// Upsert create the element or replace the existing one. Returns true if the element was created
+func (s *AOF[K, V]) Upsert(key K, value V) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	_, exists := s.items[key]
+	if err := s.set(key, value, exists); err != nil {
+		return false, err
+	}
+
+	return !exists, nil
+}
+
+// set write the new version of the element. Must be called under the write lock
+func (s *AOF[K, V]) set(key K, value V, isUpdated bool) error {
+	meta := s.versions.next(key)
+	dat := aofData[K, V]{Key: key, Value: &value, Version: meta.Version, SeqNo: meta.SeqNo, IsUpdated: isUpdated}
+	if err := s.writeData(dat); err != nil {
+		return err
+	}
+	s.items[key] = value
+	s.versions.set(key, meta)
+
+	return nil
+}
+
+// Delete element from storage
+func (s *AOF[K, V]) Delete(key K) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
 	if _, ok := s.items[key]; ok {
-		if err := s.writeData(aofData[K, V]{Key: key, IsDeleted: true}); err != nil {
+		seqNo := s.versions.nextSeqNo()
+		if err := s.writeData(aofData[K, V]{Key: key, SeqNo: seqNo, IsDeleted: true}); err != nil {
 			return err
 		}
 		delete(s.items, key)
+		s.versions.delete(key, seqNo)
 		return nil
 	}
 
@@ -100,53 +189,230 @@ func (s *AOF[K, V]) All() []V {
 	return result
 }
 
+// Iterate call fn for every element until it returns false. The storage must not be modified from fn
+func (s *AOF[K, V]) Iterate(fn func(key K, value V) bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	for k, v := range s.items {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Init load the items from the file. A torn write at the end of the file is truncated,
+// corruption in the middle is handled according to the recovery mode.
+// Files in the legacy JSON lines format are converted to the current one
 func (s *AOF[K, V]) Init(ctx context.Context) error {
-	scanner := bufio.NewScanner(s.file)
+	result, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
 
-	// // @todo implement to process long lines
-	// const maxCapacity int = 10000000000
-	// buf := make([]byte, maxCapacity)
-	// scanner.Buffer(buf, maxCapacity)
+	if result.Legacy {
+		if err := s.Rewrite(); err != nil {
+			return errs.Errorf("legacy file convert err: %w", err)
+		}
+		s.logger.Info("legacy file converted", zap.String("file", s.path), zap.Int("records", result.Records))
+	}
 
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
+	return nil
+}
+
+func (s *AOF[K, V]) load(ctx context.Context) (ScanResult, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return ScanResult{}, errs.Errorf("file seek err: %w", err)
+	}
+
+	result, err := scanRecords(s.file, s.size, func(payload []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
-		v := aofData[K, V]{}
-		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
-			// @todo handle corrupted data
-			return errs.Errorf("data unmarshal err: %w", err)
+		v, err := decodeRecord[K, V](payload)
+		if err != nil {
+			return err
 		}
+		if v.IsCheckpoint {
+			s.versions.observe(v.SeqNo)
+			return nil
+		}
+		if _, ok := s.items[v.Key]; v.IsUpdated && !ok {
+			return fmt.Errorf("%w: got update of missing key %v", errInvalidRecord, v.Key)
+		}
+
+		// records written before versioning get the next version
 		if v.IsDeleted {
+			if v.SeqNo == 0 {
+				v.SeqNo = s.versions.nextSeqNo()
+			}
 			delete(s.items, v.Key)
-		} else if v.Value == nil {
-			// @todo handle corrupted data
-			return errs.Errorf("got nil value for key #%v", v.Key)
+			s.versions.delete(v.Key, v.SeqNo)
 		} else {
+			meta := Meta{Version: v.Version, SeqNo: v.SeqNo}
+			if meta.SeqNo == 0 {
+				meta = s.versions.next(v.Key)
+			}
 			s.items[v.Key] = *v.Value
+			s.versions.set(v.Key, meta)
 		}
+
+		return nil
+	})
+	if err != nil {
+		return result, errs.Errorf("init err: %w", err)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return errs.Errorf("init err: %w", err)
+	if c := result.Corruption; c != nil {
+		if !c.Tail && s.recovery == RecoveryStrict {
+			return result, errs.Errorf("file %q: %w", s.path, c)
+		}
+
+		if err := s.file.Truncate(result.Size); err != nil {
+			return result, errs.Errorf("file truncate err: %w", err)
+		}
+		s.logger.Warn(
+			"corrupted data truncated",
+			zap.String("file", s.path),
+			zap.Int64("offset", c.Offset),
+			zap.Int64("discarded_bytes", s.size-result.Size),
+			zap.String("reason", c.Reason),
+		)
+		s.size = result.Size
+		s.baseSize = s.size
 	}
 
-	return nil
+	return result, nil
+}
+
+// Close sync and close the file
+func (s *AOF[K, V]) Close() error {
+	if s.syncer != nil {
+		s.syncer.close()
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if err := s.file.Sync(); err != nil {
+		return errs.Errorf("file sync err: %w", err)
+	}
+
+	return s.file.Close()
 }
 
+// rotate continue logging to the new file and return a copy of the items and versions the previous file ends with.
+// The previous file is synced and closed
+func (s *AOF[K, V]) rotate(f *os.File) (map[K]V, versions[K], error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return nil, versions[K]{}, ErrClosed
+	}
+	if s.rewrite != nil {
+		return nil, versions[K]{}, ErrRewriteInProgress
+	}
+
+	if err := s.file.Sync(); err != nil {
+		return nil, versions[K]{}, errs.Errorf("file sync err: %w", err)
+	}
+	s.file.Close()
+
+	s.file = f
+	s.path = f.Name()
+	s.size = 0
+	s.baseSize = 0
+
+	items := make(map[K]V, len(s.items))
+	for k, v := range s.items {
+		items[k] = v
+	}
+
+	return items, s.versions.copy(), nil
+}
+
+// Sync wait until all the previous writes are on disk according to the fsync policy.
+// With everysec policy it blocks until the next background sync, so concurrent writers share a single fsync
+func (s *AOF[K, V]) Sync() error {
+	if s.syncer == nil {
+		return nil
+	}
+
+	return s.syncer.last().wait()
+}
+
+// writeData append the record to the file. Must be called under the write lock
 func (s *AOF[K, V]) writeData(dat aofData[K, V]) error {
-	// @todo make it async
-	data, err := json.Marshal(dat)
+	payload, err := json.Marshal(dat)
 	if err != nil {
 		return errs.Errorf("element marshal err: %w", err)
 	}
-	data = append(data, "\n"...)
+	record := encodeRecord(payload)
+	data := record
+	if s.size == 0 {
+		data = append(append([]byte{}, aofMagic...), record...)
+	}
 	if _, err := s.file.Write(data); err != nil {
 		return errs.Errorf("element write err: %w", err)
 	}
+	s.size += int64(len(data))
+
+	if s.rewrite != nil {
+		s.rewrite.Write(record)
+	}
+
+	switch s.fsync {
+	case FsyncAlways:
+		if err := s.file.Sync(); err != nil {
+			return errs.Errorf("element sync err: %w", err)
+		}
+	case FsyncEverySec:
+		s.syncer.next()
+	}
 
 	return nil
 }
+
+// syncLoop periodically sync the file and notify the writes waiting for it
+func (s *AOF[K, V]) syncLoop(interval time.Duration) {
+	defer close(s.syncer.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.syncer.stop:
+			s.sync()
+			return
+		case <-ticker.C:
+			s.sync()
+		}
+	}
+}
+
+func (s *AOF[K, V]) sync() {
+	// the read lock keeps writers away, so all of them that got the pending result are in the file already
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	result := s.syncer.take()
+	if result == nil {
+		return
+	}
+
+	if err := s.file.Sync(); err != nil {
+		result.err = errs.Errorf("file sync err: %w", err)
+	}
+	s.syncer.done(result)
+}
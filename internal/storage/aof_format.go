@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+// ErrCorrupted the file contains an invalid record
+var ErrCorrupted = fmt.Errorf("data is corrupted")
+
+// errInvalidRecord returned by record handlers when the record passed the checksum but cannot be applied
+var errInvalidRecord = fmt.Errorf("invalid record")
+
+// aofMagic starts every file in the framed format. Files without it are treated as legacy JSON lines
+var aofMagic = []byte("AOF1")
+
+// aofHeaderSize record header: payload length and CRC32 (Castagnoli) of the payload, both little-endian uint32
+const aofHeaderSize = 8
+
+// aofMaxRecordSize records declaring a larger payload are considered corrupted
+const aofMaxRecordSize = 1 << 30
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// RecoveryMode defines how the corrupted data is handled on load.
+// A partially written record at the end of the file is always truncated as it is a result of a crash during write.
+type RecoveryMode string
+
+const (
+	// RecoveryStrict refuse to load a file corrupted in the middle
+	RecoveryStrict RecoveryMode = "strict"
+	// RecoveryTruncate drop everything starting from the first corrupted record
+	RecoveryTruncate RecoveryMode = "truncate"
+)
+
+func ParseRecoveryMode(s string) (RecoveryMode, error) {
+	switch m := RecoveryMode(s); m {
+	case RecoveryStrict, RecoveryTruncate:
+		return m, nil
+	}
+
+	return "", fmt.Errorf("unknown recovery mode %q", s)
+}
+
+// ScanResult summary of the file contents
+type ScanResult struct {
+	// Legacy the file is in JSON lines format
+	Legacy bool
+	// Records number of valid records
+	Records int
+	// Size length of the valid part of the file
+	Size int64
+	// Corruption the first invalid record if any
+	Corruption *Corruption
+}
+
+// Corruption describes an invalid record
+type Corruption struct {
+	Offset int64
+	// Tail the record is the last one in the file and was not written completely
+	Tail   bool
+	Reason string
+}
+
+func (c *Corruption) Error() string {
+	return fmt.Sprintf("%s at offset %d: %s", ErrCorrupted.Error(), c.Offset, c.Reason)
+}
+
+func (c *Corruption) Unwrap() error {
+	return ErrCorrupted
+}
+
+// Verify check the file format and record checksums. Size is the length of the data the reader returns
+func Verify(r io.Reader, size int64) (ScanResult, error) {
+	return scanRecords(r, size, func(payload []byte) error {
+		_, err := decodeRecord[interface{}, json.RawMessage](payload)
+		return err
+	})
+}
+
+func encodeRecord(payload []byte) []byte {
+	data := make([]byte, aofHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(data[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(data[4:8], crc32.Checksum(payload, crcTable))
+	copy(data[aofHeaderSize:], payload)
+
+	return data
+}
+
+func decodeRecord[K comparable, V any](payload []byte) (aofData[K, V], error) {
+	v := aofData[K, V]{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return v, fmt.Errorf("%w: %s", errInvalidRecord, err.Error())
+	}
+	if v.IsCheckpoint {
+		return v, nil
+	}
+	if v.IsDeleted && v.IsUpdated {
+		return v, fmt.Errorf("%w: got both update and delete for key %v", errInvalidRecord, v.Key)
+	}
+	if !v.IsDeleted && v.Value == nil {
+		return v, fmt.Errorf("%w: got nil value for key %v", errInvalidRecord, v.Key)
+	}
+
+	return v, nil
+}
+
+// scanRecords read the records and pass their payloads to the handler.
+// Stops on the first invalid record, handler errors other than errInvalidRecord are returned as is.
+// Size is the length of the data the reader returns, record lengths are checked against it before reading
+func scanRecords(r io.Reader, size int64, handle func(payload []byte) error) (ScanResult, error) {
+	reader := bufio.NewReader(r)
+
+	magic, err := reader.Peek(len(aofMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ScanResult{}, errs.Errorf("file read err: %w", err)
+	}
+
+	if len(magic) == 0 {
+		return ScanResult{}, nil
+	}
+	if !bytes.Equal(magic, aofMagic) {
+		if magic[0] == '{' {
+			return scanLegacyRecords(reader, handle)
+		}
+		if bytes.HasPrefix(aofMagic, magic) {
+			return ScanResult{Corruption: &Corruption{Tail: true, Reason: "incomplete file header"}}, nil
+		}
+
+		return ScanResult{Corruption: &Corruption{Reason: "unknown file format"}}, nil
+	}
+	reader.Discard(len(aofMagic))
+
+	result := ScanResult{Size: int64(len(aofMagic))}
+	header := make([]byte, aofHeaderSize)
+	for {
+		n, err := io.ReadFull(reader, header)
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			result.Corruption = &Corruption{Offset: result.Size, Tail: true, Reason: fmt.Sprintf("incomplete record header of %d bytes", n)}
+			return result, nil
+		}
+		if err != nil {
+			return result, errs.Errorf("file read err: %w", err)
+		}
+
+		length := binary.LittleEndian.Uint32(header[0:4])
+		remaining := size - result.Size - aofHeaderSize
+		if int64(length) > remaining {
+			c, err := scanOverrun(reader, result.Size, length, remaining)
+			if err != nil {
+				return result, err
+			}
+			result.Corruption = c
+			return result, nil
+		}
+
+		if length > aofMaxRecordSize {
+			result.Corruption = &Corruption{Offset: result.Size, Reason: fmt.Sprintf("invalid record length %d", length)}
+			return result, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return result, errs.Errorf("file read err: %w", err)
+		}
+
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+			_, err := reader.Peek(1)
+			result.Corruption = &Corruption{Offset: result.Size, Tail: errors.Is(err, io.EOF), Reason: "checksum mismatch"}
+			return result, nil
+		}
+
+		if err := handle(payload); err != nil {
+			if errors.Is(err, errInvalidRecord) {
+				result.Corruption = &Corruption{Offset: result.Size, Reason: err.Error()}
+				return result, nil
+			}
+			return result, err
+		}
+
+		result.Records++
+		result.Size += int64(aofHeaderSize) + int64(length)
+	}
+}
+
+// scanOverrun classify the record declaring more bytes than the file has left.
+// It is a torn write only if it is the last record: if a complete record is found in the bytes after its header,
+// the length is corrupted in the middle of the file
+func scanOverrun(reader io.Reader, offset int64, length uint32, remaining int64) (*Corruption, error) {
+	rest, err := io.ReadAll(io.LimitReader(reader, remaining))
+	if err != nil {
+		return nil, errs.Errorf("file read err: %w", err)
+	}
+
+	if containsRecord(rest) {
+		return &Corruption{Offset: offset, Reason: fmt.Sprintf("record length %d exceeds the remaining %d bytes", length, remaining)}, nil
+	}
+
+	return &Corruption{Offset: offset, Tail: true, Reason: "incomplete record"}, nil
+}
+
+// containsRecord whether the data contains a complete record with a valid checksum at any offset
+func containsRecord(data []byte) bool {
+	for i := 0; i+aofHeaderSize < len(data); i++ {
+		length := int(binary.LittleEndian.Uint32(data[i : i+4]))
+		end := i + aofHeaderSize + length
+		// payloads are JSON objects
+		if length == 0 || end > len(data) || data[i+aofHeaderSize] != '{' {
+			continue
+		}
+		if crc32.Checksum(data[i+aofHeaderSize:end], crcTable) == binary.LittleEndian.Uint32(data[i+4:i+8]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanLegacyRecords read JSON lines written before records were framed.
+// The last line without a line break that cannot be parsed is considered a torn write
+func scanLegacyRecords(reader *bufio.Reader, handle func(payload []byte) error) (ScanResult, error) {
+	result := ScanResult{Legacy: true}
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return result, errs.Errorf("file read err: %w", err)
+		}
+		if len(line) == 0 {
+			return result, nil
+		}
+
+		complete := err == nil
+		if payload := bytes.TrimSpace(line); len(payload) > 0 {
+			if err := handle(payload); err != nil {
+				if errors.Is(err, errInvalidRecord) {
+					result.Corruption = &Corruption{Offset: result.Size, Tail: !complete, Reason: err.Error()}
+					return result, nil
+				}
+				return result, err
+			}
+			result.Records++
+		}
+
+		result.Size += int64(len(line))
+		if !complete {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func framed(payloads ...string) string {
+	data := append([]byte{}, aofMagic...)
+	for _, p := range payloads {
+		data = append(data, encodeRecord([]byte(p))...)
+	}
+
+	return string(data)
+}
+
+func Test_ParseRecoveryMode(t *testing.T) {
+	t.Run("must return err on unknown mode", func(t *testing.T) {
+		_, err := ParseRecoveryMode("ignore")
+		require.Error(t, err)
+	})
+
+	t.Run("must parse known modes", func(t *testing.T) {
+		for _, m := range []RecoveryMode{RecoveryStrict, RecoveryTruncate} {
+			result, err := ParseRecoveryMode(string(m))
+			require.NoError(t, err)
+			require.Equal(t, m, result)
+		}
+	})
+}
+
+func Test_Verify(t *testing.T) {
+	record1 := `{"key":"key","value":{"value":"value"}}`
+	record2 := `{"key":"key","isDeleted":true}`
+	valid := framed(record1, record2)
+
+	corruptedChecksum := []byte(framed(record1))
+	corruptedChecksum[len(aofMagic)+4]++
+	corruptedChecksum = append(corruptedChecksum, encodeRecord([]byte(record2))...)
+
+	tornChecksum := []byte(valid)
+	tornChecksum[len(tornChecksum)-1]++
+
+	corruptedLength := []byte(framed(record1, record2, record1))
+	corruptedLength[len(framed(record1))+1]++
+
+	tornLength := []byte(valid)
+	tornLength[len(framed(record1))+3] = 0x40
+
+	cases := []struct {
+		name     string
+		data     string
+		expected ScanResult
+	}{
+		{
+			name:     "empty file",
+			data:     "",
+			expected: ScanResult{},
+		},
+		{
+			name:     "valid records",
+			data:     valid,
+			expected: ScanResult{Records: 2, Size: int64(len(valid))},
+		},
+		{
+			name:     "legacy records",
+			data:     record1 + "\n" + record2 + "\n",
+			expected: ScanResult{Legacy: true, Records: 2, Size: int64(len(record1) + len(record2) + 2)},
+		},
+		{
+			name: "legacy torn line",
+			data: record1 + "\n" + record2[:5],
+			expected: ScanResult{
+				Legacy:     true,
+				Records:    1,
+				Size:       int64(len(record1) + 1),
+				Corruption: &Corruption{Offset: int64(len(record1) + 1), Tail: true},
+			},
+		},
+		{
+			name: "legacy corrupted line",
+			data: record1 + "\n{invalid\n" + record2 + "\n",
+			expected: ScanResult{
+				Legacy:     true,
+				Records:    1,
+				Size:       int64(len(record1) + 1),
+				Corruption: &Corruption{Offset: int64(len(record1) + 1)},
+			},
+		},
+		{
+			name:     "incomplete file header",
+			data:     string(aofMagic[:2]),
+			expected: ScanResult{Corruption: &Corruption{Tail: true}},
+		},
+		{
+			name:     "unknown format",
+			data:     "unknown",
+			expected: ScanResult{Corruption: &Corruption{}},
+		},
+		{
+			name: "incomplete record header",
+			data: valid + "\x01\x02",
+			expected: ScanResult{
+				Records:    2,
+				Size:       int64(len(valid)),
+				Corruption: &Corruption{Offset: int64(len(valid)), Tail: true},
+			},
+		},
+		{
+			name: "incomplete record",
+			data: valid[:len(valid)-3],
+			expected: ScanResult{
+				Records:    1,
+				Size:       int64(len(framed(record1))),
+				Corruption: &Corruption{Offset: int64(len(framed(record1))), Tail: true},
+			},
+		},
+		{
+			name: "length of the last record exceeding the file",
+			data: string(tornLength),
+			expected: ScanResult{
+				Records:    1,
+				Size:       int64(len(framed(record1))),
+				Corruption: &Corruption{Offset: int64(len(framed(record1))), Tail: true},
+			},
+		},
+		{
+			name: "length exceeding the file in the middle",
+			data: string(corruptedLength),
+			expected: ScanResult{
+				Records:    1,
+				Size:       int64(len(framed(record1))),
+				Corruption: &Corruption{Offset: int64(len(framed(record1)))},
+			},
+		},
+		{
+			name: "checksum mismatch of the last record",
+			data: string(tornChecksum),
+			expected: ScanResult{
+				Records:    1,
+				Size:       int64(len(framed(record1))),
+				Corruption: &Corruption{Offset: int64(len(framed(record1))), Tail: true},
+			},
+		},
+		{
+			name: "checksum mismatch in the middle",
+			data: string(corruptedChecksum),
+			expected: ScanResult{
+				Size:       int64(len(aofMagic)),
+				Corruption: &Corruption{Offset: int64(len(aofMagic))},
+			},
+		},
+		{
+			name: "invalid record",
+			data: framed(record1, `{"key":"key"}`, record2),
+			expected: ScanResult{
+				Records:    1,
+				Size:       int64(len(framed(record1))),
+				Corruption: &Corruption{Offset: int64(len(framed(record1)))},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run("must handle "+c.name, func(t *testing.T) {
+			result, err := Verify(bytes.NewReader([]byte(c.data)), int64(len(c.data)))
+			require.NoError(t, err)
+
+			if result.Corruption != nil {
+				require.NotEmpty(t, result.Corruption.Reason)
+				result.Corruption.Reason = ""
+			}
+			require.Equal(t, c.expected, result)
+		})
+	}
+}
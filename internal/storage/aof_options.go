@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type AOFOption func(o *aofOptions)
+
+type aofOptions struct {
+	fsync         FsyncPolicy
+	fsyncInterval time.Duration
+	recovery      RecoveryMode
+	logger        *zap.Logger
+}
+
+func newAOFOptions(opts []AOFOption) aofOptions {
+	o := aofOptions{
+		fsync:         FsyncNo,
+		fsyncInterval: DefaultFsyncInterval,
+		recovery:      RecoveryStrict,
+		logger:        zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithFsync set the fsync policy of the file
+func WithFsync(policy FsyncPolicy) AOFOption {
+	return func(o *aofOptions) {
+		o.fsync = policy
+	}
+}
+
+// WithFsyncInterval set the sync interval for FsyncEverySec policy
+func WithFsyncInterval(interval time.Duration) AOFOption {
+	return func(o *aofOptions) {
+		o.fsyncInterval = interval
+	}
+}
+
+// WithRecovery set how the corrupted data is handled on load
+func WithRecovery(mode RecoveryMode) AOFOption {
+	return func(o *aofOptions) {
+		o.recovery = mode
+	}
+}
+
+// WithLogger set the logger to report recovery actions
+func WithLogger(logger *zap.Logger) AOFOption {
+	return func(o *aofOptions) {
+		o.logger = logger
+	}
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/f1monkey/search/pkg/errs"
+)
+
+var ErrRewriteInProgress = fmt.Errorf("rewrite is already in progress")
+var ErrClosed = fmt.Errorf("storage is closed")
+
+// RewritePolicy thresholds for the automatic AOF rewrite
+type RewritePolicy struct {
+	// Ratio rewrite when the file is Ratio times larger than after the previous rewrite. Zero disables rewrites
+	Ratio float64
+	// MinSize do not rewrite files smaller than this
+	MinSize int64
+}
+
+// NeedsRewrite check if the file has grown enough to be rewritten
+func (s *AOF[K, V]) NeedsRewrite(policy RewritePolicy) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if policy.Ratio <= 0 || s.rewrite != nil || s.size == 0 || s.size < policy.MinSize {
+		return false
+	}
+
+	return float64(s.size) >= float64(s.baseSize)*policy.Ratio
+}
+
+// Size current file size in bytes
+func (s *AOF[K, V]) Size() int64 {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.size
+}
+
+// Rewrite replace the log with the minimal set of records producing the current items.
+// Writes are accepted while the new file is being written and are appended to it before the swap.
+func (s *AOF[K, V]) Rewrite() error {
+	items, versions, err := s.startRewrite()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := s.writeSnapshot(items, versions)
+	if err != nil {
+		s.abortRewrite()
+		return err
+	}
+
+	if err := s.finishRewrite(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+func (s *AOF[K, V]) startRewrite() (map[K]V, versions[K], error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return nil, versions[K]{}, ErrClosed
+	}
+	if s.rewrite != nil {
+		return nil, versions[K]{}, ErrRewriteInProgress
+	}
+
+	items := make(map[K]V, len(s.items))
+	for k, v := range s.items {
+		items[k] = v
+	}
+	s.rewrite = &bytes.Buffer{}
+
+	return items, s.versions.copy(), nil
+}
+
+func (s *AOF[K, V]) abortRewrite() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.rewrite = nil
+}
+
+// writeSnapshot write the items with their versions. The sequence number is kept with a checkpoint record,
+// so the numbers of the deleted elements are not reused
+func (s *AOF[K, V]) writeSnapshot(items map[K]V, versions versions[K]) (*os.File, error) {
+	tmp, err := os.OpenFile(s.path+".rewrite", os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errs.Errorf("rewrite file open err: %w", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	w.Write(aofMagic)
+
+	write := func(record aofData[K, V]) error {
+		payload, err := json.Marshal(record)
+		if err != nil {
+			return errs.Errorf("rewrite element marshal err: %w", err)
+		}
+		_, err = w.Write(encodeRecord(payload))
+		return err
+	}
+
+	err = write(aofData[K, V]{SeqNo: versions.seqNo, IsCheckpoint: true})
+	for k := range items {
+		if err != nil {
+			break
+		}
+		v := items[k]
+		meta := versions.get(k)
+		err = write(aofData[K, V]{Key: k, Value: &v, Version: meta.Version, SeqNo: meta.SeqNo})
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, errs.Errorf("rewrite file write err: %w", err)
+	}
+
+	return tmp, nil
+}
+
+func (s *AOF[K, V]) finishRewrite(tmp *os.File) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	defer func() { s.rewrite = nil }()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if _, err := tmp.Write(s.rewrite.Bytes()); err != nil {
+		return errs.Errorf("rewrite buffer write err: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return errs.Errorf("rewrite file sync err: %w", err)
+	}
+
+	stat, err := tmp.Stat()
+	if err != nil {
+		return errs.Errorf("rewrite file stat err: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errs.Errorf("rewrite file rename err: %w", err)
+	}
+	syncDir(filepath.Dir(s.path))
+
+	s.file.Close()
+	s.file = tmp
+	s.size = stat.Size()
+	s.baseSize = s.size
+
+	return nil
+}
+
+// syncDir persist the directory entry after rename. Errors are ignored as some platforms do not support it
+func syncDir(path string) {
+	d, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+
+	d.Sync()
+}
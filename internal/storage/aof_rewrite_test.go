@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_AOF_NeedsRewrite(t *testing.T) {
+	f := path.Join(t.TempDir(), "tmp.dat")
+	s, err := NewAOFFromPath[string, testData](f)
+	require.NoError(t, err)
+
+	t.Run("must return false if file is empty", func(t *testing.T) {
+		require.False(t, s.NeedsRewrite(RewritePolicy{Ratio: 2}))
+	})
+
+	require.NoError(t, s.Create("key", testData{"value"}))
+
+	t.Run("must return false if rewrites are disabled", func(t *testing.T) {
+		require.False(t, s.NeedsRewrite(RewritePolicy{}))
+	})
+
+	t.Run("must return false if file is smaller than min size", func(t *testing.T) {
+		require.False(t, s.NeedsRewrite(RewritePolicy{Ratio: 2, MinSize: 1024}))
+	})
+
+	t.Run("must return true if file has grown enough", func(t *testing.T) {
+		require.True(t, s.NeedsRewrite(RewritePolicy{Ratio: 2}))
+	})
+
+	t.Run("must compare size with size after previous rewrite", func(t *testing.T) {
+		require.NoError(t, s.Rewrite())
+		require.False(t, s.NeedsRewrite(RewritePolicy{Ratio: 2}))
+
+		require.NoError(t, s.Delete("key"))
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Update("key", testData{"value2"}))
+		require.True(t, s.NeedsRewrite(RewritePolicy{Ratio: 2}))
+	})
+}
+
+func Test_AOF_Rewrite(t *testing.T) {
+	t.Run("must keep only current items", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+		require.NoError(t, s.Delete("key"))
+
+		require.NoError(t, s.Rewrite())
+
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t, framed(
+			`{"key":"","seqNo":3,"isCheckpoint":true}`,
+			`{"key":"key2","value":{"value":"value2"},"version":1,"seqNo":2}`,
+		), string(data))
+		require.Equal(t, int64(len(data)), s.Size())
+		require.NoFileExists(t, f+".rewrite")
+	})
+
+	t.Run("must not reuse sequence numbers of deleted elements", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+		require.NoError(t, s.Delete("key2"))
+		require.NoError(t, s.Rewrite())
+		require.NoError(t, s.Close())
+
+		restored, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+		require.NoError(t, restored.Init(context.Background()))
+		require.NoError(t, restored.Create("key2", testData{"value2"}))
+
+		_, meta, err := restored.GetWithMeta("key2")
+		require.NoError(t, err)
+		require.Equal(t, Meta{Version: 1, SeqNo: 4}, meta)
+	})
+
+	t.Run("must append writes to the new file", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Rewrite())
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t, framed(
+			`{"key":"","seqNo":1,"isCheckpoint":true}`,
+			`{"key":"key","value":{"value":"value"},"version":1,"seqNo":1}`,
+			`{"key":"key2","value":{"value":"value2"},"version":1,"seqNo":2}`,
+		), string(data))
+	})
+
+	t.Run("must keep writes made during rewrite", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+
+		items, versions, err := s.startRewrite()
+		require.NoError(t, err)
+		require.ErrorIs(t, s.Rewrite(), ErrRewriteInProgress)
+
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+		require.NoError(t, s.Delete("key"))
+
+		tmp, err := s.writeSnapshot(items, versions)
+		require.NoError(t, err)
+		require.NoError(t, s.finishRewrite(tmp))
+
+		restored, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+		require.NoError(t, restored.Init(context.Background()))
+		require.Equal(t, map[string]testData{"key2": {"value2"}}, restored.items)
+	})
+
+	t.Run("must return err if storage is closed", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		items, versions, err := s.startRewrite()
+		require.NoError(t, err)
+		tmp, err := s.writeSnapshot(items, versions)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Close())
+		require.ErrorIs(t, s.finishRewrite(tmp), ErrClosed)
+		require.ErrorIs(t, s.Rewrite(), ErrClosed)
+	})
+}
@@ -22,7 +22,7 @@ func Test_AOF_Create(t *testing.T) {
 
 		data, err := os.ReadFile(f)
 		require.NoError(t, err)
-		require.Equal(t, "{\"key\":\"key\",\"value\":{\"value\":\"value\"}}\n", string(data))
+		require.Equal(t, framed(`{"key":"key","value":{"value":"value"},"version":1,"seqNo":1}`), string(data))
 		require.Contains(t, storage.items, key)
 		require.Equal(t, storage.items[key], value)
 	})
@@ -34,9 +34,8 @@ func Test_AOF_Create(t *testing.T) {
 
 		data, err := os.ReadFile(f)
 		require.NoError(t, err)
-		require.Equal(t, `{"key":"key","value":{"value":"value"}}
-{"key":"key2","value":{"value":"value2"}}
-`,
+		require.Equal(t,
+			framed(`{"key":"key","value":{"value":"value"},"version":1,"seqNo":1}`, `{"key":"key2","value":{"value":"value2"},"version":1,"seqNo":2}`),
 			string(data),
 		)
 		require.Contains(t, storage.items, key)
@@ -71,6 +70,48 @@ func Test_AOF_Get(t *testing.T) {
 	})
 }
 
+func Test_AOF_Update(t *testing.T) {
+	t.Run("must write update record", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Update("key", testData{"value2"}))
+
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t,
+			framed(`{"key":"key","value":{"value":"value"},"version":1,"seqNo":1}`, `{"key":"key","value":{"value":"value2"},"version":2,"seqNo":2,"isUpdated":true}`),
+			string(data),
+		)
+	})
+}
+
+func Test_AOF_Upsert(t *testing.T) {
+	t.Run("must write create record for new element and update record for existing one", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+
+		created, err := s.Upsert("key", testData{"value"})
+		require.NoError(t, err)
+		require.True(t, created)
+
+		created, err = s.Upsert("key", testData{"value2"})
+		require.NoError(t, err)
+		require.False(t, created)
+
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t,
+			framed(`{"key":"key","value":{"value":"value"},"version":1,"seqNo":1}`, `{"key":"key","value":{"value":"value2"},"version":2,"seqNo":2,"isUpdated":true}`),
+			string(data),
+		)
+		require.Equal(t, map[string]testData{"key": {"value2"}}, s.items)
+	})
+}
+
 func Test_AOF_Delete(t *testing.T) {
 	t.Run("must return err if element not found", func(t *testing.T) {
 		f := path.Join(t.TempDir(), "tmp.dat")
@@ -92,9 +133,8 @@ func Test_AOF_Delete(t *testing.T) {
 		require.NoError(t, err)
 		data, err := os.ReadFile(f)
 		require.NoError(t, err)
-		require.Equal(t, `{"key":"key","value":{"value":"value"}}
-{"key":"key","isDeleted":true}
-`,
+		require.Equal(t,
+			framed(`{"key":"key","value":{"value":"value"},"version":1,"seqNo":1}`, `{"key":"key","seqNo":2,"isDeleted":true}`),
 			string(data),
 		)
 
@@ -110,7 +150,7 @@ func Test_AOF_All(t *testing.T) {
 	s.items["key"] = testData{Value: "value"}
 	s.items["key2"] = testData{Value: "value2"}
 
-	require.Equal(t, []testData{{Value: "value"}, {Value: "value2"}}, s.All())
+	require.ElementsMatch(t, []testData{{Value: "value"}, {Value: "value2"}}, s.All())
 }
 
 func Test_AOF_Init(t *testing.T) {
@@ -151,4 +191,136 @@ func Test_AOF_Init(t *testing.T) {
 		require.Contains(t, s.items, "key2")
 		require.Equal(t, testData{Value: "value2"}, s.items["key2"])
 	})
+
+	t.Run("must assign versions to records written without them", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		require.NoError(t, os.WriteFile(f, []byte(framed(
+			`{"key":"key","value":{"value":"value"}}`,
+			`{"key":"key","value":{"value":"value2"}}`,
+			`{"key":"key2","value":{"value":"value"},"version":4,"seqNo":7}`,
+		)), 0600))
+
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+		require.NoError(t, s.Init(context.Background()))
+
+		_, meta, err := s.GetWithMeta("key")
+		require.NoError(t, err)
+		require.Equal(t, Meta{Version: 2, SeqNo: 2}, meta)
+		_, meta, err = s.GetWithMeta("key2")
+		require.NoError(t, err)
+		require.Equal(t, Meta{Version: 4, SeqNo: 7}, meta)
+	})
+
+	t.Run("must convert legacy file to the framed format", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+
+		err := os.WriteFile(f, []byte(`{"key":"key","value":{"value":"value"}}
+`), 0600)
+		require.NoError(t, err)
+
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+		require.NoError(t, s.Init(context.Background()))
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t,
+			framed(
+				`{"key":"","seqNo":1,"isCheckpoint":true}`,
+				`{"key":"key","value":{"value":"value"},"version":1,"seqNo":1}`,
+				`{"key":"key2","value":{"value":"value2"},"version":1,"seqNo":2}`,
+			),
+			string(data),
+		)
+	})
+
+	t.Run("must truncate torn tail", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+
+		valid := framed(`{"key":"key","value":{"value":"value"}}`)
+		torn := encodeRecord([]byte(`{"key":"key2","value":{"value":"value2"}}`))
+		err := os.WriteFile(f, append([]byte(valid), torn[:10]...), 0600)
+		require.NoError(t, err)
+
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+		require.NoError(t, s.Init(context.Background()))
+		require.Equal(t, map[string]testData{"key": {"value"}}, s.items)
+		require.Equal(t, int64(len(valid)), s.Size())
+
+		require.NoError(t, s.Create("key3", testData{"value3"}))
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t,
+			framed(`{"key":"key","value":{"value":"value"}}`, `{"key":"key3","value":{"value":"value3"},"version":1,"seqNo":2}`),
+			string(data),
+		)
+	})
+
+	corrupted := []byte(framed(
+		`{"key":"key","value":{"value":"value"}}`,
+		`{"key":"key2","value":{"value":"value2"}}`,
+		`{"key":"key3","value":{"value":"value3"}}`,
+	))
+	corrupted[len(framed(`{"key":"key","value":{"value":"value"}}`))+aofHeaderSize]++
+
+	t.Run("must return err on corruption in the middle in strict mode", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		require.NoError(t, os.WriteFile(f, corrupted, 0600))
+
+		s, err := NewAOFFromPath[string, testData](f, WithRecovery(RecoveryStrict))
+		require.NoError(t, err)
+		require.ErrorIs(t, s.Init(context.Background()), ErrCorrupted)
+
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t, corrupted, data, "must not modify the file")
+	})
+
+	t.Run("must return err on corrupted record length in the middle in strict mode", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		data := []byte(framed(
+			`{"key":"key","value":{"value":"value"}}`,
+			`{"key":"key2","value":{"value":"value2"}}`,
+			`{"key":"key3","value":{"value":"value3"}}`,
+		))
+		data[len(framed(`{"key":"key","value":{"value":"value"}}`))+1]++
+		require.NoError(t, os.WriteFile(f, data, 0600))
+
+		s, err := NewAOFFromPath[string, testData](f, WithRecovery(RecoveryStrict))
+		require.NoError(t, err)
+		require.ErrorIs(t, s.Init(context.Background()), ErrCorrupted)
+
+		result, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t, data, result, "must not modify the file")
+	})
+
+	t.Run("must truncate corruption in the middle in truncate mode", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		require.NoError(t, os.WriteFile(f, corrupted, 0600))
+
+		s, err := NewAOFFromPath[string, testData](f, WithRecovery(RecoveryTruncate))
+		require.NoError(t, err)
+		require.NoError(t, s.Init(context.Background()))
+		require.Equal(t, map[string]testData{"key": {"value"}}, s.items)
+
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t, framed(`{"key":"key","value":{"value":"value"}}`), string(data))
+	})
+
+	t.Run("must return err on update of missing element in strict mode", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		require.NoError(t, os.WriteFile(f, []byte(framed(
+			`{"key":"key","value":{"value":"value"}}`,
+			`{"key":"key2","value":{"value":"value2"},"isUpdated":true}`,
+		)), 0600))
+
+		s, err := NewAOFFromPath[string, testData](f)
+		require.NoError(t, err)
+		require.ErrorIs(t, s.Init(context.Background()), ErrCorrupted)
+	})
 }
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Engine key-value storage backend
+type Engine[K comparable, V any] interface {
+	// Create element, returns ErrAlreadyExists if the key is taken
+	Create(key K, value V) error
+	// Get element, returns ErrNotFound if there is no such key
+	Get(key K) (V, error)
+	// GetWithMeta get element together with its version, returns ErrNotFound if there is no such key
+	GetWithMeta(key K) (V, Meta, error)
+	// Update replace the existing element, returns ErrNotFound if there is no such key
+	Update(key K, value V) error
+	// Upsert create the element or replace the existing one. Returns true if the element was created
+	Upsert(key K, value V) (bool, error)
+	// Delete element, returns ErrNotFound if there is no such key
+	Delete(key K) error
+	// Iterate call fn for every element until it returns false. The storage must not be modified from fn
+	Iterate(fn func(key K, value V) bool)
+	// Sync wait until the previous changes are persisted as far as the engine guarantees
+	Sync() error
+	// Close persist the pending changes and release the resources
+	Close() error
+}
+
+var _ Engine[string, string] = (*File[string, string])(nil)
+var _ Engine[string, string] = (*Snapshot[string, string])(nil)
+var _ Engine[string, string] = (*AOF[string, string])(nil)
+var _ Engine[string, string] = (*Hybrid[string, string])(nil)
+
+// EngineKind storage backend type
+type EngineKind string
+
+const (
+	// EngineMemory keep the data in memory only
+	EngineMemory EngineKind = "memory"
+	// EngineSnapshot save the whole data to a file periodically
+	EngineSnapshot EngineKind = "snapshot"
+	// EngineAOF log every change to an append-only file
+	EngineAOF EngineKind = "aof"
+	// EngineHybrid save the whole data periodically and log the changes made since the last save
+	EngineHybrid EngineKind = "hybrid"
+)
+
+func ParseEngineKind(s string) (EngineKind, error) {
+	switch k := EngineKind(s); k {
+	case EngineMemory, EngineSnapshot, EngineAOF, EngineHybrid:
+		return k, nil
+	}
+
+	return "", fmt.Errorf("unknown storage engine %q", s)
+}
+
+// Config engine settings
+type Config struct {
+	Engine           EngineKind
+	SnapshotInterval time.Duration
+	AOF              []AOFOption
+	Logger           *zap.Logger
+}
+
+const (
+	aofExt      = ".dat"
+	snapshotExt = ".snapshot"
+)
+
+// Open create the engine storing its data at the path (without extension) and load the persisted data
+func Open[K comparable, V any](ctx context.Context, path string, cfg Config) (Engine[K, V], error) {
+	switch cfg.Engine {
+	case EngineMemory:
+		return NewFile[K, V](), nil
+	case EngineSnapshot:
+		return NewSnapshotFromPath[K, V](path+snapshotExt, cfg.SnapshotInterval, cfg.Logger)
+	case EngineAOF:
+		s, err := NewAOFFromPath[K, V](path+aofExt, cfg.AOF...)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Init(ctx); err != nil {
+			s.Close()
+			return nil, err
+		}
+		return s, nil
+	case EngineHybrid:
+		return NewHybridFromPath[K, V](ctx, path, cfg.SnapshotInterval, cfg.AOF...)
+	}
+
+	return nil, fmt.Errorf("unknown storage engine %q", cfg.Engine)
+}
+
+// Remove delete the data of every engine type stored at the path
+func Remove(path string) error {
+	paths := []string{path + aofExt, path + aofExt + ".rewrite", path + snapshotExt, path + snapshotExt + ".tmp"}
+
+	segments, err := listSegments(path)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		paths = append(paths, seg.path)
+	}
+
+	var result error
+	for _, p := range paths {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			result = errors.Join(result, err)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// engineFactory opens the engine at the path. Persistent engines must restore the data on reopen
+type engineFactory func(t *testing.T, path string) Engine[string, testData]
+
+// testEngine conformance suite every engine must pass
+func testEngine(t *testing.T, open engineFactory, persistent bool) {
+	t.Run("must create and get element", func(t *testing.T) {
+		s := open(t, path.Join(t.TempDir(), "data"))
+		defer s.Close()
+
+		_, err := s.Get("key")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.ErrorIs(t, s.Create("key", testData{"value2"}), ErrAlreadyExists)
+
+		value, err := s.Get("key")
+		require.NoError(t, err)
+		require.Equal(t, testData{"value"}, value)
+	})
+
+	t.Run("must update existing element", func(t *testing.T) {
+		s := open(t, path.Join(t.TempDir(), "data"))
+		defer s.Close()
+
+		require.ErrorIs(t, s.Update("key", testData{"value"}), ErrNotFound)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Update("key", testData{"value2"}))
+
+		value, err := s.Get("key")
+		require.NoError(t, err)
+		require.Equal(t, testData{"value2"}, value)
+	})
+
+	t.Run("must upsert element", func(t *testing.T) {
+		s := open(t, path.Join(t.TempDir(), "data"))
+		defer s.Close()
+
+		created, err := s.Upsert("key", testData{"value"})
+		require.NoError(t, err)
+		require.True(t, created)
+
+		created, err = s.Upsert("key", testData{"value2"})
+		require.NoError(t, err)
+		require.False(t, created)
+
+		value, err := s.Get("key")
+		require.NoError(t, err)
+		require.Equal(t, testData{"value2"}, value)
+	})
+
+	t.Run("must delete element", func(t *testing.T) {
+		s := open(t, path.Join(t.TempDir(), "data"))
+		defer s.Close()
+
+		require.ErrorIs(t, s.Delete("key"), ErrNotFound)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Delete("key"))
+
+		_, err := s.Get("key")
+		require.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, s.Create("key", testData{"value2"}))
+	})
+
+	t.Run("must iterate all elements", func(t *testing.T) {
+		s := open(t, path.Join(t.TempDir(), "data"))
+		defer s.Close()
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+		require.NoError(t, s.Create("key3", testData{"value3"}))
+		require.NoError(t, s.Delete("key3"))
+
+		items := make(map[string]testData)
+		s.Iterate(func(key string, value testData) bool {
+			items[key] = value
+			return true
+		})
+		require.Equal(t, map[string]testData{"key": {"value"}, "key2": {"value2"}}, items)
+
+		count := 0
+		s.Iterate(func(key string, value testData) bool {
+			count++
+			return false
+		})
+		require.Equal(t, 1, count, "must stop iteration when fn returns false")
+	})
+
+	t.Run("must track versions", func(t *testing.T) {
+		s := open(t, path.Join(t.TempDir(), "data"))
+		defer s.Close()
+
+		_, _, err := s.GetWithMeta("key")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Create("key2", testData{"value"}))
+		require.NoError(t, s.Update("key", testData{"value2"}))
+		_, err = s.Upsert("key", testData{"value3"})
+		require.NoError(t, err)
+
+		value, meta, err := s.GetWithMeta("key")
+		require.NoError(t, err)
+		require.Equal(t, testData{"value3"}, value)
+		require.Equal(t, Meta{Version: 3, SeqNo: 4}, meta)
+
+		require.NoError(t, s.Delete("key"))
+		require.NoError(t, s.Create("key", testData{"value"}))
+		_, meta, err = s.GetWithMeta("key")
+		require.NoError(t, err)
+		require.Equal(t, Meta{Version: 1, SeqNo: 6}, meta, "must start new version but keep sequence")
+	})
+
+	t.Run("must sync changes", func(t *testing.T) {
+		s := open(t, path.Join(t.TempDir(), "data"))
+		defer s.Close()
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Sync())
+	})
+
+	if !persistent {
+		return
+	}
+
+	t.Run("must restore data after reopen", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "data")
+		s := open(t, p)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+		require.NoError(t, s.Create("key3", testData{"value3"}))
+		require.NoError(t, s.Update("key2", testData{"value22"}))
+		_, err := s.Upsert("key4", testData{"value4"})
+		require.NoError(t, err)
+		_, err = s.Upsert("key4", testData{"value44"})
+		require.NoError(t, err)
+		require.NoError(t, s.Delete("key3"))
+		require.NoError(t, s.Close())
+
+		s = open(t, p)
+		defer s.Close()
+
+		items := make(map[string]testData)
+		s.Iterate(func(key string, value testData) bool {
+			items[key] = value
+			return true
+		})
+		require.Equal(t, map[string]testData{"key": {"value"}, "key2": {"value22"}, "key4": {"value44"}}, items)
+
+		_, meta, err := s.GetWithMeta("key2")
+		require.NoError(t, err)
+		require.Equal(t, Meta{Version: 2, SeqNo: 4}, meta)
+
+		require.NoError(t, s.Create("key5", testData{"value5"}))
+		_, meta, err = s.GetWithMeta("key5")
+		require.NoError(t, err)
+		require.Equal(t, Meta{Version: 1, SeqNo: 8}, meta, "must continue sequence")
+	})
+
+	t.Run("must remove data", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "data")
+		s := open(t, p)
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Close())
+
+		require.NoError(t, Remove(p))
+
+		s = open(t, p)
+		defer s.Close()
+		_, err := s.Get("key")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func openTestEngine(cfg Config) engineFactory {
+	return func(t *testing.T, path string) Engine[string, testData] {
+		s, err := Open[string, testData](context.Background(), path, cfg)
+		require.NoError(t, err)
+		return s
+	}
+}
+
+func Test_Engine_Memory(t *testing.T) {
+	testEngine(t, openTestEngine(Config{Engine: EngineMemory}), false)
+}
+
+func Test_Engine_Snapshot(t *testing.T) {
+	testEngine(t, openTestEngine(Config{Engine: EngineSnapshot}), true)
+}
+
+func Test_Engine_AOF(t *testing.T) {
+	testEngine(t, openTestEngine(Config{Engine: EngineAOF}), true)
+}
+
+func Test_Engine_AOF_EverySec(t *testing.T) {
+	testEngine(t, openTestEngine(Config{Engine: EngineAOF, AOF: []AOFOption{WithFsync(FsyncEverySec), WithFsyncInterval(time.Millisecond)}}), true)
+}
+
+func Test_Engine_Hybrid(t *testing.T) {
+	testEngine(t, openTestEngine(Config{Engine: EngineHybrid}), true)
+}
+
+func Test_Engine_Hybrid_Snapshots(t *testing.T) {
+	testEngine(t, openTestEngine(Config{Engine: EngineHybrid, SnapshotInterval: time.Millisecond}), true)
+}
+
+func Test_ParseEngineKind(t *testing.T) {
+	t.Run("must return err on unknown engine", func(t *testing.T) {
+		_, err := ParseEngineKind("unknown")
+		require.Error(t, err)
+	})
+
+	t.Run("must parse known engines", func(t *testing.T) {
+		for _, k := range []EngineKind{EngineMemory, EngineSnapshot, EngineAOF, EngineHybrid} {
+			result, err := ParseEngineKind(string(k))
+			require.NoError(t, err)
+			require.Equal(t, k, result)
+		}
+	})
+}
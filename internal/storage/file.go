@@ -11,13 +11,15 @@ var ErrAlreadyExists = fmt.Errorf("element already exists")
 var ErrNotFound = fmt.Errorf("element not found")
 
 type File[K comparable, V any] struct {
-	mtx   sync.RWMutex
-	items map[K]V
+	mtx      sync.RWMutex
+	items    map[K]V
+	versions versions[K]
 }
 
 func NewFile[K comparable, V any]() *File[K, V] {
 	return &File[K, V]{
-		items: make(map[K]V),
+		items:    make(map[K]V),
+		versions: newVersions[K](),
 	}
 }
 
@@ -30,7 +32,7 @@ func (s *File[K, V]) Create(key K, value V) error {
 		return ErrAlreadyExists
 	}
 
-	s.items[key] = value
+	s.set(key, value)
 
 	return nil
 }
@@ -49,13 +51,58 @@ func (s *File[K, V]) Get(key K) (V, error) {
 	return empty, ErrNotFound
 }
 
-// Delete element from storage
-func (s *File[K, V]) Delete(key K) error {
+// GetWithMeta get element from storage together with its version
+func (s *File[K, V]) GetWithMeta(key K) (V, Meta, error) {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
 
+	if value, ok := s.items[key]; ok {
+		return value, s.versions.get(key), nil
+	}
+
+	var empty V
+
+	return empty, Meta{}, ErrNotFound
+}
+
+// Update replace the existing element
+func (s *File[K, V]) Update(key K, value V) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.items[key]; !ok {
+		return ErrNotFound
+	}
+
+	s.set(key, value)
+
+	return nil
+}
+
+// Upsert create the element or replace the existing one. Returns true if the element was created
+func (s *File[K, V]) Upsert(key K, value V) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	_, exists := s.items[key]
+	s.set(key, value)
+
+	return !exists, nil
+}
+
+func (s *File[K, V]) set(key K, value V) {
+	s.items[key] = value
+	s.versions.set(key, s.versions.next(key))
+}
+
+// Delete element from storage
+func (s *File[K, V]) Delete(key K) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
 	if _, ok := s.items[key]; ok {
 		delete(s.items, key)
+		s.versions.delete(key, s.versions.nextSeqNo())
 		return nil
 	}
 
@@ -74,8 +121,32 @@ func (s *File[K, V]) All() []V {
 	return result
 }
 
+// Iterate call fn for every element until it returns false. The storage must not be modified from fn
+func (s *File[K, V]) Iterate(fn func(key K, value V) bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	for k, v := range s.items {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
 type fileData[K comparable, V any] struct {
 	Items map[K]V
+	Meta  map[K]Meta
+	SeqNo uint64
+}
+
+// Sync is a no-op as the data is kept in memory until Save
+func (s *File[K, V]) Sync() error {
+	return nil
+}
+
+// Close is a no-op as the data is kept in memory
+func (s *File[K, V]) Close() error {
+	return nil
 }
 
 // Save encodes data and writes it to the provided writer
@@ -85,6 +156,8 @@ func (s *File[K, V]) Save(w io.Writer) error {
 
 	data := fileData[K, V]{
 		Items: s.items,
+		Meta:  s.versions.meta,
+		SeqNo: s.versions.seqNo,
 	}
 
 	return gob.NewEncoder(w).Encode(data)
@@ -99,7 +172,12 @@ func Load[K comparable, V any](reader io.Reader) (*File[K, V], error) {
 		return nil, err
 	}
 
+	if data.Items == nil {
+		data.Items = make(map[K]V)
+	}
+
 	return &File[K, V]{
-		items: data.Items,
+		items:    data.Items,
+		versions: restoreVersions(data.Items, data.Meta, data.SeqNo),
 	}, nil
 }
@@ -86,5 +86,5 @@ func Test_File_All(t *testing.T) {
 	s.items["key"] = testData{Value: "value"}
 	s.items["key2"] = testData{Value: "value2"}
 
-	require.Equal(t, []testData{{Value: "value"}, {Value: "value2"}}, s.All())
+	require.ElementsMatch(t, []testData{{Value: "value"}, {Value: "value2"}}, s.All())
 }
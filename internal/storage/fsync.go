@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy defines when the written data is flushed to disk
+type FsyncPolicy string
+
+const (
+	// FsyncAlways sync the file after every write
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncEverySec sync the file once per second, writes wait for the next sync
+	FsyncEverySec FsyncPolicy = "everysec"
+	// FsyncNo leave flushing to the OS
+	FsyncNo FsyncPolicy = "no"
+)
+
+// DefaultFsyncInterval how often the file is synced with FsyncEverySec policy
+const DefaultFsyncInterval = time.Second
+
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	switch p := FsyncPolicy(s); p {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("unknown fsync policy %q", s)
+}
+
+// syncResult the outcome of a single background sync shared by all writes made before it
+type syncResult struct {
+	done chan struct{}
+	err  error
+}
+
+func newSyncResult() *syncResult {
+	return &syncResult{done: make(chan struct{})}
+}
+
+// wait block until the sync is completed. Nil result means there is nothing to wait for
+func (r *syncResult) wait() error {
+	if r == nil {
+		return nil
+	}
+	<-r.done
+
+	return r.err
+}
+
+// syncer groups writes to be synced by a background goroutine
+type syncer struct {
+	mtx      sync.Mutex
+	pending  *syncResult
+	running  *syncResult
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newSyncer() *syncer {
+	return &syncer{
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// next mark that a write needs to be covered by the next sync
+func (s *syncer) next() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.pending == nil {
+		s.pending = newSyncResult()
+	}
+}
+
+// last the sync covering all the writes made so far
+func (s *syncer) last() *syncResult {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.pending != nil {
+		return s.pending
+	}
+
+	return s.running
+}
+
+// take start the sync of the pending writes
+func (s *syncer) take() *syncResult {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.running = s.pending
+	s.pending = nil
+
+	return s.running
+}
+
+// done notify the writers waiting for the sync
+func (s *syncer) done(result *syncResult) {
+	s.mtx.Lock()
+	s.running = nil
+	s.mtx.Unlock()
+
+	close(result.done)
+}
+
+// close stop the background goroutine after the final sync
+func (s *syncer) close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.stopped
+}
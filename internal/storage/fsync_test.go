@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseFsyncPolicy(t *testing.T) {
+	t.Run("must return err on unknown policy", func(t *testing.T) {
+		_, err := ParseFsyncPolicy("sometimes")
+		require.Error(t, err)
+	})
+
+	t.Run("must parse known policies", func(t *testing.T) {
+		for _, p := range []FsyncPolicy{FsyncAlways, FsyncEverySec, FsyncNo} {
+			result, err := ParseFsyncPolicy(string(p))
+			require.NoError(t, err)
+			require.Equal(t, p, result)
+		}
+	})
+}
+
+func Test_AOF_Fsync(t *testing.T) {
+	t.Run("must write data with always policy", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f, WithFsync(FsyncAlways))
+		require.NoError(t, err)
+		defer s.Close()
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Delete("key"))
+		require.NoError(t, s.Sync())
+
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.Equal(t, framed(`{"key":"key","value":{"value":"value"},"version":1,"seqNo":1}`, `{"key":"key","seqNo":2,"isDeleted":true}`), string(data))
+	})
+
+	t.Run("must not wait if nothing was written with everysec policy", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f, WithFsync(FsyncEverySec), WithFsyncInterval(time.Hour))
+		require.NoError(t, err)
+		defer s.Close()
+
+		require.NoError(t, s.Sync())
+	})
+
+	t.Run("must return after background sync with everysec policy", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f, WithFsync(FsyncEverySec), WithFsyncInterval(10*time.Millisecond))
+		require.NoError(t, err)
+		defer s.Close()
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Delete("key"))
+		require.NoError(t, s.Sync())
+		require.NoError(t, s.Sync())
+	})
+
+	t.Run("must wait for sync with everysec policy", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "tmp.dat")
+		s, err := NewAOFFromPath[string, testData](f, WithFsync(FsyncEverySec), WithFsyncInterval(time.Hour))
+		require.NoError(t, err)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+
+		done := make(chan error)
+		go func() {
+			done <- s.Sync()
+		}()
+
+		select {
+		case <-done:
+			require.Fail(t, "sync must wait for the background sync")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		require.NoError(t, s.Close())
+		require.NoError(t, <-done)
+	})
+}
@@ -0,0 +1,362 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f1monkey/search/pkg/errs"
+	"go.uber.org/zap"
+)
+
+// segmentExt log segments are stored as <path>.<seq>.aof
+const segmentExt = ".aof"
+
+// Hybrid storage logging every change to the current segment of the append-only log
+// and periodically saving the whole data to a snapshot.
+// After the snapshot is saved the log segments it contains are deleted,
+// so only the changes made since the last snapshot are replayed on load
+type Hybrid[K comparable, V any] struct {
+	aof *AOF[K, V]
+
+	path   string
+	opts   []AOFOption
+	logger *zap.Logger
+
+	mtx    sync.Mutex
+	seq    uint64
+	closed bool
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+type hybridSnapshot[K comparable, V any] struct {
+	// Seq the last log segment included into the snapshot
+	Seq   uint64
+	Items map[K]V
+	Meta  map[K]Meta
+	SeqNo uint64
+}
+
+type segment struct {
+	seq  uint64
+	path string
+}
+
+// NewHybridFromPath load the snapshot stored at the path (without extension), replay the log segments
+// written after it and start saving snapshots periodically.
+// An append-only file of the aof engine stored at the same path is taken as the first segment
+func NewHybridFromPath[K comparable, V any](ctx context.Context, path string, interval time.Duration, opts ...AOFOption) (*Hybrid[K, V], error) {
+	snapshot, err := loadHybridSnapshot[K, V](path + snapshotExt)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := listSegments(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		segments, err = adoptAOF(path, snapshot.Seq+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Hybrid[K, V]{
+		path:    path,
+		opts:    opts,
+		logger:  newAOFOptions(opts).logger,
+		seq:     snapshot.Seq,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	opened := false
+	defer func() {
+		if !opened && s.aof != nil {
+			s.aof.Close()
+		}
+	}()
+
+	items := snapshot.Items
+	versions := restoreVersions(snapshot.Items, snapshot.Meta, snapshot.SeqNo)
+	for _, seg := range segments {
+		if seg.seq <= snapshot.Seq {
+			if err := os.Remove(seg.path); err != nil {
+				return nil, errs.Errorf("obsolete segment remove err: %w", err)
+			}
+			continue
+		}
+		if seg.seq != s.seq+1 {
+			return nil, errs.Errorf("%w: log segment %d is missing", ErrCorrupted, s.seq+1)
+		}
+
+		if s.aof != nil {
+			if err := s.aof.Close(); err != nil {
+				return nil, err
+			}
+		}
+		aof, err := s.openSegment(ctx, seg.path, items, versions)
+		if err != nil {
+			return nil, err
+		}
+		s.aof = aof
+		s.seq = seg.seq
+		versions = aof.versions
+	}
+
+	if s.aof == nil {
+		s.seq++
+		aof, err := s.openSegment(ctx, segmentPath(path, s.seq), items, versions)
+		if err != nil {
+			return nil, err
+		}
+		s.aof = aof
+	}
+
+	opened = true
+	go s.saveLoop(interval)
+
+	return s, nil
+}
+
+// openSegment replay the segment on top of the items
+func (s *Hybrid[K, V]) openSegment(ctx context.Context, path string, items map[K]V, versions versions[K]) (*AOF[K, V], error) {
+	aof, err := NewAOFFromPath[K, V](path, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	aof.items = items
+	aof.versions = versions
+
+	if err := aof.Init(ctx); err != nil {
+		aof.Close()
+		return nil, err
+	}
+
+	return aof, nil
+}
+
+func loadHybridSnapshot[K comparable, V any](path string) (hybridSnapshot[K, V], error) {
+	data := hybridSnapshot[K, V]{}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data.Items = make(map[K]V)
+		return data, nil
+	}
+	if err != nil {
+		return data, errs.Errorf("snapshot open err: %w", err)
+	}
+	defer f.Close()
+
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&data); err != nil {
+		return data, errs.Errorf("snapshot %q load err: %w", path, err)
+	}
+	if data.Items == nil {
+		data.Items = make(map[K]V)
+	}
+
+	return data, nil
+}
+
+// adoptAOF rename the file of the aof engine to the segment with the number
+func adoptAOF(path string, seq uint64) ([]segment, error) {
+	if _, err := os.Stat(path + aofExt); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errs.Errorf("file stat err: %w", err)
+	}
+
+	seg := segment{seq: seq, path: segmentPath(path, seq)}
+	if err := os.Rename(path+aofExt, seg.path); err != nil {
+		return nil, errs.Errorf("file rename err: %w", err)
+	}
+	syncDir(filepath.Dir(path))
+
+	return []segment{seg}, nil
+}
+
+func segmentPath(path string, seq uint64) string {
+	return fmt.Sprintf("%s.%d%s", path, seq, segmentExt)
+}
+
+// listSegments find the log segments stored at the path ordered by their numbers
+func listSegments(path string) ([]segment, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.Errorf("segments list err: %w", err)
+	}
+
+	prefix := filepath.Base(path) + "."
+	result := make([]segment, 0)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		// other storages may share the prefix, so the part between it and the extension must be a number
+		s := strings.TrimSuffix(strings.TrimPrefix(name, prefix), segmentExt)
+		seq, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || strconv.FormatUint(seq, 10) != s {
+			continue
+		}
+		result = append(result, segment{seq: seq, path: filepath.Join(filepath.Dir(path), name)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].seq < result[j].seq })
+
+	return result, nil
+}
+
+// Create element
+func (s *Hybrid[K, V]) Create(key K, value V) error {
+	return s.aof.Create(key, value)
+}
+
+// Get element from storage
+func (s *Hybrid[K, V]) Get(key K) (V, error) {
+	return s.aof.Get(key)
+}
+
+// GetWithMeta get element from storage together with its version
+func (s *Hybrid[K, V]) GetWithMeta(key K) (V, Meta, error) {
+	return s.aof.GetWithMeta(key)
+}
+
+// Update replace the existing element
+func (s *Hybrid[K, V]) Update(key K, value V) error {
+	return s.aof.Update(key, value)
+}
+
+// Upsert create the element or replace the existing one
+func (s *Hybrid[K, V]) Upsert(key K, value V) (bool, error) {
+	return s.aof.Upsert(key, value)
+}
+
+// Delete element from storage
+func (s *Hybrid[K, V]) Delete(key K) error {
+	return s.aof.Delete(key)
+}
+
+// Iterate call fn for every element until it returns false. The storage must not be modified from fn
+func (s *Hybrid[K, V]) Iterate(fn func(key K, value V) bool) {
+	s.aof.Iterate(fn)
+}
+
+// Sync wait until all the previous writes are on disk according to the fsync policy
+func (s *Hybrid[K, V]) Sync() error {
+	return s.aof.Sync()
+}
+
+// Close stop saving snapshots and close the current segment.
+// The changes since the last snapshot are replayed from it on the next load
+func (s *Hybrid[K, V]) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.stopped
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.closed = true
+
+	return s.aof.Close()
+}
+
+// Snapshot start a new log segment, save the data the previous one ends with and delete the segments it contains.
+// Does nothing if the current segment is empty
+func (s *Hybrid[K, V]) Snapshot() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.aof.Size() == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	seq := s.seq
+	f, err := os.OpenFile(segmentPath(s.path, seq+1), os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0600)
+	if err != nil {
+		return errs.Errorf("segment open err: %w", err)
+	}
+
+	items, versions, err := s.aof.rotate(f)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	s.seq = seq + 1
+
+	// if the snapshot is not saved, the previous segments are kept and replayed on load
+	err = saveFile(s.path+snapshotExt, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(hybridSnapshot[K, V]{Seq: seq, Items: items, Meta: versions.meta, SeqNo: versions.seqNo})
+	})
+	if err != nil {
+		return err
+	}
+
+	segments, err := listSegments(s.path)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		if seg.seq > seq {
+			break
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return errs.Errorf("obsolete segment remove err: %w", err)
+		}
+	}
+
+	s.logger.Debug(
+		"snapshot saved",
+		zap.String("file", s.path+snapshotExt),
+		zap.Uint64("seq", seq),
+		zap.Duration("took", time.Since(start)),
+	)
+
+	return nil
+}
+
+func (s *Hybrid[K, V]) saveLoop(interval time.Duration) {
+	defer close(s.stopped)
+
+	if interval <= 0 {
+		<-s.stop
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.Snapshot(); err != nil {
+				s.logger.Error("snapshot save err", zap.String("file", s.path+snapshotExt), zap.Error(err))
+			}
+		}
+	}
+}
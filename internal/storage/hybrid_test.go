@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openTestHybrid(t *testing.T, p string) *Hybrid[string, testData] {
+	s, err := NewHybridFromPath[string, testData](context.Background(), p, 0)
+	require.NoError(t, err)
+	return s
+}
+
+func testHybridItems(s *Hybrid[string, testData]) map[string]testData {
+	items := make(map[string]testData)
+	s.Iterate(func(key string, value testData) bool {
+		items[key] = value
+		return true
+	})
+	return items
+}
+
+func Test_Hybrid_Snapshot(t *testing.T) {
+	t.Run("must not save snapshot if nothing changed", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "data")
+		s := openTestHybrid(t, p)
+		defer s.Close()
+
+		require.NoError(t, s.Snapshot())
+		require.NoFileExists(t, p+snapshotExt)
+	})
+
+	t.Run("must start new segment and delete the saved ones", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "data")
+		s := openTestHybrid(t, p)
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Snapshot())
+		require.FileExists(t, p+snapshotExt)
+		require.NoFileExists(t, segmentPath(p, 1))
+
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+		require.NoError(t, s.Close())
+
+		segments, err := listSegments(p)
+		require.NoError(t, err)
+		require.Equal(t, []segment{{seq: 2, path: segmentPath(p, 2)}}, segments)
+
+		s = openTestHybrid(t, p)
+		defer s.Close()
+		require.Equal(t, map[string]testData{"key": {"value"}, "key2": {"value2"}}, testHybridItems(s))
+	})
+
+	t.Run("must return err if closed", func(t *testing.T) {
+		s := openTestHybrid(t, path.Join(t.TempDir(), "data"))
+		require.NoError(t, s.Close())
+		require.ErrorIs(t, s.Snapshot(), ErrClosed)
+	})
+}
+
+func Test_Hybrid_Load(t *testing.T) {
+	t.Run("must replay only segments newer than snapshot", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "data")
+		s := openTestHybrid(t, p)
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.NoError(t, s.Snapshot())
+		require.NoError(t, s.Create("key2", testData{"value2"}))
+		require.NoError(t, s.Close())
+
+		// a segment left by a crash before it was deleted must not be replayed over the snapshot
+		stale, err := NewAOFFromPath[string, testData](segmentPath(p, 1))
+		require.NoError(t, err)
+		require.NoError(t, stale.Create("stale", testData{"stale"}))
+		require.NoError(t, stale.Close())
+
+		s = openTestHybrid(t, p)
+		defer s.Close()
+		require.Equal(t, map[string]testData{"key": {"value"}, "key2": {"value2"}}, testHybridItems(s))
+		require.NoFileExists(t, segmentPath(p, 1))
+	})
+
+	t.Run("must replay all segments if snapshot was not saved", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "data")
+		for i, key := range []string{"key", "key2"} {
+			seg, err := NewAOFFromPath[string, testData](segmentPath(p, uint64(i+1)))
+			require.NoError(t, err)
+			require.NoError(t, seg.Create(key, testData{"value"}))
+			require.NoError(t, seg.Close())
+		}
+
+		s := openTestHybrid(t, p)
+		defer s.Close()
+		require.Equal(t, map[string]testData{"key": {"value"}, "key2": {"value"}}, testHybridItems(s))
+	})
+
+	t.Run("must return err if segment is missing", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "data")
+		seg, err := NewAOFFromPath[string, testData](segmentPath(p, 2))
+		require.NoError(t, err)
+		require.NoError(t, seg.Create("key", testData{"value"}))
+		require.NoError(t, seg.Close())
+
+		_, err = NewHybridFromPath[string, testData](context.Background(), p, 0)
+		require.ErrorIs(t, err, ErrCorrupted)
+	})
+
+	t.Run("must take file of aof engine as first segment", func(t *testing.T) {
+		p := path.Join(t.TempDir(), "data")
+		aof, err := NewAOFFromPath[string, testData](p + aofExt)
+		require.NoError(t, err)
+		require.NoError(t, aof.Create("key", testData{"value"}))
+		require.NoError(t, aof.Close())
+
+		s := openTestHybrid(t, p)
+		defer s.Close()
+		require.Equal(t, map[string]testData{"key": {"value"}}, testHybridItems(s))
+		require.NoFileExists(t, p+aofExt)
+		require.FileExists(t, segmentPath(p, 1))
+	})
+
+	t.Run("must ignore files of storages sharing the prefix", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(path.Join(dir, "data.other.1.aof"), []byte("invalid"), 0600))
+		require.NoError(t, os.WriteFile(path.Join(dir, "data.01.aof"), []byte("invalid"), 0600))
+
+		s := openTestHybrid(t, path.Join(dir, "data"))
+		defer s.Close()
+		require.Empty(t, testHybridItems(s))
+	})
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/f1monkey/search/pkg/errs"
+	"go.uber.org/zap"
+)
+
+// DefaultSnapshotInterval how often the changed snapshot storage is saved
+const DefaultSnapshotInterval = time.Minute
+
+// Snapshot in-memory storage periodically saved to a file as a whole.
+// Changes made after the last save are lost on crash
+type Snapshot[K comparable, V any] struct {
+	*File[K, V]
+
+	path   string
+	logger *zap.Logger
+
+	mtx   sync.Mutex
+	dirty bool
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSnapshotFromPath load the storage from the file if it exists and start saving it periodically
+func NewSnapshotFromPath[K comparable, V any](path string, interval time.Duration, logger *zap.Logger) (*Snapshot[K, V], error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	file, err := loadSnapshot[K, V](path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot[K, V]{
+		File:    file,
+		path:    path,
+		logger:  logger,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.saveLoop(interval)
+
+	return s, nil
+}
+
+func loadSnapshot[K comparable, V any](path string) (*File[K, V], error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewFile[K, V](), nil
+	}
+	if err != nil {
+		return nil, errs.Errorf("snapshot open err: %w", err)
+	}
+	defer f.Close()
+
+	file, err := Load[K, V](bufio.NewReader(f))
+	if err != nil {
+		return nil, errs.Errorf("snapshot %q load err: %w", path, err)
+	}
+
+	return file, nil
+}
+
+// Create element
+func (s *Snapshot[K, V]) Create(key K, value V) error {
+	return s.change(s.File.Create(key, value))
+}
+
+// Update replace the existing element
+func (s *Snapshot[K, V]) Update(key K, value V) error {
+	return s.change(s.File.Update(key, value))
+}
+
+// Upsert create the element or replace the existing one
+func (s *Snapshot[K, V]) Upsert(key K, value V) (bool, error) {
+	created, err := s.File.Upsert(key, value)
+
+	return created, s.change(err)
+}
+
+// Delete element from storage
+func (s *Snapshot[K, V]) Delete(key K) error {
+	return s.change(s.File.Delete(key))
+}
+
+func (s *Snapshot[K, V]) change(err error) error {
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	s.dirty = true
+	s.mtx.Unlock()
+
+	return nil
+}
+
+// Sync is a no-op, the changes are persisted with the next periodic save
+func (s *Snapshot[K, V]) Sync() error {
+	return nil
+}
+
+// Close save the changes and stop the periodic saving
+func (s *Snapshot[K, V]) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.stopped
+
+	return s.save()
+}
+
+func (s *Snapshot[K, V]) saveLoop(interval time.Duration) {
+	defer close(s.stopped)
+
+	if interval <= 0 {
+		<-s.stop
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.save(); err != nil {
+				s.logger.Error("snapshot save err", zap.String("file", s.path), zap.Error(err))
+			}
+		}
+	}
+}
+
+// save write the items over the previous snapshot
+func (s *Snapshot[K, V]) save() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	if err := saveFile(s.path, s.File.Save); err != nil {
+		return err
+	}
+
+	s.dirty = false
+
+	return nil
+}
+
+// saveFile write the encoded data to a temporary file and rename it over the path
+func saveFile(path string, encode func(w io.Writer) error) error {
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errs.Errorf("snapshot file open err: %w", err)
+	}
+
+	if err := writeFile(tmp, encode); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return errs.Errorf("snapshot file close err: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return errs.Errorf("snapshot file rename err: %w", err)
+	}
+	syncDir(filepath.Dir(path))
+
+	return nil
+}
+
+func writeFile(f *os.File, encode func(w io.Writer) error) error {
+	w := bufio.NewWriter(f)
+	if err := encode(w); err != nil {
+		return errs.Errorf("snapshot encode err: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return errs.Errorf("snapshot write err: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return errs.Errorf("snapshot sync err: %w", err)
+	}
+
+	return nil
+}
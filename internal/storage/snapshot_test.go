@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Snapshot_Save(t *testing.T) {
+	t.Run("must not create file if nothing changed", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "data.snapshot")
+		s, err := NewSnapshotFromPath[string, testData](f, 0, nil)
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		require.NoFileExists(t, f)
+	})
+
+	t.Run("must save changes periodically", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "data.snapshot")
+		s, err := NewSnapshotFromPath[string, testData](f, time.Millisecond, nil)
+		require.NoError(t, err)
+		defer s.Close()
+
+		require.NoError(t, s.Create("key", testData{"value"}))
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(f)
+			return err == nil
+		}, time.Second, time.Millisecond)
+
+		restored, err := NewSnapshotFromPath[string, testData](f, 0, nil)
+		require.NoError(t, err)
+		defer restored.Close()
+
+		value, err := restored.Get("key")
+		require.NoError(t, err)
+		require.Equal(t, testData{"value"}, value)
+	})
+
+	t.Run("must return err if snapshot is corrupted", func(t *testing.T) {
+		f := path.Join(t.TempDir(), "data.snapshot")
+		require.NoError(t, os.WriteFile(f, []byte("corrupted"), 0600))
+
+		_, err := NewSnapshotFromPath[string, testData](f, 0, nil)
+		require.Error(t, err)
+	})
+}
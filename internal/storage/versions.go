@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrConflict the element was changed or deleted after the expected change
+var ErrConflict = fmt.Errorf("version conflict")
+
+// Meta version information of the stored element
+type Meta struct {
+	// Version number of changes of the element since it was created, starting from 1
+	Version uint64
+	// SeqNo sequence number of the last change of the element, unique among all the changes of the storage
+	SeqNo uint64
+}
+
+// CheckSeqNo return ErrConflict if the element does not exist or its last change is not ifSeqNo.
+// Zero ifSeqNo matches any state. The caller must prevent the changes of the element until it is written
+func CheckSeqNo[K comparable, V any](e Engine[K, V], key K, ifSeqNo uint64) error {
+	if ifSeqNo == 0 {
+		return nil
+	}
+
+	_, meta, err := e.GetWithMeta(key)
+	if errors.Is(err, ErrNotFound) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	if meta.SeqNo != ifSeqNo {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// versions meta of the elements and the last sequence number of the storage. Must be guarded by the storage lock
+type versions[K comparable] struct {
+	seqNo uint64
+	meta  map[K]Meta
+}
+
+func newVersions[K comparable]() versions[K] {
+	return versions[K]{meta: make(map[K]Meta)}
+}
+
+// restoreVersions build the versions from the saved meta.
+// Elements saved without meta get the first version
+func restoreVersions[K comparable, V any](items map[K]V, meta map[K]Meta, seqNo uint64) versions[K] {
+	if meta == nil {
+		meta = make(map[K]Meta)
+	}
+
+	result := versions[K]{seqNo: seqNo, meta: meta}
+	for k := range items {
+		if _, ok := meta[k]; !ok {
+			result.set(k, result.next(k))
+		}
+	}
+
+	return result
+}
+
+func (v *versions[K]) get(key K) Meta {
+	return v.meta[key]
+}
+
+// next meta of the element after the change
+func (v *versions[K]) next(key K) Meta {
+	return Meta{Version: v.meta[key].Version + 1, SeqNo: v.seqNo + 1}
+}
+
+// nextSeqNo sequence number of the next change
+func (v *versions[K]) nextSeqNo() uint64 {
+	return v.seqNo + 1
+}
+
+// set apply the change of the element
+func (v *versions[K]) set(key K, meta Meta) {
+	v.meta[key] = meta
+	v.observe(meta.SeqNo)
+}
+
+// delete apply the deletion of the element
+func (v *versions[K]) delete(key K, seqNo uint64) {
+	delete(v.meta, key)
+	v.observe(seqNo)
+}
+
+// observe keep the sequence number monotonic
+func (v *versions[K]) observe(seqNo uint64) {
+	if seqNo > v.seqNo {
+		v.seqNo = seqNo
+	}
+}
+
+func (v *versions[K]) copy() versions[K] {
+	result := versions[K]{seqNo: v.seqNo, meta: make(map[K]Meta, len(v.meta))}
+	for k, m := range v.meta {
+		result.meta[k] = m
+	}
+
+	return result
+}
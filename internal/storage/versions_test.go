@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CheckSeqNo(t *testing.T) {
+	s := NewFile[string, testData]()
+	require.NoError(t, s.Create("key", testData{"value"}))
+	require.NoError(t, s.Update("key", testData{"value2"}))
+
+	t.Run("must match anything if sequence number is not set", func(t *testing.T) {
+		require.NoError(t, CheckSeqNo[string, testData](s, "key", 0))
+		require.NoError(t, CheckSeqNo[string, testData](s, "unknown", 0))
+	})
+
+	t.Run("must return err if element was changed", func(t *testing.T) {
+		require.ErrorIs(t, CheckSeqNo[string, testData](s, "key", 1), ErrConflict)
+	})
+
+	t.Run("must return err if element does not exist", func(t *testing.T) {
+		require.ErrorIs(t, CheckSeqNo[string, testData](s, "unknown", 1), ErrConflict)
+	})
+
+	t.Run("must match the last change", func(t *testing.T) {
+		require.NoError(t, CheckSeqNo[string, testData](s, "key", 2))
+	})
+}
+
+func Test_restoreVersions(t *testing.T) {
+	t.Run("must assign first version to elements without meta", func(t *testing.T) {
+		v := restoreVersions(
+			map[string]testData{"key": {"value"}, "key2": {"value2"}},
+			map[string]Meta{"key": {Version: 3, SeqNo: 5}},
+			5,
+		)
+		require.Equal(t, Meta{Version: 3, SeqNo: 5}, v.get("key"))
+		require.Equal(t, Meta{Version: 1, SeqNo: 6}, v.get("key2"))
+		require.Equal(t, uint64(6), v.seqNo)
+	})
+}
@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/pkg/errs"
+	"github.com/invopop/validation"
+	"go.uber.org/zap"
+)
+
+// ErrBulkMalformed the bulk body cannot be split into actions, so the rest of it cannot be processed
+var ErrBulkMalformed = fmt.Errorf("malformed bulk body")
+
+type BulkAction string
+
+const (
+	BulkActionIndex  BulkAction = "index"
+	BulkActionCreate BulkAction = "create"
+	BulkActionUpdate BulkAction = "update"
+	BulkActionDelete BulkAction = "delete"
+)
+
+// BulkItem result of a single bulk action
+type BulkItem struct {
+	Action  BulkAction
+	ID      string
+	Created bool
+	Err     error
+}
+
+type Bulk struct {
+	logger   *zap.Logger
+	getter   indexGetter
+	putter   documentPutter
+	upserter documentUpserter
+	updater  documentUpdater
+	deleter  documentDeleter
+	syncer   documentSyncer
+}
+
+type documentUpdater func(indexName string, id string, fields schema.Source, ifSeqNo uint64) error
+
+func NewBulk(
+	logger *zap.Logger,
+	getter indexGetter,
+	putter documentPutter,
+	upserter documentUpserter,
+	updater documentUpdater,
+	deleter documentDeleter,
+	syncer documentSyncer,
+) *Bulk {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &Bulk{
+		logger:   logger,
+		getter:   getter,
+		putter:   putter,
+		upserter: upserter,
+		updater:  updater,
+		deleter:  deleter,
+		syncer:   syncer,
+	}
+}
+
+type bulkActionLine map[BulkAction]bulkActionMeta
+
+type bulkActionMeta struct {
+	ID      string `json:"_id"`
+	IfSeqNo uint64 `json:"if_seq_no"`
+}
+
+type bulkUpdateLine struct {
+	Doc schema.Source `json:"doc"`
+}
+
+// Process read newline-delimited action/source pairs from the reader and apply them one by one.
+// Failed items do not stop processing, except for a malformed action line.
+// The changes are synced once after all the items are applied.
+func (u *Bulk) Process(indexName string, r io.Reader) ([]BulkItem, error) {
+	if _, err := u.getter(indexName); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(r)
+	items := make([]BulkItem, 0)
+	for {
+		line, err := readBulkLine(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return items, errs.Errorf("bulk body read err: %w", err)
+		}
+
+		action, meta, err := parseBulkAction(line)
+		if errors.Is(err, ErrBulkMalformed) {
+			items = append(items, BulkItem{Action: action, Err: err})
+			break
+		}
+
+		item := BulkItem{Action: action, ID: meta.ID, Err: err}
+		if action == BulkActionDelete {
+			if item.Err == nil {
+				item.Err = u.deleter(indexName, meta.ID, meta.IfSeqNo)
+			}
+			items = append(items, item)
+			continue
+		}
+
+		source, err := readBulkLine(reader)
+		if errors.Is(err, io.EOF) {
+			item.Err = errs.Errorf("%w: source line expected", ErrBulkMalformed)
+			items = append(items, item)
+			break
+		}
+		if err != nil {
+			return items, errs.Errorf("bulk body read err: %w", err)
+		}
+
+		if item.Err == nil {
+			item.Created, item.Err = u.apply(indexName, action, meta, source)
+		}
+		items = append(items, item)
+	}
+
+	if err := u.syncer(indexName); err != nil {
+		return items, err
+	}
+
+	u.logger.Debug("bulk processed", zap.String("index", indexName), zap.Int("items", len(items)))
+
+	return items, nil
+}
+
+func (u *Bulk) apply(indexName string, action BulkAction, meta bulkActionMeta, line []byte) (bool, error) {
+	if action == BulkActionUpdate {
+		update := bulkUpdateLine{}
+		if err := decodeBulkLine(line, &update); err != nil {
+			return false, err
+		}
+		if update.Doc == nil {
+			return false, validation.Errors{"doc": validation.ErrRequired}
+		}
+
+		return false, u.updater(indexName, meta.ID, update.Doc, meta.IfSeqNo)
+	}
+
+	source := schema.Source{}
+	if err := decodeBulkLine(line, &source); err != nil {
+		return false, err
+	}
+	doc := index.NewDocument(meta.ID, source)
+
+	if action == BulkActionCreate {
+		if err := u.putter(indexName, doc); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	return u.upserter(indexName, doc, meta.IfSeqNo)
+}
+
+func parseBulkAction(line []byte) (BulkAction, bulkActionMeta, error) {
+	actions := bulkActionLine{}
+	if err := json.Unmarshal(line, &actions); err != nil {
+		return "", bulkActionMeta{}, errs.Errorf("%w: %s", ErrBulkMalformed, err.Error())
+	}
+	if len(actions) != 1 {
+		return "", bulkActionMeta{}, errs.Errorf("%w: exactly one action expected", ErrBulkMalformed)
+	}
+
+	for action, meta := range actions {
+		switch action {
+		case BulkActionIndex, BulkActionCreate, BulkActionUpdate, BulkActionDelete:
+		default:
+			return action, bulkActionMeta{}, errs.Errorf("%w: unknown action %q", ErrBulkMalformed, action)
+		}
+
+		if meta.ID == "" {
+			return action, meta, validation.Errors{"_id": validation.ErrRequired}
+		}
+		if action == BulkActionCreate && meta.IfSeqNo != 0 {
+			return action, meta, validation.Errors{"if_seq_no": validation.NewError("", "not supported for create")}
+		}
+
+		return action, meta, nil
+	}
+
+	return "", bulkActionMeta{}, nil
+}
+
+func decodeBulkLine(line []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+
+	return d.Decode(v)
+}
+
+// readBulkLine read the next non-empty line
+func readBulkLine(r *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkCall struct {
+	action  BulkAction
+	doc     index.Document
+	ifSeqNo uint64
+}
+
+func newTestBulk(calls *[]bulkCall, err error) *Bulk {
+	return NewBulk(
+		nil,
+		func(name string) (index.Index, error) {
+			return index.Index{Name: name}, nil
+		},
+		func(indexName string, doc index.Document) error {
+			*calls = append(*calls, bulkCall{action: BulkActionCreate, doc: doc})
+			return err
+		},
+		func(indexName string, doc index.Document, ifSeqNo uint64) (bool, error) {
+			*calls = append(*calls, bulkCall{action: BulkActionIndex, doc: doc, ifSeqNo: ifSeqNo})
+			return false, err
+		},
+		func(indexName string, id string, fields schema.Source, ifSeqNo uint64) error {
+			*calls = append(*calls, bulkCall{action: BulkActionUpdate, doc: index.NewDocument(id, fields), ifSeqNo: ifSeqNo})
+			return err
+		},
+		func(indexName string, id string, ifSeqNo uint64) error {
+			*calls = append(*calls, bulkCall{action: BulkActionDelete, doc: index.Document{ID: id}, ifSeqNo: ifSeqNo})
+			return err
+		},
+		func(indexName string) error {
+			*calls = append(*calls, bulkCall{action: "sync"})
+			return nil
+		},
+	)
+}
+
+func Test_Bulk_Process(t *testing.T) {
+	t.Run("must return error if index not found", func(t *testing.T) {
+		c := NewBulk(nil, func(name string) (index.Index, error) {
+			return index.Index{}, storage.ErrNotFound
+		}, nil, nil, nil, nil, nil)
+
+		_, err := c.Process("name", strings.NewReader(""))
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must apply all actions", func(t *testing.T) {
+		var calls []bulkCall
+		c := newTestBulk(&calls, nil)
+
+		body := `{"create":{"_id":"1"}}
+{"field":"a"}
+
+{"index":{"_id":"2"}}
+{"field":1}
+{"update":{"_id":"1"}}
+{"doc":{"field":"b"}}
+{"delete":{"_id":"2"}}`
+
+		items, err := c.Process("name", strings.NewReader(body))
+		require.NoError(t, err)
+		require.Equal(t, []BulkItem{
+			{Action: BulkActionCreate, ID: "1", Created: true},
+			{Action: BulkActionIndex, ID: "2"},
+			{Action: BulkActionUpdate, ID: "1"},
+			{Action: BulkActionDelete, ID: "2"},
+		}, items)
+		require.Equal(t, []bulkCall{
+			{action: BulkActionCreate, doc: index.NewDocument("1", schema.Source{"field": "a"})},
+			{action: BulkActionIndex, doc: index.NewDocument("2", schema.Source{"field": json.Number("1")})},
+			{action: BulkActionUpdate, doc: index.NewDocument("1", schema.Source{"field": "b"})},
+			{action: BulkActionDelete, doc: index.Document{ID: "2"}},
+			{action: "sync"},
+		}, calls)
+	})
+
+	t.Run("must continue processing after failed items", func(t *testing.T) {
+		var calls []bulkCall
+		expectedErr := fmt.Errorf("error")
+		c := newTestBulk(&calls, expectedErr)
+
+		body := "{\"create\":{}}\n{\"field\":\"a\"}\n{\"index\":{\"_id\":\"1\"}}\n{invalid\n{\"update\":{\"_id\":\"1\"}}\n{}\n{\"delete\":{\"_id\":\"1\"}}\n"
+
+		items, err := c.Process("name", strings.NewReader(body))
+		require.NoError(t, err)
+		require.Len(t, items, 4)
+
+		var ve validation.Errors
+		require.ErrorAs(t, items[0].Err, &ve)
+		require.Contains(t, ve, "_id")
+		require.Error(t, items[1].Err)
+		require.ErrorAs(t, items[2].Err, &ve)
+		require.Contains(t, ve, "doc")
+		require.ErrorIs(t, items[3].Err, expectedErr)
+		require.Len(t, calls, 2)
+	})
+
+	t.Run("must stop processing on malformed action line", func(t *testing.T) {
+		var calls []bulkCall
+		c := newTestBulk(&calls, nil)
+
+		body := "{\"delete\":{\"_id\":\"1\"}}\n{\"unknown\":{\"_id\":\"1\"}}\n{\"delete\":{\"_id\":\"2\"}}\n"
+
+		items, err := c.Process("name", strings.NewReader(body))
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.NoError(t, items[0].Err)
+		require.ErrorIs(t, items[1].Err, ErrBulkMalformed)
+		require.Len(t, calls, 2)
+	})
+
+	t.Run("must pass sequence numbers of actions", func(t *testing.T) {
+		var calls []bulkCall
+		c := newTestBulk(&calls, nil)
+
+		body := `{"index":{"_id":"1","if_seq_no":3}}
+{"field":"a"}
+{"update":{"_id":"1","if_seq_no":4}}
+{"doc":{"field":"b"}}
+{"delete":{"_id":"1","if_seq_no":5}}
+{"create":{"_id":"2","if_seq_no":6}}
+{"field":"a"}`
+
+		items, err := c.Process("name", strings.NewReader(body))
+		require.NoError(t, err)
+		require.Len(t, items, 4)
+
+		var ve validation.Errors
+		require.ErrorAs(t, items[3].Err, &ve)
+		require.Contains(t, ve, "if_seq_no")
+		require.Equal(t, []bulkCall{
+			{action: BulkActionIndex, doc: index.NewDocument("1", schema.Source{"field": "a"}), ifSeqNo: 3},
+			{action: BulkActionUpdate, doc: index.NewDocument("1", schema.Source{"field": "b"}), ifSeqNo: 4},
+			{action: BulkActionDelete, doc: index.Document{ID: "1"}, ifSeqNo: 5},
+			{action: "sync"},
+		}, calls)
+	})
+
+	t.Run("must return item error if source line is missing", func(t *testing.T) {
+		var calls []bulkCall
+		c := newTestBulk(&calls, nil)
+
+		items, err := c.Process("name", strings.NewReader(`{"index":{"_id":"1"}}`))
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		require.ErrorIs(t, items[0].Err, ErrBulkMalformed)
+		require.Equal(t, []bulkCall{{action: "sync"}}, calls)
+	})
+}
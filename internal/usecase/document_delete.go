@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"go.uber.org/zap"
+)
+
+type DocumentDelete struct {
+	logger  *zap.Logger
+	deleter documentDeleter
+	syncer  documentSyncer
+}
+
+type documentDeleter func(indexName string, id string, ifSeqNo uint64) error
+
+func NewDocumentDelete(logger *zap.Logger, deleter documentDeleter, syncer documentSyncer) *DocumentDelete {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &DocumentDelete{
+		logger:  logger,
+		deleter: deleter,
+		syncer:  syncer,
+	}
+}
+
+// Delete the document. Non-zero ifSeqNo requires the document to be last changed with this sequence number
+func (u *DocumentDelete) Delete(indexName string, id string, ifSeqNo uint64) error {
+	if err := u.deleter(indexName, id, ifSeqNo); err != nil {
+		return err
+	}
+
+	if err := u.syncer(indexName); err != nil {
+		return err
+	}
+
+	u.logger.Debug("document deleted", zap.String("index", indexName), zap.String("id", id))
+
+	return nil
+}
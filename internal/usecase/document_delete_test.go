@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DocumentDelete_Delete(t *testing.T) {
+	t.Run("must return error if failed to delete document", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewDocumentDelete(nil, func(indexName string, id string, ifSeqNo uint64) error {
+			return expectedErr
+		}, func(indexName string) error { return nil })
+
+		err := c.Delete("name", "1", 0)
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must return error if failed to sync", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewDocumentDelete(nil, func(indexName string, id string, ifSeqNo uint64) error {
+			return nil
+		}, func(indexName string) error {
+			return expectedErr
+		})
+
+		err := c.Delete("name", "1", 0)
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must not return error if document deleted successfully", func(t *testing.T) {
+		c := NewDocumentDelete(nil, func(indexName string, id string, ifSeqNo uint64) error {
+			return nil
+		}, func(indexName string) error { return nil })
+
+		err := c.Delete("name", "1", 0)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/storage"
+)
+
+type DocumentGetter struct {
+	getter documentGetter
+}
+
+type documentGetter func(indexName string, id string) (index.Document, storage.Meta, error)
+
+func NewDocumentGet(getter documentGetter) *DocumentGetter {
+	return &DocumentGetter{
+		getter: getter,
+	}
+}
+
+func (u *DocumentGetter) Get(indexName string, id string) (index.Document, storage.Meta, error) {
+	return u.getter(indexName, id)
+}
@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DocumentGet_Get(t *testing.T) {
+	t.Run("must return error if failed to get document", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewDocumentGet(func(indexName string, id string) (index.Document, storage.Meta, error) {
+			return index.Document{}, storage.Meta{}, expectedErr
+		})
+
+		_, _, err := c.Get("name", "1")
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must return document", func(t *testing.T) {
+		val := index.Document{ID: "1"}
+		meta := storage.Meta{Version: 2, SeqNo: 3}
+
+		c := NewDocumentGet(func(indexName string, id string) (index.Document, storage.Meta, error) {
+			return val, meta, nil
+		})
+
+		result, resultMeta, err := c.Get("name", "1")
+		require.NoError(t, err)
+		require.Equal(t, val, result)
+		require.Equal(t, meta, resultMeta)
+	})
+}
@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"github.com/f1monkey/search/internal/index"
+	"go.uber.org/zap"
+)
+
+type DocumentPut struct {
+	logger *zap.Logger
+	putter documentPutter
+	syncer documentSyncer
+}
+
+type documentPutter func(indexName string, doc index.Document) error
+type documentSyncer func(indexName string) error
+
+func NewDocumentPut(logger *zap.Logger, putter documentPutter, syncer documentSyncer) *DocumentPut {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &DocumentPut{
+		logger: logger,
+		putter: putter,
+		syncer: syncer,
+	}
+}
+
+func (u *DocumentPut) Put(indexName string, doc index.Document) error {
+	if err := u.putter(indexName, doc); err != nil {
+		return err
+	}
+
+	if err := u.syncer(indexName); err != nil {
+		return err
+	}
+
+	u.logger.Debug("document stored", zap.String("index", indexName), zap.String("id", doc.ID))
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DocumentPut_Put(t *testing.T) {
+	t.Run("must return error if failed to put document", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewDocumentPut(nil, func(indexName string, doc index.Document) error {
+			return expectedErr
+		}, func(indexName string) error { return nil })
+
+		err := c.Put("name", index.Document{ID: "1"})
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must return error if failed to sync", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewDocumentPut(nil, func(indexName string, doc index.Document) error {
+			return nil
+		}, func(indexName string) error {
+			return expectedErr
+		})
+
+		err := c.Put("name", index.Document{ID: "1"})
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must not return error if document stored successfully", func(t *testing.T) {
+		c := NewDocumentPut(nil, func(indexName string, doc index.Document) error {
+			return nil
+		}, func(indexName string) error { return nil })
+
+		err := c.Put("name", index.Document{ID: "1"})
+		require.NoError(t, err)
+	})
+}
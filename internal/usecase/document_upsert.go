@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"github.com/f1monkey/search/internal/index"
+	"go.uber.org/zap"
+)
+
+type DocumentUpsert struct {
+	logger   *zap.Logger
+	upserter documentUpserter
+	syncer   documentSyncer
+}
+
+type documentUpserter func(indexName string, doc index.Document, ifSeqNo uint64) (bool, error)
+
+func NewDocumentUpsert(logger *zap.Logger, upserter documentUpserter, syncer documentSyncer) *DocumentUpsert {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &DocumentUpsert{
+		logger:   logger,
+		upserter: upserter,
+		syncer:   syncer,
+	}
+}
+
+// Upsert store the document replacing the existing one. Returns true if the document was created.
+// Non-zero ifSeqNo requires the existing document to be last changed with this sequence number
+func (u *DocumentUpsert) Upsert(indexName string, doc index.Document, ifSeqNo uint64) (bool, error) {
+	created, err := u.upserter(indexName, doc, ifSeqNo)
+	if err != nil {
+		return false, err
+	}
+
+	if err := u.syncer(indexName); err != nil {
+		return false, err
+	}
+
+	u.logger.Debug("document stored", zap.String("index", indexName), zap.String("id", doc.ID), zap.Bool("created", created))
+
+	return created, nil
+}
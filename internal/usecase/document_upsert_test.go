@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DocumentUpsert_Upsert(t *testing.T) {
+	t.Run("must return error if failed to upsert document", func(t *testing.T) {
+		c := NewDocumentUpsert(nil, func(indexName string, doc index.Document, ifSeqNo uint64) (bool, error) {
+			return false, storage.ErrConflict
+		}, func(indexName string) error { return nil })
+
+		_, err := c.Upsert("name", index.Document{ID: "1"}, 1)
+		require.ErrorIs(t, err, storage.ErrConflict)
+	})
+
+	t.Run("must return error if failed to sync", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewDocumentUpsert(nil, func(indexName string, doc index.Document, ifSeqNo uint64) (bool, error) {
+			return true, nil
+		}, func(indexName string) error {
+			return expectedErr
+		})
+
+		_, err := c.Upsert("name", index.Document{ID: "1"}, 0)
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must pass sequence number and return created flag", func(t *testing.T) {
+		var passed uint64
+		c := NewDocumentUpsert(nil, func(indexName string, doc index.Document, ifSeqNo uint64) (bool, error) {
+			passed = ifSeqNo
+			return true, nil
+		}, func(indexName string) error { return nil })
+
+		created, err := c.Upsert("name", index.Document{ID: "1"}, 3)
+		require.NoError(t, err)
+		require.True(t, created)
+		require.Equal(t, uint64(3), passed)
+	})
+}
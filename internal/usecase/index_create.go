@@ -9,11 +9,13 @@ import (
 type IndexCreate struct {
 	logger  *zap.Logger
 	creator indexCreator
+	syncer  indexSyncer
 }
 
 type indexCreator func(name string, index index.Index) error
+type indexSyncer func() error
 
-func NewIndexCreate(logger *zap.Logger, creator indexCreator) *IndexCreate {
+func NewIndexCreate(logger *zap.Logger, creator indexCreator, syncer indexSyncer) *IndexCreate {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -21,6 +23,7 @@ func NewIndexCreate(logger *zap.Logger, creator indexCreator) *IndexCreate {
 	return &IndexCreate{
 		logger:  logger,
 		creator: creator,
+		syncer:  syncer,
 	}
 }
 
@@ -33,6 +36,10 @@ func (u *IndexCreate) Create(index index.Index) error {
 		return err
 	}
 
+	if err := u.syncer(); err != nil {
+		return err
+	}
+
 	u.logger.Info("index created", zap.String("index", index.Name))
 
 	return nil
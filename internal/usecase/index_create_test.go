@@ -25,17 +25,44 @@ func Test_IndexCreate_Create(t *testing.T) {
 	t.Run("must return error if entity validation fails", func(t *testing.T) {
 		c := NewIndexCreate(nil, func(name string, index index.Index) error {
 			return nil
-		})
+		}, func() error { return nil })
 
 		err := c.Create(index.Index{})
 		require.Error(t, err)
 	})
 
+	t.Run("must not create index with the name unsafe for the file system", func(t *testing.T) {
+		c := NewIndexCreate(nil, func(name string, index index.Index) error {
+			t.Fatalf("index %q must not be created", name)
+			return nil
+		}, func() error { return nil })
+
+		for _, name := range []string{".", ".."} {
+			idx := validIndex
+			idx.Name = name
+			err := c.Create(idx)
+			require.Error(t, err)
+		}
+	})
+
 	t.Run("must return error if failed to create index", func(t *testing.T) {
 		expectedErr := fmt.Errorf("error")
 
 		c := NewIndexCreate(nil, func(name string, index index.Index) error {
 			return expectedErr
+		}, func() error { return nil })
+
+		err := c.Create(validIndex)
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must return error if failed to sync", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewIndexCreate(nil, func(name string, index index.Index) error {
+			return nil
+		}, func() error {
+			return expectedErr
 		})
 
 		err := c.Create(validIndex)
@@ -45,7 +72,7 @@ func Test_IndexCreate_Create(t *testing.T) {
 	t.Run("must not return error if index created successfully", func(t *testing.T) {
 		c := NewIndexCreate(nil, func(name string, index index.Index) error {
 			return nil
-		})
+		}, func() error { return nil })
 
 		err := c.Create(validIndex)
 		require.NoError(t, err)
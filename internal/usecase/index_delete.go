@@ -7,11 +7,12 @@ import (
 type IndexDelete struct {
 	logger  *zap.Logger
 	deleter indexDeleter
+	syncer  indexSyncer
 }
 
-type indexDeleter func(name string) error
+type indexDeleter func(name string, ifSeqNo uint64) error
 
-func NewIndexDelete(logger *zap.Logger, deleter indexDeleter) *IndexDelete {
+func NewIndexDelete(logger *zap.Logger, deleter indexDeleter, syncer indexSyncer) *IndexDelete {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
@@ -19,11 +20,17 @@ func NewIndexDelete(logger *zap.Logger, deleter indexDeleter) *IndexDelete {
 	return &IndexDelete{
 		logger:  logger,
 		deleter: deleter,
+		syncer:  syncer,
 	}
 }
 
-func (u *IndexDelete) Delete(name string) error {
-	if err := u.deleter(name); err != nil {
+// Delete the index. Non-zero ifSeqNo requires the index to be last changed with this sequence number
+func (u *IndexDelete) Delete(name string, ifSeqNo uint64) error {
+	if err := u.deleter(name, ifSeqNo); err != nil {
+		return err
+	}
+
+	if err := u.syncer(); err != nil {
 		return err
 	}
 
@@ -11,20 +11,33 @@ func Test_IndexDelete_Delete(t *testing.T) {
 	t.Run("must return error if failed to delete index", func(t *testing.T) {
 		expectedErr := fmt.Errorf("error")
 
-		c := NewIndexDelete(nil, func(name string) error {
+		c := NewIndexDelete(nil, func(name string, ifSeqNo uint64) error {
+			return expectedErr
+		}, func() error { return nil })
+
+		err := c.Delete("name", 0)
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must return error if failed to sync", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewIndexDelete(nil, func(name string, ifSeqNo uint64) error {
+			return nil
+		}, func() error {
 			return expectedErr
 		})
 
-		err := c.Delete("name")
+		err := c.Delete("name", 0)
 		require.ErrorIs(t, err, expectedErr)
 	})
 
 	t.Run("must not return error if index created successfully", func(t *testing.T) {
-		c := NewIndexDelete(nil, func(name string) error {
+		c := NewIndexDelete(nil, func(name string, ifSeqNo uint64) error {
 			return nil
-		})
+		}, func() error { return nil })
 
-		err := c.Delete("name")
+		err := c.Delete("name", 0)
 		require.NoError(t, err)
 	})
 }
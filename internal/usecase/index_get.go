@@ -2,20 +2,22 @@ package usecase
 
 import (
 	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/storage"
 )
 
 type IndexGetter struct {
-	getter indexGetter
+	getter indexMetaGetter
 }
 
 type indexGetter func(name string) (index.Index, error)
+type indexMetaGetter func(name string) (index.Index, storage.Meta, error)
 
-func NewIndexGet(getter indexGetter) *IndexGetter {
+func NewIndexGet(getter indexMetaGetter) *IndexGetter {
 	return &IndexGetter{
 		getter: getter,
 	}
 }
 
-func (u *IndexGetter) Get(name string) (index.Index, error) {
+func (u *IndexGetter) Get(name string) (index.Index, storage.Meta, error) {
 	return u.getter(name)
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/storage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -12,23 +13,25 @@ func Test_IndexGet_Get(t *testing.T) {
 	t.Run("must return error if failed to get index", func(t *testing.T) {
 		expectedErr := fmt.Errorf("error")
 
-		c := NewIndexGet(func(name string) (index.Index, error) {
-			return index.Index{}, expectedErr
+		c := NewIndexGet(func(name string) (index.Index, storage.Meta, error) {
+			return index.Index{}, storage.Meta{}, expectedErr
 		})
 
-		_, err := c.Get("name")
+		_, _, err := c.Get("name")
 		require.ErrorIs(t, err, expectedErr)
 	})
 
 	t.Run("must not return error if index created successfully", func(t *testing.T) {
 		val := index.Index{Name: "name"}
+		meta := storage.Meta{Version: 2, SeqNo: 3}
 
-		c := NewIndexGet(func(name string) (index.Index, error) {
-			return val, nil
+		c := NewIndexGet(func(name string) (index.Index, storage.Meta, error) {
+			return val, meta, nil
 		})
 
-		result, err := c.Get("name")
+		result, resultMeta, err := c.Get("name")
 		require.NoError(t, err)
 		require.Equal(t, val, result)
+		require.Equal(t, meta, resultMeta)
 	})
 }
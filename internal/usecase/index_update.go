@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/invopop/validation"
+	"go.uber.org/zap"
+)
+
+type IndexUpdate struct {
+	logger  *zap.Logger
+	getter  indexMetaGetter
+	updater indexUpdater
+	syncer  indexSyncer
+}
+
+type indexUpdater func(name string, index index.Index, ifSeqNo uint64) error
+
+func NewIndexUpdate(logger *zap.Logger, getter indexMetaGetter, updater indexUpdater, syncer indexSyncer) *IndexUpdate {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &IndexUpdate{
+		logger:  logger,
+		getter:  getter,
+		updater: updater,
+		syncer:  syncer,
+	}
+}
+
+// Update add the fields and analyzers of the patch to the index schema.
+// The changes must keep the stored documents valid.
+// Non-zero ifSeqNo requires the index to be last changed with this sequence number.
+// The index is updated only if it was not changed after it was read, otherwise storage.ErrConflict is returned
+func (u *IndexUpdate) Update(name string, patch schema.Schema, ifSeqNo uint64) (index.Index, error) {
+	current, meta, err := u.getter(name)
+	if err != nil {
+		return index.Index{}, err
+	}
+	if ifSeqNo != 0 && ifSeqNo != meta.SeqNo {
+		return index.Index{}, storage.ErrConflict
+	}
+
+	updated := index.Index{
+		Name:   current.Name,
+		Schema: current.Schema.Patch(patch),
+	}
+	if err := validation.Validate(updated); err != nil {
+		return index.Index{}, err
+	}
+	if err := schema.ValidateCompatible(current.Schema, updated.Schema); err != nil {
+		return index.Index{}, err
+	}
+
+	if err := u.updater(name, updated, meta.SeqNo); err != nil {
+		return index.Index{}, err
+	}
+
+	if err := u.syncer(); err != nil {
+		return index.Index{}, err
+	}
+
+	u.logger.Info("index updated", zap.String("index", name))
+
+	return updated, nil
+}
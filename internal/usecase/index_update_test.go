@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index"
+	"github.com/f1monkey/search/internal/index/schema"
+	"github.com/f1monkey/search/internal/storage"
+	"github.com/invopop/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IndexUpdate_Update(t *testing.T) {
+	current := index.Index{
+		Name: "name",
+		Schema: schema.Schema{
+			Fields: map[string]schema.Field{
+				"field": {Type: schema.TypeBool},
+			},
+		},
+	}
+	getter := func(name string) (index.Index, storage.Meta, error) {
+		return current, storage.Meta{Version: 1, SeqNo: 5}, nil
+	}
+	patch := schema.Schema{
+		Fields: map[string]schema.Field{
+			"field2": {Type: schema.TypeKeyword},
+		},
+	}
+
+	t.Run("must return error if index not found", func(t *testing.T) {
+		u := NewIndexUpdate(nil, func(name string) (index.Index, storage.Meta, error) {
+			return index.Index{}, storage.Meta{}, storage.ErrNotFound
+		}, func(name string, index index.Index, ifSeqNo uint64) error {
+			return nil
+		}, func() error { return nil })
+
+		_, err := u.Update("name", patch, 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("must return validation error if patched schema is invalid", func(t *testing.T) {
+		u := NewIndexUpdate(nil, getter, func(name string, index index.Index, ifSeqNo uint64) error {
+			return nil
+		}, func() error { return nil })
+
+		_, err := u.Update("name", schema.Schema{Fields: map[string]schema.Field{"field2": {Type: "invalid"}}}, 0)
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+	})
+
+	t.Run("must return validation error if field type is changed", func(t *testing.T) {
+		u := NewIndexUpdate(nil, getter, func(name string, index index.Index, ifSeqNo uint64) error {
+			return nil
+		}, func() error { return nil })
+
+		_, err := u.Update("name", schema.Schema{Fields: map[string]schema.Field{"field": {Type: schema.TypeKeyword}}}, 0)
+		var ve validation.Errors
+		require.ErrorAs(t, err, &ve)
+		require.Contains(t, ve, "fields.field")
+	})
+
+	t.Run("must return error if failed to update index", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		u := NewIndexUpdate(nil, getter, func(name string, index index.Index, ifSeqNo uint64) error {
+			return expectedErr
+		}, func() error { return nil })
+
+		_, err := u.Update("name", patch, 0)
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must return error if failed to sync", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		u := NewIndexUpdate(nil, getter, func(name string, index index.Index, ifSeqNo uint64) error {
+			return nil
+		}, func() error {
+			return expectedErr
+		})
+
+		_, err := u.Update("name", patch, 0)
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must return conflict error if index was changed", func(t *testing.T) {
+		u := NewIndexUpdate(nil, getter, func(name string, index index.Index, ifSeqNo uint64) error {
+			return nil
+		}, func() error { return nil })
+
+		_, err := u.Update("name", patch, 4)
+		require.ErrorIs(t, err, storage.ErrConflict)
+	})
+
+	t.Run("must update index with the patched schema", func(t *testing.T) {
+		var updated index.Index
+		var updatedSeqNo uint64
+		u := NewIndexUpdate(nil, getter, func(name string, index index.Index, ifSeqNo uint64) error {
+			updated = index
+			updatedSeqNo = ifSeqNo
+			return nil
+		}, func() error { return nil })
+
+		result, err := u.Update("name", patch, 0)
+		require.NoError(t, err)
+		require.Equal(t, updated, result)
+		require.Equal(t, uint64(5), updatedSeqNo, "must update only the read version of the index")
+		require.Equal(t, map[string]schema.Field{
+			"field":  {Type: schema.TypeBool},
+			"field2": {Type: schema.TypeKeyword},
+		}, result.Schema.Fields)
+	})
+}
@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"github.com/f1monkey/search/internal/index/search"
+)
+
+type Search struct {
+	searcher indexSearcher
+}
+
+type indexSearcher func(indexName string, request []byte) (search.Result, error)
+
+func NewSearch(searcher indexSearcher) *Search {
+	return &Search{
+		searcher: searcher,
+	}
+}
+
+func (u *Search) Search(indexName string, request []byte) (search.Result, error) {
+	return u.searcher(indexName, request)
+}
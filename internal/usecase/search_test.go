@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/f1monkey/search/internal/index/search"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Search_Search(t *testing.T) {
+	t.Run("must return error if search failed", func(t *testing.T) {
+		expectedErr := fmt.Errorf("error")
+
+		c := NewSearch(func(indexName string, request []byte) (search.Result, error) {
+			return search.Result{}, expectedErr
+		})
+
+		_, err := c.Search("name", nil)
+		require.ErrorIs(t, err, expectedErr)
+	})
+
+	t.Run("must return search result", func(t *testing.T) {
+		val := search.Result{Hits: search.Hits{Total: 1, Hits: []search.Hit{{ID: "1"}}}}
+
+		c := NewSearch(func(indexName string, request []byte) (search.Result, error) {
+			return val, nil
+		})
+
+		result, err := c.Search("name", nil)
+		require.NoError(t, err)
+		require.Equal(t, val, result)
+	})
+}